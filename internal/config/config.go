@@ -0,0 +1,472 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/tlsconfig"
+
+	"github.com/sirupsen/logrus"
+)
+
+// minTokenSecretLen is the length of a SHA-256 output, as recommended for HS256 keys
+const minTokenSecretLen = 32
+
+// redacted replaces the value of secret settings
+const redacted = "[REDACTED]"
+
+// Config holds the service settings, read from environment variables
+type Config struct {
+	// HTTPAddr is the address the API listens on
+	HTTPAddr string
+	// GRPCAddr is the address the gRPC API listens on, disabled if empty
+	GRPCAddr string
+	// DBFile is the sqlite file, kept between starts
+	DBFile string
+	// DBReset deletes DBFile on start, so the database starts empty
+	DBReset bool
+	// GraphQL limits bound the queries of the GraphQL endpoint, see graphqltransport.Limits
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+	GraphQLMaxBatch      int
+	// GraphQLPlayground serves GraphiQL at GET /graphql, its assets are loaded from unpkg.com by the browser
+	GraphQLPlayground bool
+	// SwaggerUI serves Swagger UI at GET /docs, for the OpenAPI document at /openapi.json, its assets are loaded
+	// from unpkg.com by the browser
+	SwaggerUI bool
+	// OpenAPIStrictResponses also validates the responses against the OpenAPI document, for tests
+	OpenAPIStrictResponses bool
+	// APIV1Deprecation is when v1 of the articles API was deprecated, announced in its Deprecation header, none if zero
+	APIV1Deprecation time.Time
+	// APIV1Sunset is when v1 stops being served, announced in its Sunset header, none if zero
+	APIV1Sunset time.Time
+	// StreamHeartbeat is how often idle event streams get a comment, so proxies don't close them
+	StreamHeartbeat time.Duration
+	// StreamMaxDuration ends event streams before the write timeout of the server, clients reconnect
+	StreamMaxDuration time.Duration
+	// WebSocket limits are the resources each WebSocket connection can take, see transports.WebSocketLimits
+	WebSocketMaxSubscriptions int
+	WebSocketMaxFilters       int
+	WebSocketMaxPending       int
+	WebSocketPingInterval     time.Duration
+	// WebhookPollInterval is how often the delivery queue is checked for retries
+	WebhookPollInterval time.Duration
+	// WebhookMaxAttempts is the number of attempts before a delivery fails for good
+	WebhookMaxAttempts int
+	// WebhookRetryBaseDelay is the wait after the first failed attempt, it doubles up to WebhookRetryMaxDelay
+	WebhookRetryBaseDelay time.Duration
+	WebhookRetryMaxDelay  time.Duration
+	// WebhookSendTimeout bounds each delivery request
+	WebhookSendTimeout time.Duration
+	// WebhookAllowPrivateTargets lets webhooks target loopback, private and link-local addresses, for development
+	WebhookAllowPrivateTargets bool
+	// OutboxPollInterval is how often the outbox is checked for events to relay, and failed publications retried
+	OutboxPollInterval time.Duration
+	// OutboxRetention is how long the events every publisher got are kept in the outbox, forever if zero
+	OutboxRetention time.Duration
+	// OutboxFile is a file the events are appended to, disabled if empty
+	OutboxFile string
+	// NATSURL is the NATS server the events are published to, on NATSSubjectPrefix.<event type>, disabled if empty
+	NATSURL           string `secret:"true"`
+	NATSSubjectPrefix string
+	// AdminAddr is the private address of the admin server, disabled if empty
+	AdminAddr string
+	// LogLevel is the initial log level, it can be changed at runtime from the admin server
+	LogLevel logrus.Level
+	// LogRedactFields are the log fields whose values are replaced, see logging.RedactHook
+	LogRedactFields []string
+	// LogMaxFieldLength truncates longer log field values, 0 to disable
+	LogMaxFieldLength int
+	// Log chooses the log format and files, see logging.Options
+	Log logging.Options
+	// ErrorReportFile and ErrorReportDSN are where the logged errors are reported, a file or a Sentry compatible server
+	ErrorReportFile string
+	ErrorReportDSN  string `secret:"true"`
+	// ErrorReportInterval is how often errors with the same fingerprint are reported
+	ErrorReportInterval time.Duration
+	// TLS serves the API over TLS when the cert and key files are set, see tlsconfig.Options
+	TLS tlsconfig.Options
+	// TLSReloadInterval is how often the certificate files are checked for changes
+	TLSReloadInterval time.Duration
+	// ClientCertScopes and ClientCertRoles are granted to the principals of verified client certificates
+	ClientCertScopes []string
+	ClientCertRoles  []string
+	// ShutdownTimeout bounds the time in-flight requests have to finish on shutdown
+	ShutdownTimeout time.Duration
+	// ShutdownDrainDelay is the time readiness fails before the server stops accepting requests
+	// so the load balancer notices and stops routing new traffic
+	ShutdownDrainDelay time.Duration
+
+	// BootstrapAPIKey is stored on start with all scopes, so the first keys can be created
+	BootstrapAPIKey string `secret:"true"`
+
+	// JWKSFile is the local JWKS used to verify bearer tokens, bearer auth is disabled if empty
+	JWKSFile string
+	// JWKSReloadInterval is how often the JWKS file is checked for changes
+	JWKSReloadInterval time.Duration
+	// JWTIssuer and JWTAudience are checked against the iss and aud claims when not empty
+	JWTIssuer   string
+	JWTAudience string
+	// JWTLeeway is the clock skew tolerated when checking exp and nbf
+	JWTLeeway time.Duration
+
+	// TokenSecret signs the tokens issued on log in, login is disabled if empty
+	TokenSecret string `secret:"true"`
+	// TokenTTL is how long the issued tokens are valid
+	TokenTTL time.Duration
+
+	// RateLimits choose the limit of each request, see ratelimit.Rules
+	RateLimits ratelimit.Rules
+	// RateLimitStore is where the buckets are kept, memory or sqlite to share them between instances
+	RateLimitStore string
+	// RateLimitTrustForwarded uses the X-Forwarded-For header as client IP
+	RateLimitTrustForwarded bool
+}
+
+// Load reads the configuration from the environment
+func Load() (Config, error) {
+	cfg := Config{
+		HTTPAddr:  stringOr("HTTP_ADDR", "0.0.0.0:8080"),
+		GRPCAddr:  optional("GRPC_ADDR", "0.0.0.0:9000"),
+		AdminAddr: optional("ADMIN_ADDR", "127.0.0.1:9090"),
+		DBFile:    stringOr("DB_FILE", "./articles.db"),
+		Log: logging.Options{
+			Format:    stringOr("LOG_FORMAT", logging.FormatText),
+			File:      os.Getenv("LOG_FILE"),
+			ErrorFile: os.Getenv("LOG_ERROR_FILE"),
+		},
+		TLS: tlsconfig.Options{
+			CertFile:     os.Getenv("TLS_CERT_FILE"),
+			KeyFile:      os.Getenv("TLS_KEY_FILE"),
+			ClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+			ClientAuth:   stringOr("TLS_CLIENT_AUTH", "request"),
+		},
+		ErrorReportFile:   os.Getenv("ERROR_REPORT_FILE"),
+		ErrorReportDSN:    os.Getenv("ERROR_REPORT_DSN"),
+		ClientCertScopes:  list("TLS_CLIENT_SCOPES"),
+		ClientCertRoles:   list("TLS_CLIENT_ROLES"),
+		BootstrapAPIKey:   os.Getenv("API_BOOTSTRAP_KEY"),
+		JWKSFile:          os.Getenv("JWT_JWKS_FILE"),
+		JWTIssuer:         os.Getenv("JWT_ISSUER"),
+		JWTAudience:       os.Getenv("JWT_AUDIENCE"),
+		TokenSecret:       os.Getenv("AUTH_TOKEN_SECRET"),
+		RateLimitStore:    stringOr("RATE_LIMIT_STORE", "memory"),
+		OutboxFile:        os.Getenv("OUTBOX_FILE"),
+		NATSURL:           os.Getenv("NATS_URL"),
+		NATSSubjectPrefix: stringOr("NATS_SUBJECT_PREFIX", "articles"),
+	}
+
+	var err error
+	if cfg.LogLevel, err = logrus.ParseLevel(stringOr("LOG_LEVEL", "info")); err != nil {
+		return Config{}, fmt.Errorf("could not parse LOG_LEVEL: %w", err)
+	}
+	if cfg.LogRedactFields = list("LOG_REDACT_FIELDS"); cfg.LogRedactFields == nil {
+		cfg.LogRedactFields = logging.DefaultRedactFields
+	}
+	if cfg.LogMaxFieldLength, err = integer("LOG_MAX_FIELD_LENGTH", 256); err != nil {
+		return Config{}, err
+	}
+	if _, err := logging.NewFormatter(cfg.Log.Format); err != nil {
+		return Config{}, fmt.Errorf("could not parse LOG_FORMAT: %w", err)
+	}
+	if cfg.Log.MaxSize, err = integer("LOG_FILE_MAX_SIZE", 100); err != nil {
+		return Config{}, err
+	}
+	if cfg.Log.RotateInterval, err = duration("LOG_FILE_ROTATE_INTERVAL", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.Log.MaxAge, err = duration("LOG_FILE_MAX_AGE", 7*24*time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.Log.MaxBackups, err = integer("LOG_FILE_MAX_BACKUPS", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.Log.Compress, err = boolean("LOG_FILE_COMPRESS", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.DBReset, err = boolean("DB_RESET", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.ErrorReportInterval, err = duration("ERROR_REPORT_INTERVAL", time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.TLS.MinVersion, err = tlsconfig.ParseVersion(stringOr("TLS_MIN_VERSION", "1.2")); err != nil {
+		return Config{}, fmt.Errorf("could not parse TLS_MIN_VERSION: %w", err)
+	}
+	if cfg.TLS.CipherSuites, err = tlsconfig.ParseCipherSuites(os.Getenv("TLS_CIPHER_SUITES")); err != nil {
+		return Config{}, fmt.Errorf("could not parse TLS_CIPHER_SUITES: %w", err)
+	}
+	if cfg.TLS.ClientAuth != "request" && cfg.TLS.ClientAuth != "require" {
+		return Config{}, fmt.Errorf("TLS_CLIENT_AUTH must be request or require, got %q", cfg.TLS.ClientAuth)
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return Config{}, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLS.ClientCAFile != "" && cfg.TLS.CertFile == "" {
+		return Config{}, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if cfg.TLSReloadInterval, err = duration("TLS_RELOAD_INTERVAL", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.GraphQLMaxDepth, err = integer("GRAPHQL_MAX_DEPTH", 6); err != nil {
+		return Config{}, err
+	}
+	if cfg.GraphQLMaxComplexity, err = integer("GRAPHQL_MAX_COMPLEXITY", 1000); err != nil {
+		return Config{}, err
+	}
+	if cfg.GraphQLMaxBatch, err = integer("GRAPHQL_MAX_BATCH", 10); err != nil {
+		return Config{}, err
+	}
+	if cfg.GraphQLPlayground, err = boolean("GRAPHQL_PLAYGROUND", true); err != nil {
+		return Config{}, err
+	}
+	if cfg.SwaggerUI, err = boolean("SWAGGER_UI", true); err != nil {
+		return Config{}, err
+	}
+	if cfg.OpenAPIStrictResponses, err = boolean("OPENAPI_STRICT_RESPONSES", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.APIV1Deprecation, err = date("API_V1_DEPRECATION_DATE", ""); err != nil {
+		return Config{}, err
+	}
+	if cfg.APIV1Sunset, err = date("API_V1_SUNSET_DATE", ""); err != nil {
+		return Config{}, err
+	}
+	if !cfg.APIV1Sunset.IsZero() && cfg.APIV1Deprecation.IsZero() {
+		return Config{}, fmt.Errorf("API_V1_SUNSET_DATE needs API_V1_DEPRECATION_DATE")
+	}
+	if !cfg.APIV1Sunset.IsZero() && !cfg.APIV1Sunset.After(cfg.APIV1Deprecation) {
+		return Config{}, fmt.Errorf("API_V1_SUNSET_DATE must be after API_V1_DEPRECATION_DATE")
+	}
+	if cfg.StreamHeartbeat, err = duration("STREAM_HEARTBEAT_INTERVAL", 15*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.StreamMaxDuration, err = duration("STREAM_MAX_DURATION", 25*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.StreamHeartbeat <= 0 || cfg.StreamMaxDuration <= 0 {
+		return Config{}, fmt.Errorf("STREAM_HEARTBEAT_INTERVAL and STREAM_MAX_DURATION must be positive")
+	}
+	if cfg.WebSocketMaxSubscriptions, err = integer("WS_MAX_SUBSCRIPTIONS", 10); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebSocketMaxFilters, err = integer("WS_MAX_FILTERS", 100); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebSocketMaxPending, err = integer("WS_MAX_PENDING", 64); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebSocketPingInterval, err = duration("WS_PING_INTERVAL", 20*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebSocketMaxSubscriptions <= 0 || cfg.WebSocketMaxFilters <= 0 || cfg.WebSocketMaxPending <= 0 || cfg.WebSocketPingInterval <= 0 {
+		return Config{}, fmt.Errorf("WS_MAX_SUBSCRIPTIONS, WS_MAX_FILTERS, WS_MAX_PENDING and WS_PING_INTERVAL must be positive")
+	}
+	if cfg.WebhookPollInterval, err = duration("WEBHOOK_POLL_INTERVAL", 5*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebhookMaxAttempts, err = integer("WEBHOOK_MAX_ATTEMPTS", 8); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebhookRetryBaseDelay, err = duration("WEBHOOK_RETRY_BASE_DELAY", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebhookRetryMaxDelay, err = duration("WEBHOOK_RETRY_MAX_DELAY", time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebhookSendTimeout, err = duration("WEBHOOK_SEND_TIMEOUT", 10*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.WebhookPollInterval <= 0 || cfg.WebhookMaxAttempts <= 0 || cfg.WebhookRetryBaseDelay <= 0 || cfg.WebhookSendTimeout <= 0 {
+		return Config{}, fmt.Errorf("WEBHOOK_POLL_INTERVAL, WEBHOOK_MAX_ATTEMPTS, WEBHOOK_RETRY_BASE_DELAY and WEBHOOK_SEND_TIMEOUT must be positive")
+	}
+	if cfg.WebhookRetryMaxDelay < cfg.WebhookRetryBaseDelay {
+		return Config{}, fmt.Errorf("WEBHOOK_RETRY_MAX_DELAY must not be shorter than WEBHOOK_RETRY_BASE_DELAY")
+	}
+	if cfg.WebhookAllowPrivateTargets, err = boolean("WEBHOOK_ALLOW_PRIVATE_TARGETS", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.OutboxPollInterval, err = duration("OUTBOX_POLL_INTERVAL", time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.OutboxPollInterval <= 0 {
+		return Config{}, fmt.Errorf("OUTBOX_POLL_INTERVAL must be positive")
+	}
+	if cfg.OutboxRetention, err = duration("OUTBOX_RETENTION", 7*24*time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.OutboxRetention < 0 {
+		return Config{}, fmt.Errorf("OUTBOX_RETENTION must not be negative")
+	}
+	if cfg.ShutdownTimeout, err = duration("SHUTDOWN_TIMEOUT", 15*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.ShutdownDrainDelay, err = duration("SHUTDOWN_DRAIN_DELAY", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.JWKSReloadInterval, err = duration("JWT_JWKS_RELOAD_INTERVAL", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.JWTLeeway, err = duration("JWT_LEEWAY", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.TokenTTL, err = duration("AUTH_TOKEN_TTL", time.Hour); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.RateLimits.Default, err = ratelimit.ParseLimit(stringOr("RATE_LIMIT_DEFAULT", "100/1m")); err != nil {
+		return Config{}, fmt.Errorf("could not parse RATE_LIMIT_DEFAULT: %w", err)
+	}
+	if cfg.RateLimits.Routes, err = ratelimit.ParseLimits(stringOr("RATE_LIMIT_ROUTES", "POST /articles=20/1m")); err != nil {
+		return Config{}, fmt.Errorf("could not parse RATE_LIMIT_ROUTES: %w", err)
+	}
+	if cfg.RateLimits.Principals, err = ratelimit.ParseLimits(os.Getenv("RATE_LIMIT_PRINCIPALS")); err != nil {
+		return Config{}, fmt.Errorf("could not parse RATE_LIMIT_PRINCIPALS: %w", err)
+	}
+	if cfg.RateLimitTrustForwarded, err = boolean("RATE_LIMIT_TRUST_FORWARDED", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.RateLimitStore != "memory" && cfg.RateLimitStore != "sqlite" {
+		return Config{}, fmt.Errorf("RATE_LIMIT_STORE must be memory or sqlite, got %q", cfg.RateLimitStore)
+	}
+
+	if cfg.TokenSecret != "" && len(cfg.TokenSecret) < minTokenSecretLen {
+		return Config{}, fmt.Errorf("AUTH_TOKEN_SECRET must be at least %d characters", minTokenSecretLen)
+	}
+
+	return cfg, nil
+}
+
+// Redacted returns a copy of the config with the settings tagged as secret replaced, in the nested options too
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+// redact replaces the strings and string slices tagged as secret in the struct, and walks its nested structs and
+// slices of structs. Slices are copied before, so the original keeps its values. Maps and pointers are not walked,
+// secrets must not be kept in them
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		secret := t.Field(i).Tag.Get("secret") == "true"
+
+		switch field.Kind() {
+		case reflect.String:
+			if secret && field.String() != "" {
+				field.SetString(redacted)
+			}
+		case reflect.Struct:
+			redact(field)
+		case reflect.Slice:
+			elem := field.Type().Elem().Kind()
+			if field.Len() == 0 || (elem != reflect.Struct && !(secret && elem == reflect.String)) {
+				continue
+			}
+			copied := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(copied, field)
+			for j := 0; j < copied.Len(); j++ {
+				if elem == reflect.Struct {
+					redact(copied.Index(j))
+				} else if copied.Index(j).String() != "" {
+					copied.Index(j).SetString(redacted)
+				}
+			}
+			field.Set(copied)
+		}
+	}
+}
+
+// duration parses an environment variable like 30s or 5m, or returns the fallback if it's not set
+func duration(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// date parses an environment variable like 2006-01-02, as midnight UTC, or the fallback if it's not set.
+// An empty fallback is the zero time
+func date(name, fallback string) (time.Time, error) {
+	value := stringOr(name, fallback)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// boolean parses an environment variable like true or 1, or returns the fallback if it's not set
+func boolean(name string, fallback bool) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// integer parses an environment variable, or returns the fallback if it's not set
+func integer(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %s: %w", name, err)
+	}
+	return i, nil
+}
+
+// stringOr returns the environment variable, or the fallback if it's not set
+func stringOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// optional returns the fallback only if the environment variable is not set, so it can be set empty to disable a feature
+func optional(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
+// list splits a comma separated environment variable
+func list(name string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
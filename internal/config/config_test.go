@@ -0,0 +1,83 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Redacted(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want Config
+	}{
+		{
+			name: "secrets are redacted",
+			cfg:  Config{HTTPAddr: "0.0.0.0:8080", BootstrapAPIKey: "key", TokenSecret: "secret", ErrorReportDSN: "dsn"},
+			want: Config{HTTPAddr: "0.0.0.0:8080", BootstrapAPIKey: redacted, TokenSecret: redacted, ErrorReportDSN: redacted},
+		},
+		{
+			name: "empty secrets stay empty",
+			cfg:  Config{HTTPAddr: "0.0.0.0:8080"},
+			want: Config{HTTPAddr: "0.0.0.0:8080"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.Redacted()
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// options have secrets nested in structs and slices, like the options of the packages in Config
+type options struct {
+	Name     string
+	Password string   `secret:"true"`
+	Tokens   []string `secret:"true"`
+	Scopes   []string
+	Upstream upstream
+	Replicas []upstream
+	Headers  map[string]string
+	private  string
+}
+
+type upstream struct {
+	URL      string
+	Password string `secret:"true"`
+}
+
+func TestRedact(t *testing.T) {
+	opts := options{
+		Name:     "primary",
+		Password: "password",
+		Tokens:   []string{"token", ""},
+		Scopes:   []string{"articles:read"},
+		Upstream: upstream{URL: "http://upstream", Password: "upstream"},
+		Replicas: []upstream{{URL: "http://replica", Password: "replica"}, {URL: "http://open"}},
+		Headers:  map[string]string{"Authorization": "kept"},
+		private:  "private",
+	}
+	original := opts
+	original.Tokens = append([]string(nil), opts.Tokens...)
+	original.Replicas = append([]upstream(nil), opts.Replicas...)
+
+	got := opts
+	redact(reflect.ValueOf(&got).Elem())
+
+	assert.Equal(t, options{
+		Name:     "primary",
+		Password: redacted,
+		Tokens:   []string{redacted, ""},
+		Scopes:   []string{"articles:read"},
+		Upstream: upstream{URL: "http://upstream", Password: redacted},
+		Replicas: []upstream{{URL: "http://replica", Password: redacted}, {URL: "http://open"}},
+		Headers:  map[string]string{"Authorization": "kept"},
+		private:  "private",
+	}, got)
+	// the slices of the original are not modified
+	assert.Equal(t, original, opts)
+}
@@ -4,3 +4,15 @@ import "fmt"
 
 // ErrEntityNotFound is returned (wrapped) when an entity is not found
 var ErrEntityNotFound = fmt.Errorf("entity not found")
+
+// ErrInvalidArgument is returned (wrapped) when the input of a usecase is not valid
+var ErrInvalidArgument = fmt.Errorf("invalid argument")
+
+// ErrUnauthenticated is returned (wrapped) when the credentials of a request are missing or not valid
+var ErrUnauthenticated = fmt.Errorf("unauthenticated")
+
+// ErrConflict is returned (wrapped) when an entity collides with an existing one
+var ErrConflict = fmt.Errorf("conflict")
+
+// ErrForbidden is returned (wrapped) when the principal is not allowed to perform an action
+var ErrForbidden = fmt.Errorf("forbidden")
@@ -0,0 +1,18 @@
+package consts
+
+// Types of article events
+const (
+	EventArticleCreated = "created"
+	EventArticleUpdated = "updated"
+	EventArticleDeleted = "deleted"
+)
+
+// EventTypes are all the types of article events
+var EventTypes = []string{EventArticleCreated, EventArticleUpdated, EventArticleDeleted}
+
+// Statuses of a webhook delivery
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
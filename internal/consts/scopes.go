@@ -0,0 +1,35 @@
+package consts
+
+// Scopes, or permissions, that can be granted to an API key or to a role
+const (
+	ScopeArticlesRead    = "articles:read"
+	ScopeArticlesWrite   = "articles:write"
+	ScopeArticlesEditAny = "articles:edit_any"
+	ScopeKeysAdmin       = "keys:admin"
+	ScopeUsersAdmin      = "users:admin"
+	ScopeWebhooksAdmin   = "webhooks:admin"
+)
+
+// Scopes are all the known scopes
+var Scopes = []string{ScopeArticlesRead, ScopeArticlesWrite, ScopeArticlesEditAny, ScopeKeysAdmin, ScopeUsersAdmin, ScopeWebhooksAdmin}
+
+// Kinds of principal, depending on how they authenticated. Users log in with a password and get tokens of
+// the local signer, jwt are the tokens of other issuers
+const (
+	PrincipalAPIKey     = "api_key"
+	PrincipalJWT        = "jwt"
+	PrincipalUser       = "user"
+	PrincipalClientCert = "client_cert"
+)
+
+// Roles that can be granted to a principal, their permissions live in the database
+// editors and admins can modify articles of any author
+const (
+	RoleReader = "reader"
+	RoleAuthor = "author"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles are all the known roles
+var Roles = []string{RoleReader, RoleAuthor, RoleEditor, RoleAdmin}
@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// APIKey is a credential used to authenticate against the API
+// Only the hash of the key is stored, the plain key is returned once when created or rotated
+type APIKey struct {
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	Roles     []string   `json:"roles"`
+	Key       string     `json:"key,omitempty"`
+	Hash      string     `json:"-"`
+}
@@ -11,3 +11,27 @@ type Article struct {
 	Content   string    `json:"content"`
 	Author    string    `json:"author"`
 }
+
+// ArticleFilter selects articles sorted by creation date, the zero value selects all of them
+type ArticleFilter struct {
+	IDs           []string
+	Authors       []string
+	TitleContains string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// AfterCreatedAt and AfterID skip the articles up to that one, to continue a previous page
+	AfterCreatedAt time.Time
+	AfterID        string
+	// Limit bounds the number of articles, 0 for no limit
+	Limit int
+	// LimitPerAuthor bounds the number of articles of each author, the first ones, 0 for no limit
+	LimitPerAuthor int
+}
+
+// ArticleEvent is a change of an article, the ids of the events grow in the order they happened
+type ArticleEvent struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	Article    Article   `json:"article"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
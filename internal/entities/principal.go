@@ -0,0 +1,37 @@
+package entities
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string   `json:"subject"`
+	Kind    string   `json:"kind"`
+	Scopes  []string `json:"scopes"`
+	Roles   []string `json:"roles"`
+}
+
+// ID identifies the principal across kinds, like user:alice, as the subjects of different kinds can be the same:
+// an api key can be named like a user
+func (p Principal) ID() string {
+	return p.Kind + ":" + p.Subject
+}
+
+// HasScope reports whether the principal was granted the scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the principal was granted any of the roles
+func (p Principal) HasRole(roles ...string) bool {
+	for _, r := range p.Roles {
+		for _, role := range roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+// User is an account that can log in with a password
+type User struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	Username     string    `json:"username"`
+	Roles        []string  `json:"roles"`
+	PasswordHash string    `json:"-"`
+}
+
+// Credentials are used to log in
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Token is an access token issued on log in
+type Token struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int64  `json:"expiresIn"`
+}
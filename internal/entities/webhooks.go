@@ -0,0 +1,35 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is a subscription of a partner to the article events, they are posted to its URL
+// signed with the secret, which is returned once when created
+type Webhook struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+// WebhookDelivery is an event queued to be posted to a webhook, and the result of its last attempt
+type WebhookDelivery struct {
+	ID        string          `json:"id"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	WebhookID string          `json:"webhookId"`
+	EventID   int64           `json:"eventId"`
+	EventType string          `json:"eventType"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// NextAttemptAt is when a pending delivery is sent, and the time of the last attempt once it succeeded or failed
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	// ResponseStatus and Error are the result of the last attempt
+	ResponseStatus int    `json:"responseStatus,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
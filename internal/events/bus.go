@@ -0,0 +1,195 @@
+// Package events fans out the article events of a log to the subscribers of the process,
+// so subscribers can resume after the last event they got
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+)
+
+// ErrClosed is returned once the bus is closed, when the server shuts down
+var ErrClosed = errors.New("event bus is closed")
+
+// replayPage is how many events are read from the log at once when a subscription catches up
+const replayPage = 100
+
+// Log is where the events are stored before they are published, Memory or the outbox of the database
+type Log interface {
+	// Since returns up to limit events after the id, oldest first
+	Since(ctx context.Context, afterID int64, limit int) ([]entities.ArticleEvent, error)
+	// Last returns the id of the last event, 0 if there are none
+	Last(ctx context.Context) (int64, error)
+}
+
+// Bus publishes the events of the log to the subscriptions
+type Bus struct {
+	log    Log
+	buffer int
+
+	// publishing keeps the subscriptions from starting in the middle of a fan out
+	publishing sync.Mutex
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	done   chan struct{}
+}
+
+// NewBus is the Bus constructor, buffer is how many events a subscription can fall behind
+// before it catches up from the log
+func NewBus(log Log, buffer int) *Bus {
+	return &Bus{
+		log:    log,
+		buffer: buffer,
+		subs:   map[*Subscription]struct{}{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish sends an event that is already in the log to the subscriptions, it never blocks on them
+// Events must be published in the order of their ids, once the bus is closed they are dropped
+func (b *Bus) Publish(ctx context.Context, event entities.ArticleEvent) error {
+	b.publishing.Lock()
+	defer b.publishing.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		select {
+		case sub.live <- event:
+		default:
+			// the subscription reads this event, and the next ones, from the log
+			select {
+			case sub.lagged <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a subscription to the events added to the log from now on
+func (b *Bus) Subscribe(ctx context.Context) (*Subscription, error) {
+	// no event is published between reading the last id and registering the subscription
+	b.publishing.Lock()
+	defer b.publishing.Unlock()
+
+	last, err := b.log.Last(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.subscribe(last, false)
+}
+
+// Resume returns a subscription to the events after the id, the missed ones are read from the log.
+// An id after the last event of the log is not one of its events, e.g. the log was reset, so the whole
+// log is replayed instead of skipping the events up to that id
+func (b *Bus) Resume(ctx context.Context, afterID int64) (*Subscription, error) {
+	last, err := b.log.Last(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if afterID > last {
+		logging.FromContext(ctx).WithField("after", afterID).WithField("last", last).Warn("resumed after an event the log does not have, replaying it all")
+		afterID = 0
+	}
+	return b.subscribe(afterID, true)
+}
+
+func (b *Bus) subscribe(afterID int64, replay bool) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &Subscription{
+		bus:       b,
+		live:      make(chan entities.ArticleEvent, b.buffer),
+		lagged:    make(chan struct{}, 1),
+		last:      afterID,
+		replaying: replay,
+	}
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close ends all the subscriptions
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+	b.subs = map[*Subscription]struct{}{}
+}
+
+// Subscription receives the events of a bus in order, it must be used by a single goroutine
+type Subscription struct {
+	bus    *Bus
+	live   chan entities.ArticleEvent
+	lagged chan struct{}
+
+	last      int64
+	replaying bool
+	pending   []entities.ArticleEvent
+}
+
+// Next blocks until the next event. It returns ErrClosed when the bus is closed,
+// or the error of the context when it's done
+func (s *Subscription) Next(ctx context.Context) (entities.ArticleEvent, error) {
+	for {
+		select {
+		case <-s.bus.done:
+			return entities.ArticleEvent{}, ErrClosed
+		default:
+		}
+
+		if s.replaying {
+			if len(s.pending) == 0 {
+				events, err := s.bus.log.Since(ctx, s.last, replayPage)
+				if err != nil {
+					return entities.ArticleEvent{}, err
+				}
+				if len(events) == 0 {
+					s.replaying = false
+					continue
+				}
+				s.pending = events
+			}
+			event := s.pending[0]
+			s.pending = s.pending[1:]
+			s.last = event.ID
+			return event, nil
+		}
+
+		select {
+		case event := <-s.live:
+			// events already replayed from the log are skipped
+			if event.ID <= s.last {
+				continue
+			}
+			s.last = event.ID
+			return event, nil
+		case <-s.lagged:
+			s.replaying = true
+		case <-s.bus.done:
+			return entities.ArticleEvent{}, ErrClosed
+		case <-ctx.Done():
+			return entities.ArticleEvent{}, ctx.Err()
+		}
+	}
+}
+
+// Close stops sending events to the subscription
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	delete(s.bus.subs, s)
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events/eventstest"
+)
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+	publish := func(b *Bus, ids ...string) {
+		for _, id := range ids {
+			event, err := b.log.(*eventstest.Memory).Append(ctx, entities.ArticleEvent{Type: "created", Article: entities.Article{ID: id}})
+			assert.NoError(t, err)
+			assert.NoError(t, b.Publish(ctx, event))
+		}
+	}
+	next := func(sub *Subscription, n int) []string {
+		var ids []string
+		for i := 0; i < n; i++ {
+			event, err := sub.Next(ctx)
+			assert.NoError(t, err)
+			ids = append(ids, event.Article.ID)
+		}
+		return ids
+	}
+
+	t.Run("subscriptions get the events published after they start", func(t *testing.T) {
+		b := NewBus(eventstest.NewMemory(), 10)
+		publish(b, "a")
+		sub, err := b.Subscribe(ctx)
+		assert.NoError(t, err)
+		publish(b, "b", "c")
+		assert.Equal(t, []string{"b", "c"}, next(sub, 2))
+	})
+
+	t.Run("resumed subscriptions replay the missed events first", func(t *testing.T) {
+		b := NewBus(eventstest.NewMemory(), 10)
+		publish(b, "a", "b", "c")
+		sub, err := b.Resume(ctx, 1)
+		assert.NoError(t, err)
+		publish(b, "d")
+		assert.Equal(t, []string{"b", "c", "d"}, next(sub, 3))
+	})
+
+	t.Run("resuming after the last event of the log replays it all", func(t *testing.T) {
+		b := NewBus(eventstest.NewMemory(), 10)
+		publish(b, "a", "b")
+		sub, err := b.Resume(ctx, 5)
+		assert.NoError(t, err)
+		publish(b, "c")
+		assert.Equal(t, []string{"a", "b", "c"}, next(sub, 3))
+	})
+
+	t.Run("slow subscriptions catch up from the log", func(t *testing.T) {
+		b := NewBus(eventstest.NewMemory(), 1)
+		sub, err := b.Subscribe(ctx)
+		assert.NoError(t, err)
+		publish(b, "a", "b", "c", "d")
+		assert.Equal(t, []string{"a", "b", "c", "d"}, next(sub, 4))
+
+		// nothing is received twice
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = sub.Next(timeout)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("closing ends the subscriptions", func(t *testing.T) {
+		b := NewBus(eventstest.NewMemory(), 10)
+		sub, err := b.Subscribe(ctx)
+		assert.NoError(t, err)
+
+		go b.Close()
+		_, err = sub.Next(ctx)
+		assert.Equal(t, ErrClosed, err)
+
+		_, err = b.Subscribe(ctx)
+		assert.Equal(t, ErrClosed, err)
+		// events published after closing are dropped
+		publish(b, "a")
+	})
+}
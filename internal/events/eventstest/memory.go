@@ -0,0 +1,51 @@
+// Package eventstest has an event log in memory, for the tests of the bus and its subscribers
+package eventstest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// Memory is an event log in the process memory, the ids of the appended events start at 1
+type Memory struct {
+	mu     sync.Mutex
+	events []entities.ArticleEvent
+}
+
+// NewMemory is the Memory constructor
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Append stores the event with the next id, it's published to the bus afterwards
+func (m *Memory) Append(ctx context.Context, event entities.ArticleEvent) (entities.ArticleEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.ID = int64(len(m.events) + 1)
+	m.events = append(m.events, event)
+	return event, nil
+}
+
+// Since returns up to limit events after the id
+func (m *Memory) Since(ctx context.Context, afterID int64, limit int) ([]entities.ArticleEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start := sort.Search(len(m.events), func(i int) bool { return m.events[i].ID > afterID })
+	end := len(m.events)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	return append([]entities.ArticleEvent(nil), m.events[start:end]...), nil
+}
+
+// Last returns the id of the last event
+func (m *Memory) Last(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.events)), nil
+}
@@ -0,0 +1,203 @@
+package graphqltransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// maxBodyBytes bounds the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// jsonOnly decodes the request bodies, GraphQL is only spoken in JSON
+var jsonOnly = render.NewNegotiator(render.JSONCodec{})
+
+// GraphQL serves the articles as a GraphQL schema
+type GraphQL struct {
+	usecase ArticlesUsecase
+	authz   middlewares.Authorizer
+	schema  graphql.Schema
+	limits  Limits
+}
+
+// NewGraphQL builds the schema, resolved by the usecase. Mutations need articles:write, checked by authz
+func NewGraphQL(au ArticlesUsecase, authz middlewares.Authorizer, limits Limits) (GraphQL, error) {
+	schema, err := newSchema(au)
+	if err != nil {
+		return GraphQL{}, fmt.Errorf("could not build graphql schema: %w", err)
+	}
+	return GraphQL{usecase: au, authz: authz, schema: schema, limits: limits}, nil
+}
+
+// params are the parameters of an operation, as sent by the clients
+type params struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query executes the operations of the JSON body, a single one or an array of them.
+// The response is always a 200 with the results, or the errors, of the operations in the same order
+func (g GraphQL) Query(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+
+	var body json.RawMessage
+	if status, err := decodeBody(w, r, &body); err != nil {
+		log.WithError(err).Warn("could not decode graphql request")
+		render.WriteProblem(w, r, status, err.Error())
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []params
+		if err := json.Unmarshal(body, &batch); err != nil {
+			render.WriteProblem(w, r, http.StatusBadRequest, "request body must be an operation or an array of operations")
+			return
+		}
+		if len(batch) == 0 {
+			render.WriteProblem(w, r, http.StatusBadRequest, "a batch must have at least one operation")
+			return
+		}
+		if g.limits.MaxBatch > 0 && len(batch) > g.limits.MaxBatch {
+			render.WriteProblem(w, r, http.StatusBadRequest, fmt.Sprintf("a batch can have at most %d operations", g.limits.MaxBatch))
+			return
+		}
+
+		results := make([]*graphql.Result, len(batch))
+		for i, p := range batch {
+			results[i] = g.execute(r, p)
+		}
+		render.JSON(w, r, http.StatusOK, results)
+		return
+	}
+
+	var p params
+	if err := json.Unmarshal(body, &p); err != nil {
+		render.WriteProblem(w, r, http.StatusBadRequest, "request body must be an operation or an array of operations")
+		return
+	}
+	render.JSON(w, r, http.StatusOK, g.execute(r, p))
+}
+
+// decodeBody reads the JSON body, returning the status of the problem if it can't
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) (int, error) {
+	codec, ok := jsonOnly.Codec(r.Header.Get("Content-Type"))
+	if !ok {
+		return http.StatusUnsupportedMediaType, errors.New("Content-Type must be application/json")
+	}
+	if err := codec.Decode(http.MaxBytesReader(w, r.Body, maxBodyBytes), v); err != nil {
+		if strings.HasSuffix(err.Error(), "http: request body too large") {
+			return http.StatusRequestEntityTooLarge, fmt.Errorf("request body must not be larger than %d bytes", maxBodyBytes)
+		}
+		return http.StatusBadRequest, errors.New("request body is not valid JSON")
+	}
+	return 0, nil
+}
+
+// execute runs an operation with its own loaders, once it's valid and within the limits
+func (g GraphQL) execute(r *http.Request, p params) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(p.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if validation := graphql.ValidateDocument(&g.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	op, err := operation(doc, p.OperationName)
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if err := g.limits.check(doc, op, p.Variables); err != nil {
+		logging.FromContext(r.Context()).WithError(err).Warn("graphql query over the limits")
+		return &graphql.Result{Errors: gqlError{msg: err.Error(), code: codeTooComplex}.formatted()}
+	}
+	if op.Operation == ast.OperationTypeMutation {
+		if err := g.authz.Can(r.Context(), consts.ScopeArticlesWrite); err != nil {
+			if !errors.Is(err, consts.ErrForbidden) {
+				logging.FromContext(r.Context()).WithError(err).Error("could not check scope")
+				return &graphql.Result{Errors: gqlError{msg: "internal error", code: codeInternal}.formatted()}
+			}
+			err := gqlError{msg: "mutations require the " + consts.ScopeArticlesWrite + " scope", code: codeForbidden}
+			return &graphql.Result{Errors: err.formatted()}
+		}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        g.schema,
+		AST:           doc,
+		OperationName: p.OperationName,
+		Args:          p.Variables,
+		Context:       withLoaders(r.Context(), newLoaders(g.usecase)),
+	})
+}
+
+// operation returns the operation of the document to execute
+func operation(doc *ast.Document, name string) (*ast.OperationDefinition, error) {
+	var ops []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if name != "" && op.Name != nil && op.Name.Value == name {
+				return op, nil
+			}
+			ops = append(ops, op)
+		}
+	}
+
+	switch {
+	case name != "":
+		return nil, fmt.Errorf("unknown operation named %q", name)
+	case len(ops) != 1:
+		return nil, errors.New("operationName is required when the document has many operations")
+	default:
+		return ops[0], nil
+	}
+}
+
+// Playground serves GraphiQL, to explore the schema and run queries from the browser
+func (g GraphQL) Playground(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprint(len(playground)))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(playground))
+}
+
+// playground loads React and GraphiQL from unpkg.com, like Swagger UI the assets aren't served by the binary.
+// The API key is set in the headers tab
+const playground = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Articles GraphQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@1.4.7/graphiql.min.css">
+  <style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+</head>
+<body>
+  <div id="graphiql"></div>
+  <script src="https://unpkg.com/react@17/umd/react.production.min.js" crossorigin></script>
+  <script src="https://unpkg.com/react-dom@17/umd/react-dom.production.min.js" crossorigin></script>
+  <script src="https://unpkg.com/graphiql@1.4.7/graphiql.min.js" crossorigin></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: window.location.href });
+    ReactDOM.render(
+      React.createElement(GraphiQL, { fetcher: fetcher, headerEditorEnabled: true, headers: '{"X-API-Key": ""}' }),
+      document.getElementById('graphiql'),
+    );
+  </script>
+</body>
+</html>
+`
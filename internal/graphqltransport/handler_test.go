@@ -0,0 +1,218 @@
+package graphqltransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/graphqltransport/mocks"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases"
+)
+
+func TestGraphQL_Query(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	alice1 := entities.Article{ID: "1", Title: "first", Author: "alice", CreatedAt: start}
+	alice2 := entities.Article{ID: "2", Title: "second", Author: "alice", CreatedAt: start.Add(time.Hour)}
+	bob := entities.Article{ID: "3", Title: "third", Author: "bob", CreatedAt: start.Add(2 * time.Hour)}
+
+	tests := []struct {
+		name   string
+		body   string
+		scopes []string
+		roles  []string
+		mock   func(m *mocks.MockArticlesUsecase)
+		want   string
+	}{
+		{
+			name: "authors of a page are loaded in one batch",
+			body: `{"query": "{ articles(first: 2) { nodes { id author { name articles { id } } } pageInfo { hasNextPage } } }"}`,
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Find(gomock.Any(), entities.ArticleFilter{Limit: 3}).Return([]entities.Article{alice1, alice2, bob}, nil)
+				m.EXPECT().Find(gomock.Any(), entities.ArticleFilter{Authors: []string{"alice"}, LimitPerAuthor: defaultPageSize}).Return([]entities.Article{alice1, alice2}, nil)
+			},
+			want: `{"data": {"articles": {
+				"nodes": [
+					{"id": "1", "author": {"name": "alice", "articles": [{"id": "1"}, {"id": "2"}]}},
+					{"id": "2", "author": {"name": "alice", "articles": [{"id": "1"}, {"id": "2"}]}}
+				],
+				"pageInfo": {"hasNextPage": true}
+			}}}`,
+		},
+		{
+			name: "articles of the authors are limited by the store",
+			body: `{"query": "{ article(id: \"3\") { author { one: articles(first: 1) { id } all: articles { id } } } }"}`,
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Find(gomock.Any(), entities.ArticleFilter{IDs: []string{"3"}}).Return([]entities.Article{bob}, nil)
+				m.EXPECT().Find(gomock.Any(), entities.ArticleFilter{Authors: []string{"bob"}, LimitPerAuthor: 1}).Return([]entities.Article{bob}, nil)
+				m.EXPECT().Find(gomock.Any(), entities.ArticleFilter{Authors: []string{"bob"}, LimitPerAuthor: defaultPageSize}).Return([]entities.Article{bob}, nil)
+			},
+			want: `{"data": {"article": {"author": {"one": [{"id": "3"}], "all": [{"id": "3"}]}}}}`,
+		},
+		{
+			name: "batched operations and ids",
+			body: `[
+				{"query": "query One($id: ID!) { article(id: $id) { title } missing: article(id: \"4\") { title } }", "variables": {"id": "3"}},
+				{"query": "{ article(id: \"1\") { title } }"}
+			]`,
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Find(gomock.Any(), entities.ArticleFilter{IDs: []string{"3", "4"}}).Return([]entities.Article{bob}, nil)
+				m.EXPECT().Find(gomock.Any(), entities.ArticleFilter{IDs: []string{"1"}}).Return([]entities.Article{alice1}, nil)
+			},
+			want: `[
+				{"data": {"article": {"title": "third"}, "missing": null}},
+				{"data": {"article": {"title": "first"}}}
+			]`,
+		},
+		{
+			name:   "mutation",
+			body:   `{"query": "mutation { deleteArticle(id: \"1\") }"}`,
+			scopes: []string{consts.ScopeArticlesWrite},
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Delete(gomock.Any(), "1").Return(nil)
+			},
+			want: `{"data": {"deleteArticle": "1"}}`,
+		},
+		{
+			name:  "mutation with write granted by a role",
+			body:  `{"query": "mutation { deleteArticle(id: \"1\") }"}`,
+			roles: []string{consts.RoleAuthor},
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Delete(gomock.Any(), "1").Return(nil)
+			},
+			want: `{"data": {"deleteArticle": "1"}}`,
+		},
+		{
+			name: "mutation without write scope",
+			body: `{"query": "mutation { deleteArticle(id: \"1\") }"}`,
+			mock: func(m *mocks.MockArticlesUsecase) {},
+			want: `{"data": null, "errors": [{"message": "mutations require the articles:write scope", "locations": [], "extensions": {"code": "FORBIDDEN"}}]}`,
+		},
+		{
+			name:   "usecase error",
+			body:   `{"query": "mutation { deleteArticle(id: \"1\") }"}`,
+			scopes: []string{consts.ScopeArticlesWrite},
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Delete(gomock.Any(), "1").Return(fmt.Errorf("could not delete article: %w", consts.ErrForbidden))
+			},
+			want: `{"data": null, "errors": [{"message": "forbidden", "locations": [{"line": 1, "column": 12}], "path": ["deleteArticle"], "extensions": {"code": "FORBIDDEN"}}]}`,
+		},
+		{
+			name: "too deep",
+			body: `{"query": "{ article(id: \"1\") { author { articles { author { articles { author { name } } } } } } }"}`,
+			mock: func(m *mocks.MockArticlesUsecase) {},
+			want: `{"data": null, "errors": [{"message": "query has depth 7, the limit is 6", "locations": [], "extensions": {"code": "QUERY_TOO_COMPLEX"}}]}`,
+		},
+		{
+			name: "invalid cursor",
+			body: `{"query": "{ articles(after: \"nope\") { nodes { id } } }"}`,
+			mock: func(m *mocks.MockArticlesUsecase) {},
+			want: `{"data": null, "errors": [{"message": "after is not a valid cursor", "locations": [{"line": 1, "column": 3}], "path": ["articles"], "extensions": {"code": "BAD_USER_INPUT"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := mocks.NewMockArticlesUsecase(ctrl)
+			tt.mock(m)
+
+			g, err := NewGraphQL(m, usecases.NewPolicy(rolePermissions{}), Limits{MaxDepth: 6, MaxComplexity: 1000, MaxBatch: 2})
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			principal := entities.Principal{Subject: "alice", Scopes: append([]string{consts.ScopeArticlesRead}, tt.scopes...), Roles: tt.roles}
+			req = req.WithContext(middlewares.WithPrincipal(context.Background(), principal))
+			rec := httptest.NewRecorder()
+			g.Query(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, tt.want, rec.Body.String())
+		})
+	}
+}
+
+func TestGraphQL_Query_BadRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantDetail  string
+	}{
+		{
+			name:        "not json",
+			contentType: "application/graphql",
+			body:        `{ articles { nodes { id } } }`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantDetail:  "Content-Type must be application/json",
+		},
+		{
+			name:        "empty batch",
+			contentType: "application/json",
+			body:        `[]`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "a batch must have at least one operation",
+		},
+		{
+			name:        "batch too large",
+			contentType: "application/json",
+			body:        `[{"query": "{ a }"}, {"query": "{ b }"}, {"query": "{ c }"}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "a batch can have at most 2 operations",
+		},
+		{
+			name:        "not an operation",
+			contentType: "application/json",
+			body:        `"query"`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body must be an operation or an array of operations",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			g, err := NewGraphQL(mocks.NewMockArticlesUsecase(ctrl), usecases.NewPolicy(rolePermissions{}), Limits{MaxBatch: 2})
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			g.Query(rec, req)
+
+			var problem struct {
+				Detail string `json:"detail"`
+			}
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.wantDetail, problem.Detail)
+		})
+	}
+}
+
+// rolePermissions grants articles:write to the author role, as seeded in the database
+type rolePermissions struct{}
+
+func (rolePermissions) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	for _, role := range roles {
+		if role == consts.RoleAuthor {
+			return []string{consts.ScopeArticlesRead, consts.ScopeArticlesWrite}, nil
+		}
+	}
+	return nil, nil
+}
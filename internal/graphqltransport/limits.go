@@ -0,0 +1,97 @@
+package graphqltransport
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound the cost of a query, they are checked before it's executed
+type Limits struct {
+	// MaxDepth is the deepest nesting of selections, 0 for no limit
+	MaxDepth int
+	// MaxComplexity is the highest complexity, 0 for no limit.
+	// Every field costs 1, and the fields under a list cost as many times as the items it can return
+	MaxComplexity int
+	// MaxBatch is the most operations a request can send at once, 0 for no limit
+	MaxBatch int
+}
+
+// cost is the depth and complexity of a selection
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// check returns an error if the operation goes over the limits
+func (l Limits) check(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	c := selectionCost(op.SelectionSet, fragments, variables)
+	if l.MaxDepth > 0 && c.depth > l.MaxDepth {
+		return fmt.Errorf("query has depth %d, the limit is %d", c.depth, l.MaxDepth)
+	}
+	if l.MaxComplexity > 0 && c.complexity > l.MaxComplexity {
+		return fmt.Errorf("query has complexity %d, the limit is %d", c.complexity, l.MaxComplexity)
+	}
+	return nil
+}
+
+// selectionCost walks the selections, fragments were already checked for cycles by the validation
+func selectionCost(set *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, variables map[string]interface{}) cost {
+	var total cost
+	if set == nil {
+		return total
+	}
+
+	for _, selection := range set.Selections {
+		var c cost
+		switch s := selection.(type) {
+		case *ast.Field:
+			children := selectionCost(s.SelectionSet, fragments, variables)
+			c = cost{depth: children.depth + 1, complexity: 1 + multiplier(s, variables)*children.complexity}
+		case *ast.InlineFragment:
+			c = selectionCost(s.SelectionSet, fragments, variables)
+		case *ast.FragmentSpread:
+			if fragment, ok := fragments[s.Name.Value]; ok {
+				c = selectionCost(fragment.SelectionSet, fragments, variables)
+			}
+		}
+
+		if c.depth > total.depth {
+			total.depth = c.depth
+		}
+		total.complexity += c.complexity
+	}
+	return total
+}
+
+// multiplier is the number of items a field can return: the page size for the list fields, 1 otherwise
+func multiplier(field *ast.Field, variables map[string]interface{}) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		n := defaultPageSize
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			n, _ = strconv.Atoi(v.Value)
+		case *ast.Variable:
+			// variables are decoded from JSON
+			if f, ok := variables[v.Name.Value].(float64); ok {
+				n = int(f)
+			}
+		}
+		return pageSize(n)
+	}
+	if listFields[field.Name.Value] {
+		return defaultPageSize
+	}
+	return 1
+}
@@ -0,0 +1,69 @@
+package graphqltransport
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_check(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		limits    Limits
+		wantErr   string
+	}{
+		{
+			name:   "within limits",
+			query:  `{ article(id: "1") { id title } }`,
+			limits: Limits{MaxDepth: 2, MaxComplexity: 3},
+		},
+		{
+			name:    "too deep",
+			query:   `{ article(id: "1") { author { name } } }`,
+			limits:  Limits{MaxDepth: 2},
+			wantErr: "query has depth 3, the limit is 2",
+		},
+		{
+			name:    "lists cost their default page size",
+			query:   `{ articles { nodes { id } } }`,
+			limits:  Limits{MaxComplexity: 40},
+			wantErr: "query has complexity 41, the limit is 40",
+		},
+		{
+			name:      "first from a variable, bounded by the max page size",
+			query:     `query($n: Int) { articles(first: $n) { nodes { id } } }`,
+			variables: map[string]interface{}{"n": float64(1000)},
+			limits:    Limits{MaxComplexity: 200},
+			wantErr:   "query has complexity 201, the limit is 200",
+		},
+		{
+			name:    "fragments are counted",
+			query:   `{ article(id: "1") { ...fields } } fragment fields on Article { id author { name } }`,
+			limits:  Limits{MaxDepth: 2},
+			wantErr: "query has depth 3, the limit is 2",
+		},
+		{
+			name:  "no limits",
+			query: `{ articles(first: 100) { nodes { author { articles(first: 100) { id } } } } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			assert.NoError(t, err)
+			op, err := operation(doc, "")
+			assert.NoError(t, err)
+
+			err = tt.limits.check(doc, op, tt.variables)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
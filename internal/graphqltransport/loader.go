@@ -0,0 +1,120 @@
+package graphqltransport
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// batchFunc loads the articles of many keys at once, grouped by key
+type batchFunc func(ctx context.Context, keys []string) (map[string][]entities.Article, error)
+
+// loader batches the lookups of the resolvers of a request into a single call to the usecase.
+// Load only queues the key, the batch runs when the first thunk is called, which the executor does
+// after all the fields of the same level were resolved. Results are cached for the rest of the request
+type loader struct {
+	batch batchFunc
+
+	mu      sync.Mutex
+	pending []string
+	cache   map[string]*result
+}
+
+// result is the outcome of the batch that loaded a key
+type result struct {
+	done     chan struct{}
+	articles []entities.Article
+	err      error
+}
+
+func newLoader(batch batchFunc) *loader {
+	return &loader{batch: batch, cache: map[string]*result{}}
+}
+
+// Load returns a thunk that resolves to the articles of the key
+func (l *loader) Load(ctx context.Context, key string) func() ([]entities.Article, error) {
+	l.mu.Lock()
+	res, ok := l.cache[key]
+	if !ok {
+		res = &result{done: make(chan struct{})}
+		l.cache[key] = res
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() ([]entities.Article, error) {
+		l.dispatch(ctx)
+		<-res.done
+		return res.articles, res.err
+	}
+}
+
+// dispatch runs the batch of the pending keys, if any
+func (l *loader) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	keys := l.pending
+	l.pending = nil
+	// sibling fields are resolved in any order, sorted keys make the same query
+	sort.Strings(keys)
+	results := make([]*result, len(keys))
+	for i, key := range keys {
+		results[i] = l.cache[key]
+	}
+	l.mu.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+
+	byKey, err := l.batch(ctx, keys)
+	for i, key := range keys {
+		results[i].articles, results[i].err = byKey[key], err
+		close(results[i].done)
+	}
+}
+
+// loaders are the loaders of a request, the articles of the authors are loaded by how many of them are asked
+type loaders struct {
+	byID *loader
+
+	usecase  ArticlesUsecase
+	mu       *sync.Mutex
+	byAuthor map[int]*loader
+}
+
+func newLoaders(usecase ArticlesUsecase) loaders {
+	return loaders{
+		byID: newLoader(func(ctx context.Context, ids []string) (map[string][]entities.Article, error) {
+			articles, err := usecase.Find(ctx, entities.ArticleFilter{IDs: ids})
+			return groupBy(articles, func(a entities.Article) string { return a.ID }), err
+		}),
+		usecase:  usecase,
+		mu:       &sync.Mutex{},
+		byAuthor: map[int]*loader{},
+	}
+}
+
+// authorArticles returns the loader of the first articles of the authors, the store only returns that many of each
+func (l loaders) authorArticles(first int) *loader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if byAuthor, ok := l.byAuthor[first]; ok {
+		return byAuthor
+	}
+
+	byAuthor := newLoader(func(ctx context.Context, authors []string) (map[string][]entities.Article, error) {
+		articles, err := l.usecase.Find(ctx, entities.ArticleFilter{Authors: authors, LimitPerAuthor: first})
+		return groupBy(articles, func(a entities.Article) string { return a.Author }), err
+	})
+	l.byAuthor[first] = byAuthor
+	return byAuthor
+}
+
+func groupBy(articles []entities.Article, key func(entities.Article) string) map[string][]entities.Article {
+	grouped := map[string][]entities.Article{}
+	for _, article := range articles {
+		grouped[key(article)] = append(grouped[key(article)], article)
+	}
+	return grouped
+}
@@ -0,0 +1,374 @@
+package graphqltransport
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+)
+
+// run go generate ./... and the mocks will be generated
+//
+//go:generate mockgen -destination=./mocks/articles_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/graphqltransport ArticlesUsecase
+
+// ArticlesUsecase describes all the functions we need from usecase layer
+type ArticlesUsecase interface {
+	Find(ctx context.Context, filter entities.ArticleFilter) ([]entities.Article, error)
+	Create(ctx context.Context, article entities.Article) (entities.Article, error)
+	Update(ctx context.Context, article entities.Article) (entities.Article, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Page sizes of the list fields
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// listFields are the fields that return many articles, their cost depends on the page size
+var listFields = map[string]bool{"articles": true}
+
+// connection is a page of articles
+type connection struct {
+	Nodes    []entities.Article `json:"nodes"`
+	PageInfo pageInfo           `json:"pageInfo"`
+}
+
+type pageInfo struct {
+	EndCursor   *string `json:"endCursor"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
+// newSchema builds the schema, resolved with the usecase and the loaders of each request
+func newSchema(usecase ArticlesUsecase) (graphql.Schema, error) {
+	article := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Article",
+		Description: "An article written by an author",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"title":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"content":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	author := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Author",
+		Description: "The principal that wrote some articles, a user or an api key",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source, nil },
+			},
+			"articles": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(article))),
+				Description: "The first articles of the author, the lookups of all the authors of a query are batched",
+				Args: graphql.FieldConfigArgument{
+					"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, _ := p.Args["first"].(int)
+					thunk := loadersFrom(p.Context).authorArticles(pageSize(first)).Load(p.Context, p.Source.(string))
+					return func() (interface{}, error) {
+						articles, err := thunk()
+						if err != nil {
+							return nil, errorFor(p.Context, err, "could not find articles of author")
+						}
+						return articles, nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	article.AddFieldConfig("author", &graphql.Field{
+		Type: graphql.NewNonNull(author),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(entities.Article).Author, nil
+		},
+	})
+
+	page := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticleConnection",
+		Fields: graphql.Fields{
+			"nodes": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(article)))},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+				Name: "PageInfo",
+				Fields: graphql.Fields{
+					"endCursor":   &graphql.Field{Type: graphql.String, Description: "The after argument of the next page"},
+					"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				},
+			}))},
+		},
+	})
+
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ArticleFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"author":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"titleContains": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"createdAfter":  &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"createdBefore": &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+
+	input := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ArticleInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"content": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"article": &graphql.Field{
+				Type:        article,
+				Description: "An article by id, null if it doesn't exist. The lookups of a query are batched",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					thunk := loadersFrom(p.Context).byID.Load(p.Context, p.Args["id"].(string))
+					return func() (interface{}, error) {
+						articles, err := thunk()
+						if err != nil {
+							return nil, errorFor(p.Context, err, "could not find article")
+						}
+						if len(articles) == 0 {
+							return nil, nil
+						}
+						return articles[0], nil
+					}, nil
+				},
+			},
+			"articles": &graphql.Field{
+				Type:        graphql.NewNonNull(page),
+				Description: "A page of the articles that match the filter, oldest first",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: filter},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+					"after":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveArticles(p, usecase)
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createArticle": &graphql.Field{
+				Type: graphql.NewNonNull(article),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					in := p.Args["input"].(map[string]interface{})
+					created, err := usecase.Create(p.Context, entities.Article{Title: in["title"].(string), Content: in["content"].(string)})
+					if err != nil {
+						return nil, errorFor(p.Context, err, "could not create article")
+					}
+					return created, nil
+				},
+			},
+			"updateArticle": &graphql.Field{
+				Type: graphql.NewNonNull(article),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					in := p.Args["input"].(map[string]interface{})
+					updated, err := usecase.Update(p.Context, entities.Article{
+						ID:      p.Args["id"].(string),
+						Title:   in["title"].(string),
+						Content: in["content"].(string),
+					})
+					if err != nil {
+						return nil, errorFor(p.Context, err, "could not update article")
+					}
+					return updated, nil
+				},
+			},
+			"deleteArticle": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Deletes an article and returns its id",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(string)
+					if err := usecase.Delete(p.Context, id); err != nil {
+						return nil, errorFor(p.Context, err, "could not delete article")
+					}
+					return id, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func resolveArticles(p graphql.ResolveParams, usecase ArticlesUsecase) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 0 {
+		return nil, gqlError{msg: "first must not be negative", code: codeBadUserInput}
+	}
+
+	var f entities.ArticleFilter
+	if in, ok := p.Args["filter"].(map[string]interface{}); ok {
+		if author, ok := in["author"].(string); ok {
+			f.Authors = []string{author}
+		}
+		f.TitleContains, _ = in["titleContains"].(string)
+		if t, ok := in["createdAfter"].(time.Time); ok {
+			f.CreatedAfter = t
+		}
+		if t, ok := in["createdBefore"].(time.Time); ok {
+			f.CreatedBefore = t
+		}
+	}
+	if after, ok := p.Args["after"].(string); ok {
+		var err error
+		if f.AfterCreatedAt, f.AfterID, err = parseCursor(after); err != nil {
+			return nil, gqlError{msg: "after is not a valid cursor", code: codeBadUserInput}
+		}
+	}
+	// one more than the page tells if there is a next one
+	f.Limit = pageSize(first) + 1
+
+	articles, err := usecase.Find(p.Context, f)
+	if err != nil {
+		return nil, errorFor(p.Context, err, "could not find articles")
+	}
+
+	page := connection{Nodes: articles}
+	if len(articles) > pageSize(first) {
+		page.Nodes = articles[:pageSize(first)]
+		page.PageInfo.HasNextPage = true
+	}
+	if page.Nodes == nil {
+		page.Nodes = []entities.Article{}
+	}
+	if len(page.Nodes) > 0 {
+		end := cursor(page.Nodes[len(page.Nodes)-1])
+		page.PageInfo.EndCursor = &end
+	}
+	return page, nil
+}
+
+// pageSize returns the page size of a first argument, bounded by maxPageSize
+func pageSize(first int) int {
+	switch {
+	case first <= 0:
+		return defaultPageSize
+	case first > maxPageSize:
+		return maxPageSize
+	default:
+		return first
+	}
+}
+
+// cursor encodes the position of an article as an opaque string
+func cursor(article entities.Article) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(article.CreatedAt.UTC().Format(time.RFC3339Nano) + " " + article.ID))
+}
+
+func parseCursor(s string) (time.Time, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	parts := strings.SplitN(string(b), " ", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", errors.New("missing id")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return createdAt, parts[1], nil
+}
+
+// Error codes of the extensions of the errors, as used by Apollo
+const (
+	codeBadUserInput    = "BAD_USER_INPUT"
+	codeNotFound        = "NOT_FOUND"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+	codeConflict        = "CONFLICT"
+	codeTooComplex      = "QUERY_TOO_COMPLEX"
+	codeInternal        = "INTERNAL_SERVER_ERROR"
+)
+
+// gqlError is an error with a code in its extensions
+type gqlError struct {
+	msg  string
+	code string
+}
+
+func (e gqlError) Error() string {
+	return e.msg
+}
+
+// Extensions are added to the error in the response
+func (e gqlError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// formatted returns the error for a result that was not executed, which only
+// gets extensions from the errors of resolvers
+func (e gqlError) formatted() []gqlerrors.FormattedError {
+	f := gqlerrors.NewFormattedError(e.msg)
+	f.Extensions = e.Extensions()
+	return []gqlerrors.FormattedError{f}
+}
+
+// errorFor maps the errors of the usecase to an error for the response, logging the unexpected ones
+func errorFor(ctx context.Context, err error, msg string) error {
+	log := logging.FromContext(ctx).WithError(err)
+	switch {
+	case errors.Is(err, consts.ErrEntityNotFound):
+		return gqlError{msg: "article not found", code: codeNotFound}
+	case errors.Is(err, consts.ErrInvalidArgument):
+		log.Warn(msg)
+		return gqlError{msg: err.Error(), code: codeBadUserInput}
+	case errors.Is(err, consts.ErrUnauthenticated):
+		log.Warn(msg)
+		return gqlError{msg: "unauthenticated", code: codeUnauthenticated}
+	case errors.Is(err, consts.ErrForbidden):
+		log.Warn(msg)
+		return gqlError{msg: "forbidden", code: codeForbidden}
+	case errors.Is(err, consts.ErrConflict):
+		log.Warn(msg)
+		return gqlError{msg: "conflict", code: codeConflict}
+	default:
+		log.Error(msg)
+		return gqlError{msg: "internal error", code: codeInternal}
+	}
+}
+
+type contextKey string
+
+func withLoaders(ctx context.Context, l loaders) context.Context {
+	return context.WithValue(ctx, contextKey("loaders"), l)
+}
+
+func loadersFrom(ctx context.Context) loaders {
+	l, _ := ctx.Value(contextKey("loaders")).(loaders)
+	return l
+}
@@ -0,0 +1,276 @@
+// Package grpctransport serves the usecases over gRPC, as package transports does over HTTP
+package grpctransport
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport/articlespb"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/transports"
+)
+
+// The generated code of the service lives in articlespb, protoc, protoc-gen-go and protoc-gen-go-grpc must be installed
+//
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative internal/grpctransport/articlespb/articles.proto
+
+// Page sizes of List
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ArticlesScopes are the scopes the methods of the service require, see RequireScope
+var ArticlesScopes = map[string]string{
+	"/articles.v1.ArticlesService/Get":    consts.ScopeArticlesRead,
+	"/articles.v1.ArticlesService/List":   consts.ScopeArticlesRead,
+	"/articles.v1.ArticlesService/Watch":  consts.ScopeArticlesRead,
+	"/articles.v1.ArticlesService/Create": consts.ScopeArticlesWrite,
+	"/articles.v1.ArticlesService/Update": consts.ScopeArticlesWrite,
+	"/articles.v1.ArticlesService/Delete": consts.ScopeArticlesWrite,
+}
+
+//go:generate mockgen -destination=./mocks/articles_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport ArticlesUsecase
+
+// ArticlesUsecase describes all the functions we need from usecase layer
+type ArticlesUsecase interface {
+	GetOne(ctx context.Context, id string) (entities.Article, error)
+	Find(ctx context.Context, filter entities.ArticleFilter) ([]entities.Article, error)
+	Create(ctx context.Context, article entities.Article) (entities.Article, error)
+	Update(ctx context.Context, article entities.Article) (entities.Article, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Articles is the gRPC transport struct, it implements articlespb.ArticlesServiceServer
+type Articles struct {
+	articlespb.UnimplementedArticlesServiceServer
+
+	usecase  ArticlesUsecase
+	events   transports.ArticleEvents
+	stop     chan struct{}
+	stopOnce *sync.Once
+}
+
+// NewArticles is the Articles transport constructor, Watch streams the events of ae
+func NewArticles(au ArticlesUsecase, ae transports.ArticleEvents) Articles {
+	return Articles{usecase: au, events: ae, stop: make(chan struct{}), stopOnce: &sync.Once{}}
+}
+
+// Stop ends the Watch streams, so the server can stop gracefully
+func (a Articles) Stop() {
+	a.stopOnce.Do(func() { close(a.stop) })
+}
+
+// Get returns one article
+func (a Articles) Get(ctx context.Context, req *articlespb.GetRequest) (*articlespb.Article, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	article, err := a.usecase.GetOne(ctx, req.GetId())
+	if err != nil {
+		return nil, errorStatus(ctx, err, "could not find article")
+	}
+	return toProto(article), nil
+}
+
+// List returns a page of articles, sorted by creation date.
+// The page token is the position of the last article of the previous page, so deletions don't skip articles
+func (a Articles) List(ctx context.Context, req *articlespb.ListRequest) (*articlespb.ListResponse, error) {
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+
+	var after cursor
+	if req.GetPageToken() != "" {
+		var err error
+		if after, err = parseCursor(req.GetPageToken()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "page_token is not valid")
+		}
+	}
+
+	// one more article than the page tells whether there is a next one
+	articles, err := a.usecase.Find(ctx, entities.ArticleFilter{AfterCreatedAt: after.createdAt, AfterID: after.id, Limit: size + 1})
+	if err != nil {
+		return nil, errorStatus(ctx, err, "could not find articles")
+	}
+
+	page := articles
+	if len(page) > size {
+		page = page[:size]
+	}
+	resp := &articlespb.ListResponse{Articles: make([]*articlespb.Article, 0, len(page))}
+	for _, article := range page {
+		resp.Articles = append(resp.Articles, toProto(article))
+	}
+	if len(articles) > size {
+		resp.NextPageToken = cursorOf(page[len(page)-1]).String()
+	}
+	return resp, nil
+}
+
+// Create creates an article
+func (a Articles) Create(ctx context.Context, req *articlespb.CreateRequest) (*articlespb.Article, error) {
+	created, err := a.usecase.Create(ctx, entities.Article{Title: req.GetTitle(), Content: req.GetContent()})
+	if err != nil {
+		return nil, errorStatus(ctx, err, "could not create article")
+	}
+	return toProto(created), nil
+}
+
+// Update updates an article
+func (a Articles) Update(ctx context.Context, req *articlespb.UpdateRequest) (*articlespb.Article, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	updated, err := a.usecase.Update(ctx, entities.Article{ID: req.GetId(), Title: req.GetTitle(), Content: req.GetContent()})
+	if err != nil {
+		return nil, errorStatus(ctx, err, "could not update article")
+	}
+	return toProto(updated), nil
+}
+
+// Delete deletes an article
+func (a Articles) Delete(ctx context.Context, req *articlespb.DeleteRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if err := a.usecase.Delete(ctx, req.GetId()); err != nil {
+		return nil, errorStatus(ctx, err, "could not delete article")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// eventTypes maps the types of the article events to the ones of the protocol
+var eventTypes = map[string]articlespb.Event_Type{
+	consts.EventArticleCreated: articlespb.Event_CREATED,
+	consts.EventArticleUpdated: articlespb.Event_UPDATED,
+	consts.EventArticleDeleted: articlespb.Event_DELETED,
+}
+
+// Watch streams the changes to the articles from now on, as they are published.
+// It ends when the client cancels or the transport is stopped
+func (a Articles) Watch(req *articlespb.WatchRequest, stream articlespb.ArticlesService_WatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-a.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	sub, err := a.events.Subscribe(ctx)
+	if err != nil {
+		return errorStatus(ctx, err, "could not subscribe to article events")
+	}
+	defer sub.Close()
+
+	for {
+		event, err := sub.Next(ctx)
+		select {
+		case <-a.stop:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		default:
+		}
+		if errors.Is(err, events.ErrClosed) {
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+		if err != nil {
+			return errorStatus(ctx, err, "could not get next article event")
+		}
+
+		if err := stream.Send(&articlespb.Event{Type: eventTypes[event.Type], Article: toProto(event.Article)}); err != nil {
+			return err
+		}
+	}
+}
+
+func toProto(article entities.Article) *articlespb.Article {
+	return &articlespb.Article{
+		Id:        article.ID,
+		CreatedAt: timestamppb.New(article.CreatedAt),
+		UpdatedAt: timestamppb.New(article.UpdatedAt),
+		Title:     article.Title,
+		Content:   article.Content,
+		Author:    article.Author,
+	}
+}
+
+// errorStatus maps the errors of the usecase to a status, logging the unexpected ones
+func errorStatus(ctx context.Context, err error, msg string) error {
+	log := logging.FromContext(ctx).WithError(err)
+	switch {
+	case errors.Is(err, consts.ErrEntityNotFound):
+		return status.Error(codes.NotFound, "article not found")
+	case errors.Is(err, consts.ErrInvalidArgument):
+		log.Warn(msg)
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, consts.ErrUnauthenticated):
+		log.Warn(msg)
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	case errors.Is(err, consts.ErrForbidden):
+		log.Warn(msg)
+		return status.Error(codes.PermissionDenied, "forbidden")
+	case errors.Is(err, consts.ErrConflict):
+		log.Warn(msg)
+		return status.Error(codes.AlreadyExists, "conflict")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		log.Error(msg)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// cursor is a position in the list of articles sorted by creation date, the id breaks ties
+type cursor struct {
+	createdAt time.Time
+	id        string
+}
+
+func cursorOf(article entities.Article) cursor {
+	return cursor{createdAt: article.CreatedAt, id: article.ID}
+}
+
+// String encodes the cursor as an opaque page token
+func (c cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.createdAt.UTC().Format(time.RFC3339Nano) + " " + c.id))
+}
+
+func parseCursor(token string) (cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor{}, err
+	}
+	parts := strings.SplitN(string(b), " ", 2)
+	if len(parts) != 2 {
+		return cursor{}, errors.New("missing id")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return cursor{}, err
+	}
+	return cursor{createdAt: createdAt, id: parts[1]}, nil
+}
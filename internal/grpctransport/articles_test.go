@@ -0,0 +1,150 @@
+package grpctransport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport/articlespb"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport/mocks"
+)
+
+func TestArticles_Get(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		mock     func(m *mocks.MockArticlesUsecase)
+		want     *articlespb.Article
+		wantCode codes.Code
+	}{
+		{
+			name: "found",
+			id:   "1",
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().GetOne(gomock.Any(), "1").Return(entities.Article{ID: "1", Title: "title"}, nil)
+			},
+			want: toProto(entities.Article{ID: "1", Title: "title"}),
+		},
+		{
+			name:     "missing id",
+			mock:     func(m *mocks.MockArticlesUsecase) {},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "not found",
+			id:   "1",
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().GetOne(gomock.Any(), "1").Return(entities.Article{}, fmt.Errorf("article id 1 not found %w", consts.ErrEntityNotFound))
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "forbidden",
+			id:   "1",
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().GetOne(gomock.Any(), "1").Return(entities.Article{}, fmt.Errorf("could not get article: %w", consts.ErrForbidden))
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "unexpected error",
+			id:   "1",
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().GetOne(gomock.Any(), "1").Return(entities.Article{}, fmt.Errorf("database is down"))
+			},
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := mocks.NewMockArticlesUsecase(ctrl)
+			tt.mock(m)
+
+			got, err := NewArticles(m, nil).Get(context.Background(), &articlespb.GetRequest{Id: tt.id})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestArticles_List(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	articles := []entities.Article{
+		{ID: "c", CreatedAt: start.Add(time.Hour)},
+		{ID: "a", CreatedAt: start},
+		{ID: "b", CreatedAt: start},
+		{ID: "d", CreatedAt: start.Add(2 * time.Hour)},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockArticlesUsecase(ctrl)
+	m.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter entities.ArticleFilter) ([]entities.Article, error) {
+		return find(articles, filter), nil
+	}).AnyTimes()
+	a := NewArticles(m, nil)
+
+	// pages are sorted by creation date and id, and chained by their tokens
+	var ids []string
+	req := &articlespb.ListRequest{PageSize: 3}
+	for pages := 0; ; pages++ {
+		resp, err := a.List(context.Background(), req)
+		assert.NoError(t, err)
+		for _, article := range resp.Articles {
+			ids = append(ids, article.Id)
+		}
+		if resp.NextPageToken == "" {
+			assert.Equal(t, 1, pages)
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, ids)
+
+	// deleting the last article of a page does not skip the next ones
+	first, err := a.List(context.Background(), &articlespb.ListRequest{PageSize: 2})
+	assert.NoError(t, err)
+	articles = append(articles[:2], articles[3:]...) // deletes b
+	second, err := a.List(context.Background(), &articlespb.ListRequest{PageSize: 2, PageToken: first.NextPageToken})
+	assert.NoError(t, err)
+	assert.Len(t, second.Articles, 2)
+	assert.Equal(t, "c", second.Articles[0].Id)
+
+	_, err = a.List(context.Background(), &articlespb.ListRequest{PageToken: "not a token"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = a.List(context.Background(), &articlespb.ListRequest{PageSize: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// find filters the articles as the store does
+func find(articles []entities.Article, filter entities.ArticleFilter) []entities.Article {
+	sorted := append([]entities.Article(nil), articles...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	var found []entities.Article
+	for _, article := range sorted {
+		after := filter.AfterCreatedAt.IsZero() || article.CreatedAt.After(filter.AfterCreatedAt) ||
+			article.CreatedAt.Equal(filter.AfterCreatedAt) && article.ID > filter.AfterID
+		if after && (filter.Limit == 0 || len(found) < filter.Limit) {
+			found = append(found, article)
+		}
+	}
+	return found
+}
@@ -0,0 +1,322 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package articlespb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ArticlesServiceClient is the client API for ArticlesService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ArticlesServiceClient interface {
+	// Get returns one article
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Article, error)
+	// List returns a page of articles, oldest first
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Create creates an article, authored by the caller
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Article, error)
+	// Update replaces the title and content of an article
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Article, error)
+	// Delete deletes an article
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Watch streams the changes to the articles until the client cancels
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (ArticlesService_WatchClient, error)
+}
+
+type articlesServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewArticlesServiceClient(cc grpc.ClientConnInterface) ArticlesServiceClient {
+	return &articlesServiceClient{cc}
+}
+
+func (c *articlesServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Article, error) {
+	out := new(Article)
+	err := c.cc.Invoke(ctx, "/articles.v1.ArticlesService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *articlesServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, "/articles.v1.ArticlesService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *articlesServiceClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Article, error) {
+	out := new(Article)
+	err := c.cc.Invoke(ctx, "/articles.v1.ArticlesService/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *articlesServiceClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Article, error) {
+	out := new(Article)
+	err := c.cc.Invoke(ctx, "/articles.v1.ArticlesService/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *articlesServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/articles.v1.ArticlesService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *articlesServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (ArticlesService_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ArticlesService_ServiceDesc.Streams[0], "/articles.v1.ArticlesService/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &articlesServiceWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ArticlesService_WatchClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type articlesServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *articlesServiceWatchClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ArticlesServiceServer is the server API for ArticlesService service.
+// All implementations must embed UnimplementedArticlesServiceServer
+// for forward compatibility
+type ArticlesServiceServer interface {
+	// Get returns one article
+	Get(context.Context, *GetRequest) (*Article, error)
+	// List returns a page of articles, oldest first
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Create creates an article, authored by the caller
+	Create(context.Context, *CreateRequest) (*Article, error)
+	// Update replaces the title and content of an article
+	Update(context.Context, *UpdateRequest) (*Article, error)
+	// Delete deletes an article
+	Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	// Watch streams the changes to the articles until the client cancels
+	Watch(*WatchRequest, ArticlesService_WatchServer) error
+	mustEmbedUnimplementedArticlesServiceServer()
+}
+
+// UnimplementedArticlesServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedArticlesServiceServer struct {
+}
+
+func (UnimplementedArticlesServiceServer) Get(context.Context, *GetRequest) (*Article, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedArticlesServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedArticlesServiceServer) Create(context.Context, *CreateRequest) (*Article, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedArticlesServiceServer) Update(context.Context, *UpdateRequest) (*Article, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedArticlesServiceServer) Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedArticlesServiceServer) Watch(*WatchRequest, ArticlesService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedArticlesServiceServer) mustEmbedUnimplementedArticlesServiceServer() {}
+
+// UnsafeArticlesServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ArticlesServiceServer will
+// result in compilation errors.
+type UnsafeArticlesServiceServer interface {
+	mustEmbedUnimplementedArticlesServiceServer()
+}
+
+func RegisterArticlesServiceServer(s grpc.ServiceRegistrar, srv ArticlesServiceServer) {
+	s.RegisterService(&ArticlesService_ServiceDesc, srv)
+}
+
+func _ArticlesService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ArticlesServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/articles.v1.ArticlesService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ArticlesServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ArticlesService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ArticlesServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/articles.v1.ArticlesService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ArticlesServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ArticlesService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ArticlesServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/articles.v1.ArticlesService/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ArticlesServiceServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ArticlesService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ArticlesServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/articles.v1.ArticlesService/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ArticlesServiceServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ArticlesService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ArticlesServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/articles.v1.ArticlesService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ArticlesServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ArticlesService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ArticlesServiceServer).Watch(m, &articlesServiceWatchServer{stream})
+}
+
+type ArticlesService_WatchServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type articlesServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *articlesServiceWatchServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ArticlesService_ServiceDesc is the grpc.ServiceDesc for ArticlesService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ArticlesService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "articles.v1.ArticlesService",
+	HandlerType: (*ArticlesServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _ArticlesService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _ArticlesService_List_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _ArticlesService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _ArticlesService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _ArticlesService_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _ArticlesService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "internal/grpctransport/articlespb/articles.proto",
+}
@@ -0,0 +1,273 @@
+package grpctransport
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+// Step runs before the handler of every call, and returns the context of the call or a status error to reject it.
+// Steps are the gRPC equivalent of the HTTP middlewares, they run in order in UnaryInterceptor and StreamInterceptor
+type Step func(ctx context.Context, method string) (context.Context, error)
+
+// UnaryInterceptor runs the steps before the unary handlers, recovers their panics and logs the calls
+func UnaryInterceptor(steps ...Step) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := time.Now()
+		ctx, err = runSteps(ctx, info.FullMethod, steps)
+		defer func() { logCall(ctx, start, err) }()
+		if err != nil {
+			return nil, err
+		}
+
+		defer recoverPanic(ctx, &err)
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor runs the steps before the stream handlers, recovers their panics and logs the calls
+func StreamInterceptor(steps ...Step) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		ctx, err := runSteps(ss.Context(), info.FullMethod, steps)
+		defer func() { logCall(ctx, start, err) }()
+		if err != nil {
+			return err
+		}
+
+		defer recoverPanic(ctx, &err)
+		return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream replaces the context of a stream with the one of the steps
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// runSteps returns the context of the last step that ran, so a rejected call is logged with what is known of it
+func runSteps(ctx context.Context, method string, steps []Step) (context.Context, error) {
+	for _, step := range steps {
+		next, err := step(ctx, method)
+		if err != nil {
+			return ctx, err
+		}
+		ctx = next
+	}
+	return ctx, nil
+}
+
+func recoverPanic(ctx context.Context, err *error) {
+	if p := recover(); p != nil {
+		logging.FromContext(ctx).WithError(stacktrace.Errorf("panic: %v", p)).Error("call panicked")
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// logCall logs the duration and status code of a call
+func logCall(ctx context.Context, start time.Time, err error) {
+	logging.FromContext(ctx).
+		WithField("duration", time.Since(start)).
+		WithField("code", status.Code(err).String()).
+		Debug("Call finished")
+}
+
+// RequestID adds a request id to the call context, and starts the call logger with it
+// and with the trace id of the traceparent metadata, if any
+func RequestID(ctx context.Context, method string) (context.Context, error) {
+	return middlewares.WithRequestID(ctx, uuid.New().String(), firstMetadata(ctx, "traceparent")), nil
+}
+
+// Logging adds the method to the call logger, and logs when a call arrives
+func Logging(ctx context.Context, method string) (context.Context, error) {
+	ctx = logging.WithFields(ctx, logrus.Fields{"route": method})
+	logging.FromContext(ctx).Debug("Call arrived")
+	return ctx, nil
+}
+
+// APIKeyAuth authenticates the calls that carry an api key in the x-api-key metadata
+// and adds the principal to the call context
+// Calls without an api key are passed through, RequireScope rejects them if needed
+func APIKeyAuth(auth middlewares.APIKeyAuthenticator) Step {
+	return func(ctx context.Context, method string) (context.Context, error) {
+		key := firstMetadata(ctx, strings.ToLower(middlewares.APIKeyHeader))
+		if key == "" {
+			return ctx, nil
+		}
+		return authenticate(ctx, "api key", func() (entities.Principal, error) { return auth.Authenticate(ctx, key) })
+	}
+}
+
+// BearerAuth authenticates the calls that carry a bearer token in the authorization metadata
+// and adds the principal to the call context
+// Calls without a bearer token are passed through, RequireScope rejects them if needed
+func BearerAuth(auth middlewares.TokenAuthenticator) Step {
+	return func(ctx context.Context, method string) (context.Context, error) {
+		parts := strings.SplitN(firstMetadata(ctx, "authorization"), " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+			return ctx, nil
+		}
+		token := strings.TrimSpace(parts[1])
+		return authenticate(ctx, "bearer token", func() (entities.Principal, error) { return auth.Authenticate(ctx, token) })
+	}
+}
+
+func authenticate(ctx context.Context, credential string, auth func() (entities.Principal, error)) (context.Context, error) {
+	log := logging.FromContext(ctx)
+	principal, err := auth()
+	if err != nil {
+		if errors.Is(err, consts.ErrUnauthenticated) {
+			log.WithError(err).Warn("invalid " + credential)
+			return ctx, status.Error(codes.Unauthenticated, "invalid "+credential)
+		}
+
+		log.WithError(err).Error("could not authenticate " + credential)
+		return ctx, status.Error(codes.Internal, "internal error")
+	}
+	return middlewares.WithPrincipal(ctx, principal), nil
+}
+
+// ClientCertAuth adds the principal of a verified client certificate to the call context
+// The subject is the certificate common name, and it's granted the scopes and roles given
+// Calls without a verified certificate are passed through, RequireScope rejects them if needed
+func ClientCertAuth(scopes, roles []string) Step {
+	return func(ctx context.Context, method string) (context.Context, error) {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return ctx, nil
+		}
+		info, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+			return ctx, nil
+		}
+
+		leaf := info.State.VerifiedChains[0][0]
+		return middlewares.WithPrincipal(ctx, entities.Principal{
+			Subject: leaf.Subject.CommonName,
+			Kind:    consts.PrincipalClientCert,
+			Scopes:  scopes,
+			Roles:   roles,
+		}), nil
+	}
+}
+
+// RequireScope rejects the calls without a principal, and those whose principal was not granted the scope of the method
+// Methods without a scope only require a principal
+func RequireScope(authz middlewares.Authorizer, scopes map[string]string) Step {
+	return func(ctx context.Context, method string) (context.Context, error) {
+		if _, ok := middlewares.GetPrincipal(ctx); !ok {
+			return ctx, status.Error(codes.Unauthenticated, "unauthenticated")
+		}
+
+		scope, ok := scopes[method]
+		if !ok {
+			return ctx, nil
+		}
+		log := logging.FromContext(ctx).WithField("scope", scope)
+		if err := authz.Can(ctx, scope); err != nil {
+			if errors.Is(err, consts.ErrForbidden) {
+				log.Warn("principal lacks scope")
+				return ctx, status.Error(codes.PermissionDenied, "forbidden")
+			}
+			log.WithError(err).Error("could not check scope")
+			return ctx, status.Error(codes.Internal, "internal error")
+		}
+		return ctx, nil
+	}
+}
+
+// RateLimit limits the calls of each principal, or peer IP for anonymous calls, to each method
+// The method is the route of the rules, like "/articles.v1.ArticlesService/Create"
+// It must run after the authentication steps, to know the principal
+func RateLimit(limiter middlewares.RateLimiter, rules ratelimit.Rules) Step {
+	return func(ctx context.Context, method string) (context.Context, error) {
+		log := logging.FromContext(ctx)
+
+		client := "ip:" + peerIP(ctx)
+		// the ID of the principal has its kind, so an api key named like a user doesn't share its buckets
+		var id string
+		if principal, ok := middlewares.GetPrincipal(ctx); ok {
+			id = principal.ID()
+			client = "principal:" + id
+		}
+
+		limit := rules.For(method, id)
+		res, err := limiter.Take(ctx, method+"|"+client, limit)
+		if err != nil {
+			// an unavailable rate limiter should not take the service down
+			log.WithError(err).Error("could not take rate limit token, letting call through")
+			return ctx, nil
+		}
+
+		md := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(res.Limit),
+			"ratelimit-remaining", strconv.Itoa(res.Remaining),
+			"ratelimit-reset", ceilSeconds(res.Reset),
+		)
+		if !res.Allowed {
+			md.Set("retry-after", ceilSeconds(res.RetryAfter))
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			log.WithError(err).Warn("could not set rate limit metadata")
+		}
+
+		if !res.Allowed {
+			log.WithField("client", client).Warn("rate limit exceeded")
+			return ctx, status.Error(codes.ResourceExhausted, "rate limit of "+limit.String()+" exceeded")
+		}
+		return ctx, nil
+	}
+}
+
+// firstMetadata returns the first value of an incoming metadata key, keys are lower case
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
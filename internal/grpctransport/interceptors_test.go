@@ -0,0 +1,198 @@
+package grpctransport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events/eventstest"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport/articlespb"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport/mocks"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/transports"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases"
+)
+
+// keys authenticates the api keys named after the scope they are granted, or the role for the author key
+type keys struct{}
+
+func (keys) Authenticate(ctx context.Context, key string) (entities.Principal, error) {
+	switch key {
+	case consts.ScopeArticlesRead, consts.ScopeArticlesWrite:
+		return entities.Principal{Subject: key, Kind: consts.PrincipalAPIKey, Scopes: []string{key}}, nil
+	case consts.RoleAuthor:
+		return entities.Principal{Subject: key, Kind: consts.PrincipalAPIKey, Roles: []string{key}}, nil
+	}
+	return entities.Principal{}, fmt.Errorf("unknown key: %w", consts.ErrUnauthenticated)
+}
+
+// rolePermissions grants articles:write to the author role, as seeded in the database
+type rolePermissions struct{}
+
+func (rolePermissions) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	for _, role := range roles {
+		if role == consts.RoleAuthor {
+			return []string{consts.ScopeArticlesRead, consts.ScopeArticlesWrite}, nil
+		}
+	}
+	return nil, nil
+}
+
+// serve starts a server with the interceptors of main and returns a client connected to it
+func serve(t *testing.T, usecase *mocks.MockArticlesUsecase, ae transports.ArticleEvents, rules ratelimit.Rules) (articlespb.ArticlesServiceClient, func()) {
+	steps := []Step{RequestID, Logging, APIKeyAuth(keys{}), RateLimit(ratelimit.NewMemory(), rules), RequireScope(usecases.NewPolicy(rolePermissions{}), ArticlesScopes)}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryInterceptor(steps...)),
+		grpc.ChainStreamInterceptor(StreamInterceptor(steps...)),
+	)
+	articles := NewArticles(usecase, ae)
+	articlespb.RegisterArticlesServiceServer(srv, articles)
+
+	ln := bufconn.Listen(1 << 20)
+	go srv.Serve(ln)
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return ln.Dial()
+	}))
+	assert.NoError(t, err)
+	return articlespb.NewArticlesServiceClient(conn), func() {
+		conn.Close()
+		articles.Stop()
+		srv.GracefulStop()
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		mock     func(m *mocks.MockArticlesUsecase)
+		wantCode codes.Code
+	}{
+		{
+			name: "granted scope",
+			key:  consts.ScopeArticlesWrite,
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(entities.Article{ID: "1"}, nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "scope granted by a role",
+			key:  consts.RoleAuthor,
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(entities.Article{ID: "1"}, nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "no credentials",
+			mock:     func(m *mocks.MockArticlesUsecase) {},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "invalid api key",
+			key:      "invalid",
+			mock:     func(m *mocks.MockArticlesUsecase) {},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "missing scope",
+			key:      consts.ScopeArticlesRead,
+			mock:     func(m *mocks.MockArticlesUsecase) {},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "panic",
+			key:  consts.ScopeArticlesWrite,
+			mock: func(m *mocks.MockArticlesUsecase) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Do(func(context.Context, entities.Article) { panic("boom") })
+			},
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := mocks.NewMockArticlesUsecase(ctrl)
+			tt.mock(m)
+			client, stop := serve(t, m, nil, ratelimit.Rules{Default: ratelimit.Limit{Burst: 10, Period: time.Minute}})
+			defer stop()
+
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", tt.key)
+			}
+			_, err := client.Create(ctx, &articlespb.CreateRequest{Title: "title"})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestInterceptors_RateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockArticlesUsecase(ctrl)
+	m.EXPECT().GetOne(gomock.Any(), "1").Return(entities.Article{ID: "1"}, nil)
+	client, stop := serve(t, m, nil, ratelimit.Rules{Default: ratelimit.Limit{Burst: 1, Period: time.Minute}})
+	defer stop()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", consts.ScopeArticlesRead)
+	var header metadata.MD
+	_, err := client.Get(ctx, &articlespb.GetRequest{Id: "1"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+	_, err = client.Get(ctx, &articlespb.GetRequest{Id: "1"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"60"}, header.Get("retry-after"))
+}
+
+func TestInterceptors_Watch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	log := eventstest.NewMemory()
+	bus := events.NewBus(log, 10)
+	defer bus.Close()
+	client, stop := serve(t, mocks.NewMockArticlesUsecase(ctrl), bus, ratelimit.Rules{Default: ratelimit.Limit{Burst: 10, Period: time.Minute}})
+	defer stop()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", consts.ScopeArticlesRead)
+	stream, err := client.Watch(ctx, &articlespb.WatchRequest{})
+	assert.NoError(t, err)
+
+	// the stream only gets the events published after it subscribed, so publish until it gets one
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			event, _ := log.Append(context.Background(), entities.ArticleEvent{Type: consts.EventArticleUpdated, Article: entities.Article{ID: "1"}})
+			bus.Publish(context.Background(), event)
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, articlespb.Event_UPDATED, event.Type)
+	assert.Equal(t, "1", event.Article.Id)
+}
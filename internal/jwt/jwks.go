@@ -0,0 +1,210 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Key is a verification key of a key set
+type Key struct {
+	ID        string
+	Algorithm string
+	// Public is a []byte for HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey for ES256
+	Public interface{}
+	// Kind is the kind of the principals of the tokens the key verifies, consts.PrincipalJWT if empty
+	Kind string
+}
+
+// jwk is the JSON representation of a key, as defined by RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseKeySet parses a JWKS document
+func ParseKeySet(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("could not decode jwks: %w", err)
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := parseKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse key %d (kid %q): %w", i, k.Kid, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func parseKey(k jwk) (Key, error) {
+	key := Key{ID: k.Kid, Algorithm: k.Alg}
+
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid k: %w", err)
+		}
+		key.Algorithm, key.Public = algorithmOr(k.Alg, AlgHS256), secret
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid e: %w", err)
+		}
+		key.Algorithm, key.Public = algorithmOr(k.Alg, AlgRS256), &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if k.Crv != "P-256" {
+			return Key{}, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid y: %w", err)
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return Key{}, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		key.Algorithm, key.Public = algorithmOr(k.Alg, AlgES256), &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+
+	if !supported(key.Algorithm) {
+		return Key{}, fmt.Errorf("unsupported algorithm %s", key.Algorithm)
+	}
+	return key, nil
+}
+
+func algorithmOr(alg, fallback string) string {
+	if alg == "" {
+		return fallback
+	}
+	return alg
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// KeyFile is a key set read from a local JWKS file
+// It can be reloaded, so keys are rotated without restarting the service
+type KeyFile struct {
+	path string
+
+	mu      sync.RWMutex
+	keys    []Key
+	modTime time.Time
+}
+
+// NewKeyFile reads the JWKS file, it fails if the file is not valid
+func NewKeyFile(path string) (*KeyFile, error) {
+	kf := &KeyFile{path: path}
+	if err := kf.Reload(); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// Keys returns the current keys
+func (kf *KeyFile) Keys() []Key {
+	kf.mu.RLock()
+	defer kf.mu.RUnlock()
+	return kf.keys
+}
+
+// Reload reads the file again, the previous keys are kept if the file is not valid
+func (kf *KeyFile) Reload() error {
+	info, err := os.Stat(kf.path)
+	if err != nil {
+		return fmt.Errorf("could not stat jwks file: %w", err)
+	}
+
+	data, err := ioutil.ReadFile(kf.path)
+	if err != nil {
+		return fmt.Errorf("could not read jwks file: %w", err)
+	}
+
+	keys, err := ParseKeySet(data)
+	if err != nil {
+		return err
+	}
+
+	kf.mu.Lock()
+	kf.keys = keys
+	kf.modTime = info.ModTime()
+	kf.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the file every time it's modified, until the context is done
+func (kf *KeyFile) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	kf.mu.RLock()
+	seen := kf.modTime
+	kf.mu.RUnlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(kf.path)
+		if err != nil {
+			logrus.WithError(err).WithField("path", kf.path).Warn("could not stat jwks file")
+			continue
+		}
+		if info.ModTime().Equal(seen) {
+			continue
+		}
+		seen = info.ModTime()
+
+		if err := kf.Reload(); err != nil {
+			logrus.WithError(err).WithField("path", kf.path).Error("could not reload jwks file, keeping previous keys")
+			continue
+		}
+		logrus.WithField("path", kf.path).WithField("keys", len(kf.Keys())).Info("jwks file reloaded")
+	}
+}
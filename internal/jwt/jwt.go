@@ -0,0 +1,220 @@
+// Package jwt verifies JSON Web Tokens signed with HS256, RS256 or ES256
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+)
+
+// Supported signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// ErrInvalidToken is returned (wrapped) when a token can not be trusted
+var ErrInvalidToken = fmt.Errorf("invalid token: %w", consts.ErrUnauthenticated)
+
+func supported(alg string) bool {
+	return alg == AlgHS256 || alg == AlgRS256 || alg == AlgES256
+}
+
+// KeyProvider returns the keys that can verify a token, a KeyFile for example
+type KeyProvider interface {
+	Keys() []Key
+}
+
+// Claims are the registered claims we check, plus the ones mapped to a principal
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+// Audience is the aud claim, which can be a string or an array of strings
+type Audience []string
+
+// UnmarshalJSON accepts both forms of the aud claim
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings: %w", err)
+	}
+	*a = many
+	return nil
+}
+
+// Contains reports whether the audience includes aud
+func (a Audience) Contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier checks the signature and the registered claims of tokens
+type Verifier struct {
+	keys     KeyProvider
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewVerifier is the Verifier constructor
+// issuer and audience are only checked when they are not empty
+func NewVerifier(keys KeyProvider, issuer, audience string, leeway time.Duration) Verifier {
+	return Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   leeway,
+		now:      time.Now,
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify returns the claims of the token if it can be trusted
+func (v Verifier) Verify(token string) (Claims, error) {
+	claims, _, err := v.verify(token)
+	return claims, err
+}
+
+// verify returns the claims of the token and the key that verified it
+func (v Verifier) verify(token string) (Claims, Key, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, Key{}, fmt.Errorf("token must have 3 parts: %w", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, Key{}, fmt.Errorf("could not decode header: %s: %w", err.Error(), ErrInvalidToken)
+	}
+	if !supported(h.Alg) {
+		return Claims{}, Key{}, fmt.Errorf("algorithm %q is not allowed: %w", h.Alg, ErrInvalidToken)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, Key{}, fmt.Errorf("could not decode signature: %s: %w", err.Error(), ErrInvalidToken)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	key, ok := v.verifySignature(h, signed, sig)
+	if !ok {
+		return Claims{}, Key{}, fmt.Errorf("signature does not match any key: %w", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, Key{}, fmt.Errorf("could not decode claims: %s: %w", err.Error(), ErrInvalidToken)
+	}
+
+	if err := v.validate(claims); err != nil {
+		return Claims{}, Key{}, err
+	}
+	return claims, key, nil
+}
+
+// verifySignature tries the keys of the token algorithm, only the one with the same kid if present,
+// and returns the one that matched
+func (v Verifier) verifySignature(h header, signed, sig []byte) (Key, bool) {
+	for _, key := range v.keys.Keys() {
+		if key.Algorithm != h.Alg {
+			continue
+		}
+		if h.Kid != "" && key.ID != h.Kid {
+			continue
+		}
+		if verify(key, signed, sig) {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+func verify(key Key, signed, sig []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch pub := key.Public.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, pub)
+		mac.Write(signed)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		// JWS encodes ECDSA signatures as the concatenation of r and s
+		if len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func (v Verifier) validate(c Claims) error {
+	now := v.now()
+
+	if c.ExpiresAt == 0 {
+		return fmt.Errorf("exp claim is required: %w", ErrInvalidToken)
+	}
+	if now.After(time.Unix(c.ExpiresAt, 0).Add(v.leeway)) {
+		return fmt.Errorf("token expired at %d: %w", c.ExpiresAt, ErrInvalidToken)
+	}
+	if c.NotBefore != 0 && now.Before(time.Unix(c.NotBefore, 0).Add(-v.leeway)) {
+		return fmt.Errorf("token not valid before %d: %w", c.NotBefore, ErrInvalidToken)
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("unexpected issuer %q: %w", c.Issuer, ErrInvalidToken)
+	}
+	if v.audience != "" && !c.Audience.Contains(v.audience) {
+		return fmt.Errorf("token is not meant for audience %q: %w", v.audience, ErrInvalidToken)
+	}
+	if c.Subject == "" {
+		return fmt.Errorf("sub claim is required: %w", ErrInvalidToken)
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
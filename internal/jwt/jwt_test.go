@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+type staticKeys []Key
+
+func (s staticKeys) Keys() []Key { return s }
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func sign(t *testing.T, alg, kid string, priv interface{}, claims map[string]interface{}) string {
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := priv.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	}
+	return signed + "." + b64(sig)
+}
+
+func TestParseKeySet(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "oct", "kid": "hs", "k": %q},
+		{"kty": "RSA", "kid": "rs", "n": %q, "e": %q},
+		{"kty": "EC", "kid": "es", "crv": "P-256", "x": %q, "y": %q},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": %q, "e": %q}
+	]}`,
+		b64([]byte("secret")),
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes()),
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()))
+
+	keys, err := ParseKeySet([]byte(jwks))
+	if err != nil {
+		t.Fatalf("ParseKeySet() error = %v", err)
+	}
+
+	assert.Len(t, keys, 3)
+	assert.Equal(t, AlgHS256, keys[0].Algorithm)
+	assert.Equal(t, AlgRS256, keys[1].Algorithm)
+	assert.Equal(t, rsaKey.PublicKey, *keys[1].Public.(*rsa.PublicKey))
+	assert.Equal(t, AlgES256, keys[2].Algorithm)
+	assert.Equal(t, ecKey.PublicKey, *keys[2].Public.(*ecdsa.PublicKey))
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret := []byte("secret")
+	keys := staticKeys{
+		{ID: "hs", Algorithm: AlgHS256, Public: secret},
+		{ID: "rs", Algorithm: AlgRS256, Public: &rsaKey.PublicKey},
+		{ID: "es", Algorithm: AlgES256, Public: &ecKey.PublicKey},
+	}
+
+	now := time.Now()
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":   "alice",
+			"iss":   "issuer",
+			"aud":   []string{"articles"},
+			"exp":   now.Add(time.Minute).Unix(),
+			"scope": "articles:read",
+		}
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := valid()
+		c[key] = value
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "Success: HS256", token: sign(t, AlgHS256, "hs", secret, valid())},
+		{name: "Success: RS256", token: sign(t, AlgRS256, "rs", rsaKey, valid())},
+		{name: "Success: ES256", token: sign(t, AlgES256, "es", ecKey, valid())},
+		{name: "Success: No kid", token: sign(t, AlgES256, "", ecKey, valid())},
+		{name: "Success: Single audience", token: sign(t, AlgHS256, "hs", secret, with("aud", "articles"))},
+		{name: "Failure: Wrong secret", token: sign(t, AlgHS256, "hs", []byte("other"), valid()), wantErr: true},
+		{name: "Failure: Algorithm of another key", token: sign(t, AlgHS256, "rs", secret, valid()), wantErr: true},
+		{name: "Failure: Algorithm none", token: b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{"sub":"alice"}`)) + ".", wantErr: true},
+		{name: "Failure: Expired", token: sign(t, AlgHS256, "hs", secret, with("exp", now.Add(-time.Hour).Unix())), wantErr: true},
+		{name: "Failure: No exp", token: sign(t, AlgHS256, "hs", secret, with("exp", 0)), wantErr: true},
+		{name: "Failure: Not yet valid", token: sign(t, AlgHS256, "hs", secret, with("nbf", now.Add(time.Hour).Unix())), wantErr: true},
+		{name: "Failure: Wrong issuer", token: sign(t, AlgHS256, "hs", secret, with("iss", "other")), wantErr: true},
+		{name: "Failure: Wrong audience", token: sign(t, AlgHS256, "hs", secret, with("aud", "other")), wantErr: true},
+		{name: "Failure: Malformed", token: "not-a-token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(keys, "issuer", "articles", time.Second)
+
+			got, err := v.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, consts.ErrUnauthenticated))
+				return
+			}
+			assert.Equal(t, "alice", got.Subject)
+			assert.Equal(t, "articles:read", got.Scope)
+		})
+	}
+}
+
+func TestVerifier_Authenticate(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signer := NewSigner("local", []byte("secret"), "", "", time.Minute)
+	v := NewVerifier(KeyProviders{signer, staticKeys{{ID: "idp", Algorithm: AlgES256, Public: &ecKey.PublicKey}}}, "", "", time.Second)
+
+	// users of the local signer and subjects of another issuer with the same name are different principals
+	local, err := signer.Issue(context.Background(), entities.Principal{Subject: "alice", Roles: []string{consts.RoleAuthor}})
+	assert.NoError(t, err)
+	user, err := v.Authenticate(context.Background(), local.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.Principal{Subject: "alice", Kind: consts.PrincipalUser, Scopes: []string{}, Roles: []string{consts.RoleAuthor}}, user)
+
+	external := sign(t, AlgES256, "idp", ecKey, map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Minute).Unix(), "scope": "articles:read"})
+	other, err := v.Authenticate(context.Background(), external)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.Principal{Subject: "alice", Kind: consts.PrincipalJWT, Scopes: []string{consts.ScopeArticlesRead}}, other)
+	assert.NotEqual(t, user.ID(), other.ID())
+}
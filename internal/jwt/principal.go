@@ -0,0 +1,29 @@
+package jwt
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// Authenticate verifies the token and maps its claims to a principal, of the kind of the key that verified it
+// The scope claim is a space separated list, as in OAuth 2, and the roles claim an array
+func (v Verifier) Authenticate(ctx context.Context, token string) (entities.Principal, error) {
+	claims, key, err := v.verify(token)
+	if err != nil {
+		return entities.Principal{}, err
+	}
+
+	kind := key.Kind
+	if kind == "" {
+		kind = consts.PrincipalJWT
+	}
+	return entities.Principal{
+		Subject: claims.Subject,
+		Kind:    kind,
+		Scopes:  strings.Fields(claims.Scope),
+		Roles:   claims.Roles,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// Signer issues HS256 tokens for the users that log in with a password, they are verified as consts.PrincipalUser
+type Signer struct {
+	key      Key
+	issuer   string
+	audience string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewSigner is the Signer constructor
+// issuer and audience are only set in the tokens when they are not empty
+func NewSigner(kid string, secret []byte, issuer, audience string, ttl time.Duration) Signer {
+	return Signer{
+		key:      Key{ID: kid, Algorithm: AlgHS256, Public: secret, Kind: consts.PrincipalUser},
+		issuer:   issuer,
+		audience: audience,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// Keys returns the key that verifies the issued tokens, so a Signer is a KeyProvider
+func (s Signer) Keys() []Key {
+	return []Key{s.key}
+}
+
+// Sign encodes and signs the claims
+func (s Signer) Sign(claims Claims) (string, error) {
+	h, err := json.Marshal(header{Alg: AlgHS256, Kid: s.key.ID, Typ: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("could not encode header: %w", err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("could not encode claims: %w", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	mac := hmac.New(sha256.New, s.key.Public.([]byte))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Issue returns a token for the principal, valid for the signer ttl
+func (s Signer) Issue(ctx context.Context, principal entities.Principal) (entities.Token, error) {
+	now := s.now()
+	claims := Claims{
+		Subject:   principal.Subject,
+		Issuer:    s.issuer,
+		ExpiresAt: now.Add(s.ttl).Unix(),
+		IssuedAt:  now.Unix(),
+		Scope:     strings.Join(principal.Scopes, " "),
+		Roles:     principal.Roles,
+	}
+	if s.audience != "" {
+		claims.Audience = Audience{s.audience}
+	}
+
+	token, err := s.Sign(claims)
+	if err != nil {
+		return entities.Token{}, err
+	}
+
+	return entities.Token{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.ttl.Seconds()),
+	}, nil
+}
+
+// KeyProviders merges the keys of several providers, a JWKS file and a Signer for example
+type KeyProviders []KeyProvider
+
+// Keys returns the keys of all the providers
+func (kp KeyProviders) Keys() []Key {
+	var keys []Key
+	for _, p := range kp {
+		keys = append(keys, p.Keys()...)
+	}
+	return keys
+}
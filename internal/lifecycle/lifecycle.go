@@ -0,0 +1,137 @@
+// Package lifecycle starts the components of the service in order, and stops them in reverse order
+// when the process receives SIGINT or SIGTERM, or when one of them fails
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a component of the service
+type Hook struct {
+	Name string
+	// OnStart must not block, long running work is started in a goroutine
+	// and its unexpected errors reported with Manager.Fail
+	OnStart func(ctx context.Context) error
+	// OnStop releases the component, it should return before the context is done
+	OnStop func(ctx context.Context) error
+}
+
+// Manager runs the hooks
+type Manager struct {
+	hooks       []Hook
+	stopTimeout time.Duration
+	failures    chan error
+	signals     chan os.Signal
+}
+
+// NewManager is the Manager constructor
+// stopTimeout bounds the time all the hooks have to stop
+func NewManager(stopTimeout time.Duration) *Manager {
+	return &Manager{
+		stopTimeout: stopTimeout,
+		failures:    make(chan error, 1),
+		signals:     make(chan os.Signal, 1),
+	}
+}
+
+// Append adds a hook, started after the previous ones and stopped before them
+func (m *Manager) Append(h Hook) {
+	m.hooks = append(m.hooks, h)
+}
+
+// Fail reports that a component can not keep running, the manager stops all the hooks
+// Only the first failure is kept
+func (m *Manager) Fail(err error) {
+	select {
+	case m.failures <- err:
+	default:
+	}
+}
+
+// Run starts the hooks and blocks until a signal or a failure, then stops them
+// The returned error is nil only when the service was asked to stop and it stopped cleanly
+func (m *Manager) Run() error {
+	signal.Notify(m.signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(m.signals)
+
+	started, err := m.start()
+	if err != nil {
+		logrus.WithError(err).Error("could not start")
+		if stopErr := m.stop(started, all); stopErr != nil {
+			logrus.WithError(stopErr).Error("could not stop cleanly")
+		}
+		return err
+	}
+
+	var runErr error
+	select {
+	case sig := <-m.signals:
+		logrus.WithField("signal", sig.String()).Warn("Shutting down gracefully")
+	case runErr = <-m.failures:
+		logrus.WithError(runErr).Error("component failed, shutting down")
+	}
+
+	if err := m.stop(started, all); err != nil {
+		logrus.WithError(err).Error("could not stop cleanly")
+		if runErr == nil {
+			runErr = err
+		}
+	}
+	return runErr
+}
+
+// Abort stops the hooks when the set up fails before Run. Nothing was started, so only the hooks without
+// an OnStart are stopped: they release what was taken before they were appended, like open files
+func (m *Manager) Abort() error {
+	return m.stop(len(m.hooks), func(h Hook) bool { return h.OnStart == nil })
+}
+
+// start runs the OnStart of the hooks in order, it returns the number of hooks started
+func (m *Manager) start() (int, error) {
+	ctx := context.Background()
+	for i, h := range m.hooks {
+		if h.OnStart == nil {
+			continue
+		}
+
+		logrus.WithField("component", h.Name).Debug("starting")
+		if err := h.OnStart(ctx); err != nil {
+			return i, fmt.Errorf("could not start %s: %w", h.Name, err)
+		}
+	}
+	return len(m.hooks), nil
+}
+
+// stop runs the OnStop of the first started hooks that match in reverse order, all of them are run even if one fails
+func (m *Manager) stop(started int, match func(h Hook) bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.stopTimeout)
+	defer cancel()
+
+	var firstErr error
+	for i := started - 1; i >= 0; i-- {
+		h := m.hooks[i]
+		if h.OnStop == nil || !match(h) {
+			continue
+		}
+
+		logrus.WithField("component", h.Name).Debug("stopping")
+		if err := h.OnStop(ctx); err != nil {
+			logrus.WithError(err).WithField("component", h.Name).Error("could not stop component")
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not stop %s: %w", h.Name, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+func all(Hook) bool {
+	return true
+}
@@ -0,0 +1,110 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_Run(t *testing.T) {
+	tests := []struct {
+		name      string
+		failStart string
+		failStop  string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name: "Success: Stopped in reverse order",
+			want: []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+		},
+		{
+			name:      "Failure: Start fails, started hooks are stopped",
+			failStart: "b",
+			want:      []string{"start a", "start b", "stop a"},
+			wantErr:   true,
+		},
+		{
+			name:     "Failure: Stop fails, the rest are stopped anyway",
+			failStop: "b",
+			want:     []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			m := NewManager(time.Second)
+			for _, name := range []string{"a", "b", "c"} {
+				name := name
+				m.Append(Hook{
+					Name: name,
+					OnStart: func(ctx context.Context) error {
+						got = append(got, "start "+name)
+						if name == tt.failStart {
+							return fmt.Errorf("start failed")
+						}
+						return nil
+					},
+					OnStop: func(ctx context.Context) error {
+						got = append(got, "stop "+name)
+						if name == tt.failStop {
+							return fmt.Errorf("stop failed")
+						}
+						return nil
+					},
+				})
+			}
+
+			// simulates a signal once everything is started
+			m.signals <- syscall.SIGTERM
+
+			err := m.Run()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Manager.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_Fail(t *testing.T) {
+	m := NewManager(time.Second)
+	m.Append(Hook{
+		Name: "server",
+		OnStart: func(ctx context.Context) error {
+			go m.Fail(fmt.Errorf("listener closed"))
+			return nil
+		},
+	})
+
+	err := m.Run()
+	assert.EqualError(t, err, "listener closed")
+}
+
+func TestManager_Abort(t *testing.T) {
+	var got []string
+	m := NewManager(time.Second)
+	m.Append(Hook{
+		Name:   "log file",
+		OnStop: func(ctx context.Context) error { got = append(got, "close log file"); return nil },
+	})
+	m.Append(Hook{
+		Name:    "worker",
+		OnStart: func(ctx context.Context) error { got = append(got, "start worker"); return nil },
+		OnStop:  func(ctx context.Context) error { got = append(got, "stop worker"); return nil },
+	})
+	m.Append(Hook{
+		Name:   "database",
+		OnStop: func(ctx context.Context) error { got = append(got, "close database"); return fmt.Errorf("busy") },
+	})
+
+	// the worker was never started, so it's not stopped
+	err := m.Abort()
+	assert.EqualError(t, err, "could not stop database: busy")
+	assert.Equal(t, []string{"close database", "close log file"}, got)
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey string
+
+const loggerKey = contextKey("logger")
+
+// WithLogger returns a copy of ctx carrying log, middlewares use it to add request fields
+func WithLogger(ctx context.Context, log *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey, log)
+}
+
+// WithFields returns a copy of ctx whose logger has the given fields added
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return WithLogger(ctx, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext returns the logger carried by ctx.
+// Outside a request, e.g. in background jobs, it falls back to the standard logger without any field.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if log, ok := ctx.Value(loggerKey).(*logrus.Entry); ok {
+			return log
+		}
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
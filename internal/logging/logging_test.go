@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want logrus.Fields
+	}{
+		{
+			name: "without logger falls back to the standard logger",
+			ctx:  context.Background(),
+			want: logrus.Fields{},
+		},
+		{
+			name: "fields are accumulated",
+			ctx: WithFields(
+				WithFields(context.Background(), logrus.Fields{"request_id": "id"}),
+				logrus.Fields{"principal": "alice"},
+			),
+			want: logrus.Fields{"request_id": "id", "principal": "alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := FromContext(tt.ctx)
+			assert.Equal(t, logrus.StandardLogger(), log.Logger)
+			assert.Equal(t, tt.want, log.Data)
+		})
+	}
+}
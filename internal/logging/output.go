@@ -0,0 +1,163 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Log formats
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// Options choose the format and the destination of the logs
+type Options struct {
+	// Format is text, the logrus default with colors on terminals, json or logfmt
+	Format string
+	// File receives the logs instead of stderr when set
+	File string
+	// ErrorFile receives a copy of the error, fatal and panic entries when set
+	ErrorFile string
+	// MaxSize is the size in megabytes a file is rotated at
+	MaxSize int
+	// RotateInterval rotates the files on a schedule too, 0 to only rotate by size
+	RotateInterval time.Duration
+	// MaxAge and MaxBackups are how long and how many rotated files are kept, 0 to keep them all
+	MaxAge     time.Duration
+	MaxBackups int
+	// Compress gzips the rotated files
+	Compress bool
+}
+
+// Output is where the logs are written, it keeps the files to rotate and close them
+type Output struct {
+	logger   *logrus.Logger
+	files    []*lumberjack.Logger
+	interval time.Duration
+}
+
+// NewFormatter returns the formatter of the format
+func NewFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case FormatText:
+		return &logrus.TextFormatter{}, nil
+	case FormatJSON:
+		return &logrus.JSONFormatter{}, nil
+	case FormatLogfmt:
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, must be text, json or logfmt", format)
+	}
+}
+
+// Setup sets the formatter and the destinations of logger
+func Setup(logger *logrus.Logger, opts Options) (*Output, error) {
+	formatter, err := NewFormatter(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	logger.SetFormatter(formatter)
+
+	out := &Output{logger: logger, interval: opts.RotateInterval}
+	if opts.File != "" {
+		logger.SetOutput(out.file(opts.File, opts))
+	}
+	if opts.ErrorFile != "" {
+		logger.AddHook(WriterHook{
+			Writer:    out.file(opts.ErrorFile, opts),
+			Formatter: formatter,
+			LogLevels: []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel},
+		})
+	}
+	return out, nil
+}
+
+func (o *Output) file(path string, opts Options) io.Writer {
+	f := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    opts.MaxSize,
+		MaxAge:     days(opts.MaxAge),
+		MaxBackups: opts.MaxBackups,
+		LocalTime:  true,
+		Compress:   opts.Compress,
+	}
+	o.files = append(o.files, f)
+	return f
+}
+
+// Watch rotates the files every RotateInterval, until ctx is canceled
+func (o *Output) Watch(ctx context.Context) {
+	if o.interval <= 0 || len(o.files) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.rotate()
+		}
+	}
+}
+
+func (o *Output) rotate() {
+	for _, f := range o.files {
+		if err := f.Rotate(); err != nil {
+			// the log file may be the one failing, so stderr is used
+			fmt.Fprintf(os.Stderr, "could not rotate log file %s: %v\n", f.Filename, err)
+		}
+	}
+}
+
+// Close closes the files, logs written after are sent to stderr
+func (o *Output) Close() error {
+	o.logger.SetOutput(os.Stderr)
+	var firstErr error
+	for _, f := range o.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// days rounds up, as lumberjack keeps files a whole number of days
+func days(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// WriterHook writes a copy of the entries of its levels to another writer
+type WriterHook struct {
+	Writer    io.Writer
+	Formatter logrus.Formatter
+	LogLevels []logrus.Level
+}
+
+// Levels returns the levels the hook is fired for
+func (h WriterHook) Levels() []logrus.Level {
+	return h.LogLevels
+}
+
+// Fire formats the entry and writes it
+func (h WriterHook) Fire(entry *logrus.Entry) error {
+	b, err := h.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.Writer.Write(b)
+	return err
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		wantErr    bool
+		wantLog    []string
+		wantErrLog []string
+	}{
+		{
+			name:       "json",
+			format:     FormatJSON,
+			wantLog:    []string{`"msg":"info entry"`, `"msg":"error entry"`},
+			wantErrLog: []string{`"msg":"error entry"`},
+		},
+		{
+			name:       "logfmt",
+			format:     FormatLogfmt,
+			wantLog:    []string{`msg="info entry"`, `msg="error entry"`},
+			wantErrLog: []string{`msg="error entry"`},
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "logs")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			logger := logrus.New()
+			out, err := Setup(logger, Options{
+				Format:    tt.format,
+				File:      filepath.Join(dir, "app.log"),
+				ErrorFile: filepath.Join(dir, "error.log"),
+				MaxSize:   1,
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			logger.Info("info entry")
+			logger.Error("error entry")
+			assert.NoError(t, out.Close())
+
+			logs := readFile(t, filepath.Join(dir, "app.log"))
+			for _, want := range tt.wantLog {
+				assert.Contains(t, logs, want)
+			}
+			errLogs := readFile(t, filepath.Join(dir, "error.log"))
+			for _, want := range tt.wantErrLog {
+				assert.Contains(t, errLogs, want)
+			}
+			assert.NotContains(t, errLogs, "info entry")
+		})
+	}
+}
+
+func TestDays(t *testing.T) {
+	assert.Equal(t, 0, days(0))
+	assert.Equal(t, 1, days(time.Hour))
+	assert.Equal(t, 7, days(7*24*time.Hour))
+	assert.Equal(t, 8, days(7*24*time.Hour+time.Minute))
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return strings.TrimSpace(string(b))
+}
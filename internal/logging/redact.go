@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces the value of redacted fields
+const Redacted = "[REDACTED]"
+
+// DefaultRedactFields are the fields that may carry secrets or personal data
+var DefaultRedactFields = []string{
+	"password", "password_hash", "secret", "token", "access_token", "api_key", "key",
+	"authorization", "cookie", "username", "title", "content", "args",
+}
+
+// RedactHook rewrites the fields of every entry before it's written:
+// fields named in the redact list are replaced, and values longer than the max length are truncated
+type RedactHook struct {
+	redact    map[string]bool
+	maxLength int
+}
+
+// NewRedactHook is the RedactHook constructor, field names are case insensitive and
+// a maxLength of 0 disables truncation
+func NewRedactHook(fields []string, maxLength int) RedactHook {
+	redact := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		redact[strings.ToLower(f)] = true
+	}
+	return RedactHook{redact: redact, maxLength: maxLength}
+}
+
+// Levels applies the hook to all levels
+func (h RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire replaces the entry fields with a rewritten copy, the original map is shared with the logger
+// the entry was created from, so it must not be modified
+func (h RedactHook) Fire(entry *logrus.Entry) error {
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = h.field(k, v)
+	}
+	entry.Data = data
+	return nil
+}
+
+func (h RedactHook) field(key string, value interface{}) interface{} {
+	if h.redact[strings.ToLower(key)] {
+		return Redacted
+	}
+	if h.maxLength <= 0 {
+		return value
+	}
+
+	var s string
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return value
+	case string:
+		s = v
+	case error:
+		if msg := v.Error(); len(msg) > h.maxLength {
+			return truncatedError{msg: truncate(msg, h.maxLength), err: v}
+		}
+		return value
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprintf("%+v", v)
+	}
+	if len(s) <= h.maxLength {
+		return value
+	}
+	return truncate(s, h.maxLength)
+}
+
+// truncate cuts s to at most n bytes without splitting a rune, and tells how long it was
+func truncate(s string, n int) string {
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(%d bytes)", s[:cut], len(s))
+}
+
+// truncatedError shortens the message of err, but keeps it in the chain for the hooks that inspect it
+type truncatedError struct {
+	msg string
+	err error
+}
+
+func (e truncatedError) Error() string { return e.msg }
+
+func (e truncatedError) Unwrap() error { return e.err }
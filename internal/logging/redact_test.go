@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactHook_Fire(t *testing.T) {
+	tests := []struct {
+		name      string
+		fields    []string
+		maxLength int
+		data      logrus.Fields
+		want      logrus.Fields
+	}{
+		{
+			name:   "redacted fields are case insensitive",
+			fields: []string{"password", "Content"},
+			data:   logrus.Fields{"Password": "hunter22", "content": "body", "id": "1"},
+			want:   logrus.Fields{"Password": Redacted, "content": Redacted, "id": "1"},
+		},
+		{
+			name:      "long values are truncated",
+			maxLength: 5,
+			data:      logrus.Fields{"query": "SELECT 1", "id": "12345", "count": 1234567},
+			want:      logrus.Fields{"query": "SELEC...(8 bytes)", "id": "12345", "count": 1234567},
+		},
+		{
+			name:      "errors and structs are truncated as text",
+			maxLength: 4,
+			data: logrus.Fields{
+				"error":    errors.New("could not connect"),
+				"article":  struct{ Title string }{"long title"},
+				"duration": time.Second,
+			},
+			want: logrus.Fields{
+				"error":    truncatedError{msg: "coul...(17 bytes)", err: errors.New("could not connect")},
+				"article":  "{Tit...(18 bytes)",
+				"duration": time.Second,
+			},
+		},
+		{
+			name:      "runes are not split",
+			maxLength: 2,
+			data:      logrus.Fields{"name": "ñandú"},
+			want:      logrus.Fields{"name": "ñ...(7 bytes)"},
+		},
+		{
+			name: "zero max length does not truncate",
+			data: logrus.Fields{"query": "SELECT 1"},
+			want: logrus.Fields{"query": "SELECT 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := logrus.WithFields(tt.data)
+			shared := entry.Data
+
+			err := NewRedactHook(tt.fields, tt.maxLength).Fire(entry)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, entry.Data)
+			assert.Equal(t, tt.data, shared, "the fields shared with the logger must not change")
+		})
+	}
+}
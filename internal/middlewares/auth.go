@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// APIKeyHeader is the header that carries the api key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator describes the function we need to resolve an api key
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (entities.Principal, error)
+}
+
+// APIKeyAuth authenticates the requests that carry an api key
+// and adds the principal to the request context
+// Requests without an api key are passed through, RequireScope rejects them if needed
+func APIKeyAuth(auth APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := logging.FromContext(r.Context())
+			principal, err := auth.Authenticate(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, consts.ErrUnauthenticated) {
+					log.WithError(err).Warn("invalid api key")
+					unauthorized(w, r)
+					return
+				}
+
+				log.WithError(err).Error("could not authenticate api key")
+				render.WriteProblem(w, r, http.StatusInternalServerError, "")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// TokenAuthenticator describes the function we need to resolve a bearer token
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (entities.Principal, error)
+}
+
+// BearerAuth authenticates the requests that carry a bearer token in the Authorization header
+// and adds the principal to the request context
+// Requests without a bearer token are passed through, RequireScope rejects them if needed
+func BearerAuth(auth TokenAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := logging.FromContext(r.Context())
+			principal, err := auth.Authenticate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, consts.ErrUnauthenticated) {
+					log.WithError(err).Warn("invalid bearer token")
+					w.Header().Set("WWW-Authenticate", `Bearer realm="articles", error="invalid_token"`)
+					render.WriteProblem(w, r, http.StatusUnauthorized, "")
+					return
+				}
+
+				log.WithError(err).Error("could not authenticate bearer token")
+				render.WriteProblem(w, r, http.StatusInternalServerError, "")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+		return "", false
+	}
+	return strings.TrimSpace(parts[1]), true
+}
+
+// ClientCertAuth adds the principal of a verified client certificate to the request context
+// The subject is the certificate common name, and it's granted the scopes and roles given
+// Requests without a verified certificate are passed through, RequireScope rejects them if needed
+func ClientCertAuth(scopes, roles []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// chains are only verified when the server is configured with client CAs
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			leaf := r.TLS.VerifiedChains[0][0]
+			principal := entities.Principal{
+				Subject: leaf.Subject.CommonName,
+				Kind:    consts.PrincipalClientCert,
+				Scopes:  scopes,
+				Roles:   roles,
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// Authorizer describes the function we need to check the scopes of the principal of a context,
+// granted by itself or through its roles
+type Authorizer interface {
+	Can(ctx context.Context, scope string) error
+}
+
+// RequireScope rejects the requests whose principal was not granted the scope
+func RequireScope(authz Authorizer, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := GetPrincipal(r.Context()); !ok {
+				unauthorized(w, r)
+				return
+			}
+
+			log := logging.FromContext(r.Context()).WithField("scope", scope)
+			if err := authz.Can(r.Context(), scope); err != nil {
+				if errors.Is(err, consts.ErrForbidden) {
+					log.Warn("principal lacks scope")
+					render.WriteProblem(w, r, http.StatusForbidden, "")
+					return
+				}
+				log.WithError(err).Error("could not check scope")
+				render.WriteProblem(w, r, http.StatusInternalServerError, "")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithPrincipal returns a copy of the context that carries the principal, also added to the request logger
+func WithPrincipal(ctx context.Context, principal entities.Principal) context.Context {
+	ctx = logging.WithFields(ctx, logrus.Fields{"principal": principal.Subject, "principal_kind": principal.Kind})
+	return context.WithValue(ctx, contextKey("principal"), principal)
+}
+
+// GetPrincipal returns the authenticated principal of the context, if existent
+func GetPrincipal(ctx context.Context) (entities.Principal, bool) {
+	principal, ok := ctx.Value(contextKey("principal")).(entities.Principal)
+	return principal, ok
+}
+
+// unauthorized lists the accepted credentials in the response
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("WWW-Authenticate", `ApiKey realm="articles"`)
+	w.Header().Add("WWW-Authenticate", `Bearer realm="articles"`)
+	render.WriteProblem(w, r, http.StatusUnauthorized, "")
+}
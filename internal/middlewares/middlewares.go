@@ -3,41 +3,53 @@ package middlewares
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
 )
 
 type contextKey string
 
-// Logging logs when a request arrives
+// Logging adds the route to the request logger, and logs when a request arrives
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logrus.WithField("path", r.RequestURI).
+		ctx := logging.WithFields(r.Context(), logrus.Fields{"route": routeName(r)})
+		logging.FromContext(ctx).
+			WithField("path", r.RequestURI).
 			WithField("method", r.Method).
-			WithField("request_id", GetRequestID(r.Context())).
 			Debug("Request arrived")
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// RequestID adds a request id to the request context
+// RequestID adds a request id to the request context, and starts the request logger with it
+// and with the trace id of the traceparent header, if any
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := uuid.New().String()
-		nctx := context.WithValue(r.Context(), contextKey("requestID"), requestID)
+		nctx := WithRequestID(r.Context(), uuid.New().String(), r.Header.Get("traceparent"))
 		next.ServeHTTP(w, r.WithContext(nctx))
 	})
 }
 
+// WithRequestID returns a copy of the context that carries the request id, and starts its logger with it
+// and with the trace id of the W3C traceparent, if valid
+func WithRequestID(ctx context.Context, requestID, traceparent string) context.Context {
+	ctx = context.WithValue(ctx, contextKey("requestID"), requestID)
+
+	fields := logrus.Fields{"request_id": requestID}
+	if traceID := traceID(traceparent); traceID != "" {
+		fields["trace_id"] = traceID
+	}
+	return logging.WithLogger(ctx, logrus.WithFields(fields))
+}
+
 // GetRequestID returns the context request id value, if existent
 func GetRequestID(ctx context.Context) string {
-	id, ok := ctx.Value(contextKey("requestID")).(string)
-	if !ok {
-		logrus.Warn("requestID not found in context")
-		return ""
-	}
+	id, _ := ctx.Value(contextKey("requestID")).(string)
 	return id
 }
 
@@ -47,8 +59,22 @@ func Timing(next http.Handler) http.Handler {
 		start := time.Now()
 		next.ServeHTTP(w, r)
 		d := time.Since(start)
-		logrus.WithField("duration", d).
-			WithField("request_id", GetRequestID(r.Context())).
+		logging.FromContext(r.Context()).
+			WithField("duration", d).
 			Debug("Request timing")
 	})
 }
+
+// traceID returns the trace id of a W3C traceparent header, like 00-<trace id>-<parent id>-<flags>
+func traceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	for _, c := range parts[1] {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return ""
+		}
+	}
+	return parts[1]
+}
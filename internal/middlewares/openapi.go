@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/openapi"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// OpenAPI rejects the requests to the routes of the document that don't match it with a 400 problem.
+// strictResponses also checks the responses, buffered until they are checked, and replaces the ones that
+// don't match with a 500 problem, to catch handlers that drift from the document in tests.
+// Streams and WebSockets can't be buffered, their responses are not checked
+func OpenAPI(v openapi.Validator, strictResponses bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			op, ok := v.Operation(r.Method, path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := logging.FromContext(r.Context())
+			if err := v.ValidateRequest(op, r, mux.Vars(r)); err != nil {
+				log.WithError(err).Warn("request does not match the openapi document")
+				render.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+			if !strictResponses || op.Streams() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &responseRecorder{header: w.Header(), status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if err := v.ValidateResponse(op, rec.status, rec.header, rec.body.Bytes()); err != nil {
+				log.WithError(err).WithField("status", rec.status).Error("response does not match the openapi document")
+				render.WriteProblem(w, r, http.StatusInternalServerError, "response does not match the openapi document: "+err.Error())
+				return
+			}
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+		})
+	}
+}
+
+// responseRecorder buffers a response, its headers are the ones of the real response
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = status, true
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// versionSegment matches the version of the API in a route name, like /v2 in "GET /v2/articles"
+var versionSegment = regexp.MustCompile(`^([A-Z]+ )/v[0-9]+/`)
+
+// RateLimiter describes the function we need to keep the token buckets
+type RateLimiter interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+}
+
+// RateLimit limits the requests of each principal, or client IP for anonymous requests, to each route
+// It must run after the authentication middlewares, to know the principal
+// trustForwarded uses the X-Forwarded-For header as client IP, enable it only behind a proxy that sets it
+func RateLimit(limiter RateLimiter, rules ratelimit.Rules, trustForwarded bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			log := logging.FromContext(ctx)
+
+			// the versions of a route share its limit and buckets
+			route := versionSegment.ReplaceAllString(routeName(r), "$1/")
+			client := "ip:" + clientIP(r, trustForwarded)
+			// the ID of the principal has its kind, so an api key named like a user doesn't share its buckets
+			var id string
+			if principal, ok := GetPrincipal(ctx); ok {
+				id = principal.ID()
+				client = "principal:" + id
+			}
+
+			limit := rules.For(route, id)
+			res, err := limiter.Take(ctx, route+"|"+client, limit)
+			if err != nil {
+				// an unavailable rate limiter should not take the service down
+				log.WithError(err).Error("could not take rate limit token, letting request through")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// RateLimit-* headers as in the IETF draft, values in seconds
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				log.WithField("client", client).Warn("rate limit exceeded")
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				render.WriteProblem(w, r, http.StatusTooManyRequests, "rate limit of "+limit.String()+" exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeName returns the method and path template of the matched route, like "PUT /articles/{id}"
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return r.Method + " " + tpl
+		}
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+func clientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.SplitN(fwd, ",", 2)[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
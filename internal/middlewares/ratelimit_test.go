@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+)
+
+// limiter records the key and limit it was asked for, and returns its result
+type limiter struct {
+	res   ratelimit.Result
+	err   error
+	key   string
+	limit ratelimit.Limit
+}
+
+func (l *limiter) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	l.key, l.limit = key, limit
+	return l.res, l.err
+}
+
+func TestRateLimit(t *testing.T) {
+	perMinute := ratelimit.Limit{Burst: 10, Period: time.Minute}
+	perHour := ratelimit.Limit{Burst: 1000, Period: time.Hour}
+	rules := ratelimit.Rules{Default: perMinute, Principals: map[string]ratelimit.Limit{"user:alice": perHour}}
+	allowed := ratelimit.Result{Allowed: true, Limit: 10, Remaining: 9, Reset: 5500 * time.Millisecond}
+
+	tests := []struct {
+		name           string
+		path           string
+		forwarded      string
+		trustForwarded bool
+		principal      *entities.Principal
+		limiter        limiter
+		wantStatus     int
+		wantKey        string
+		wantLimit      ratelimit.Limit
+		wantHeaders    map[string]string
+		wantBody       string
+	}{
+		{
+			name:        "allowed anonymous request",
+			path:        "/articles",
+			limiter:     limiter{res: allowed},
+			wantStatus:  http.StatusOK,
+			wantKey:     "GET /articles|ip:192.0.2.1",
+			wantLimit:   perMinute,
+			wantHeaders: map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "9", "RateLimit-Reset": "6"},
+		},
+		{
+			name:           "client IP of the proxy",
+			path:           "/articles",
+			forwarded:      "203.0.113.7, 10.0.0.1",
+			trustForwarded: true,
+			limiter:        limiter{res: allowed},
+			wantStatus:     http.StatusOK,
+			wantKey:        "GET /articles|ip:203.0.113.7",
+			wantLimit:      perMinute,
+		},
+		{
+			name:       "versions share the buckets of the route",
+			path:       "/v2/articles",
+			limiter:    limiter{res: allowed},
+			wantStatus: http.StatusOK,
+			wantKey:    "GET /articles|ip:192.0.2.1",
+			wantLimit:  perMinute,
+		},
+		{
+			name:       "principal with its own limit",
+			path:       "/articles",
+			principal:  &entities.Principal{Subject: "alice", Kind: consts.PrincipalUser},
+			limiter:    limiter{res: allowed},
+			wantStatus: http.StatusOK,
+			wantKey:    "GET /articles|principal:user:alice",
+			wantLimit:  perHour,
+		},
+		{
+			name:       "api key named like a user",
+			path:       "/articles",
+			principal:  &entities.Principal{Subject: "alice", Kind: consts.PrincipalAPIKey},
+			limiter:    limiter{res: allowed},
+			wantStatus: http.StatusOK,
+			wantKey:    "GET /articles|principal:api_key:alice",
+			wantLimit:  perMinute,
+		},
+		{
+			name:        "rate limit exceeded",
+			path:        "/articles",
+			limiter:     limiter{res: ratelimit.Result{Limit: 10, Reset: time.Minute, RetryAfter: 1500 * time.Millisecond}},
+			wantStatus:  http.StatusTooManyRequests,
+			wantKey:     "GET /articles|ip:192.0.2.1",
+			wantLimit:   perMinute,
+			wantHeaders: map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "2", "Content-Type": "application/problem+json"},
+			wantBody:    `{"type": "about:blank", "title": "Too Many Requests", "status": 429, "detail": "rate limit of 10/1m0s exceeded", "instance": "/articles"}`,
+		},
+		{
+			name:        "limiter failure lets the request through",
+			path:        "/articles",
+			limiter:     limiter{err: errors.New("database is locked")},
+			wantStatus:  http.StatusOK,
+			wantKey:     "GET /articles|ip:192.0.2.1",
+			wantLimit:   perMinute,
+			wantHeaders: map[string]string{"RateLimit-Limit": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mux.NewRouter()
+			r.HandleFunc("/articles", func(w http.ResponseWriter, r *http.Request) {})
+			r.HandleFunc("/v2/articles", func(w http.ResponseWriter, r *http.Request) {})
+			r.Use(RateLimit(&tt.limiter, rules, tt.trustForwarded))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.principal != nil {
+				req = req.WithContext(WithPrincipal(req.Context(), *tt.principal))
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantKey, tt.limiter.key)
+			assert.Equal(t, tt.wantLimit, tt.limiter.limit)
+			for name, value := range tt.wantHeaders {
+				assert.Equal(t, value, rec.Header().Get(name), name)
+			}
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package middlewares
+
+import (
+	"bufio"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// vendorType matches the vendor media types of the API versions, like application/vnd.articles.v2+json
+var vendorType = regexp.MustCompile(`^application/vnd\.articles\.([a-z0-9]+)(?:\+(json|xml))?$`)
+
+// APIVersion serves the paths under prefix without a version, like /articles, with a version:
+// the one of the vendor media type of their Accept or Content-Type header, like application/vnd.articles.v2+json,
+// or fallback. The vendor types are replaced with the media types of their suffix, application/json without one,
+// so the handlers don't know about them, and the response gets the vendor type back.
+// Requests to the paths of a version, like /v2/articles, can only use the vendor types of that version
+func APIVersion(prefix string, versions []string, fallback string) func(http.Handler) http.Handler {
+	known := map[string]bool{}
+	for _, v := range versions {
+		known[v] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pathVersion, ok := versionOf(r.URL.Path, prefix, known)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			accept, acceptVersion, acceptSuffix := vendorAccept(r.Header.Get("Accept"))
+			contentType, contentVersion := vendorContentType(r.Header.Get("Content-Type"))
+			version, status, detail := pickVersion(pathVersion, acceptVersion, contentVersion, known)
+			if status != 0 {
+				logging.FromContext(r.Context()).WithField("version", version).Warn(detail)
+				render.WriteProblem(w, r, status, detail)
+				return
+			}
+			if version == "" {
+				version = fallback
+			}
+
+			if pathVersion == "" || acceptVersion != "" || contentVersion != "" {
+				r = r.Clone(r.Context())
+			}
+			if acceptVersion != "" {
+				r.Header.Set("Accept", accept)
+			}
+			if contentVersion != "" {
+				r.Header.Set("Content-Type", contentType)
+			}
+			if pathVersion == "" {
+				r.URL.Path = "/" + version + r.URL.Path
+				r.URL.RawPath = ""
+			}
+			if acceptVersion != "" {
+				w = &vendorWriter{ResponseWriter: w, version: version, suffix: acceptSuffix}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Deprecated announces that the requests are to a deprecated version of the API, since the date, with the
+// Deprecation header of the IETF draft, and the Sunset header of RFC 8594 when sunset is not zero.
+// The path of the request with prefix replaced by successor is linked as its successor version
+func Deprecated(since, sunset time.Time, prefix, successor string) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			link := successor + strings.TrimPrefix(r.URL.Path, prefix)
+			w.Header().Add("Link", "<"+link+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// versionOf returns the version of a path under prefix, like v2 for /v2/articles, or an empty version
+// for the path without one, like /articles. It's false for the other paths
+func versionOf(path, prefix string, known map[string]bool) (string, bool) {
+	under := func(p string) bool {
+		return p == prefix || strings.HasPrefix(p, prefix+"/")
+	}
+	if under(path) {
+		return "", true
+	}
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) == 2 && known[parts[0]] && under("/"+parts[1]) {
+		return parts[0], true
+	}
+	return "", false
+}
+
+// pickVersion chooses the version of the request among the ones of its path and headers, any of which can be
+// empty. On conflict it returns the status and detail of the problem
+func pickVersion(pathVersion, acceptVersion, contentVersion string, known map[string]bool) (string, int, string) {
+	if acceptVersion != "" && !known[acceptVersion] {
+		return acceptVersion, http.StatusNotAcceptable, "API version " + acceptVersion + " of the Accept header does not exist"
+	}
+	if contentVersion != "" && !known[contentVersion] {
+		return contentVersion, http.StatusUnsupportedMediaType, "API version " + contentVersion + " of the Content-Type header does not exist"
+	}
+	if acceptVersion != "" && contentVersion != "" && acceptVersion != contentVersion {
+		return acceptVersion, http.StatusBadRequest, "Accept and Content-Type headers are of different API versions"
+	}
+	if pathVersion != "" && acceptVersion != "" && acceptVersion != pathVersion {
+		return acceptVersion, http.StatusNotAcceptable, "Accept header is of API version " + acceptVersion + ", the path is of " + pathVersion
+	}
+	if pathVersion != "" && contentVersion != "" && contentVersion != pathVersion {
+		return contentVersion, http.StatusUnsupportedMediaType, "Content-Type header is of API version " + contentVersion + ", the path is of " + pathVersion
+	}
+
+	for _, v := range []string{pathVersion, acceptVersion, contentVersion} {
+		if v != "" {
+			return v, 0, ""
+		}
+	}
+	return "", 0, ""
+}
+
+// vendorAccept replaces the vendor media types of an Accept header with the media types of their suffix,
+// and returns the version and suffix of the first one
+func vendorAccept(accept string) (string, string, string) {
+	var version, suffix string
+	ranges := strings.Split(accept, ",")
+	for i, rng := range ranges {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(rng))
+		if err != nil {
+			continue
+		}
+		m := vendorType.FindStringSubmatch(mediaType)
+		if m == nil {
+			continue
+		}
+		if version == "" {
+			version, suffix = m[1], m[2]
+		}
+		ranges[i] = mime.FormatMediaType(suffixType(m[2]), params)
+	}
+	return strings.Join(ranges, ", "), version, suffix
+}
+
+// vendorContentType replaces a vendor Content-Type with the media type of its suffix, and returns its version
+func vendorContentType(contentType string) (string, string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType, ""
+	}
+	m := vendorType.FindStringSubmatch(mediaType)
+	if m == nil {
+		return contentType, ""
+	}
+	return mime.FormatMediaType(suffixType(m[2]), params), m[1]
+}
+
+// suffixType is the media type of the suffix of a vendor type, JSON without one
+func suffixType(suffix string) string {
+	if suffix == "xml" {
+		return "application/xml"
+	}
+	return "application/json"
+}
+
+// vendorWriter gives the responses in the media type of the suffix the vendor type of the version back
+type vendorWriter struct {
+	http.ResponseWriter
+	version     string
+	suffix      string
+	wroteHeader bool
+}
+
+func (vw *vendorWriter) WriteHeader(status int) {
+	if !vw.wroteHeader {
+		vw.wroteHeader = true
+		h := vw.Header()
+		if mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type")); err == nil && mediaType == suffixType(vw.suffix) {
+			suffix := vw.suffix
+			if suffix == "" {
+				suffix = "json"
+			}
+			h.Set("Content-Type", mime.FormatMediaType("application/vnd.articles."+vw.version+"+"+suffix, params))
+		}
+	}
+	vw.ResponseWriter.WriteHeader(status)
+}
+
+func (vw *vendorWriter) Write(b []byte) (int, error) {
+	if !vw.wroteHeader {
+		vw.WriteHeader(http.StatusOK)
+	}
+	return vw.ResponseWriter.Write(b)
+}
+
+// Flush lets the event streams flush through the writer
+func (vw *vendorWriter) Flush() {
+	if f, ok := vw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the WebSocket handshakes take the connection through the writer
+func (vw *vendorWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := vw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
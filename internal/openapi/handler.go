@@ -0,0 +1,60 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Handler serves a document and Swagger UI
+type Handler struct {
+	spec []byte
+}
+
+// NewHandler is the Handler constructor, the document is encoded once
+func NewHandler(doc *Document) (Handler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return Handler{}, fmt.Errorf("could not encode openapi document: %w", err)
+	}
+	return Handler{spec: spec}, nil
+}
+
+// Spec serves the document as JSON
+func (h Handler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(h.spec)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.spec)
+}
+
+// UI serves Swagger UI, showing the document served at specURL
+func (h Handler) UI(specURL string) http.HandlerFunc {
+	page := fmt.Sprintf(swaggerUI, strconv.Quote(specURL))
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(page))
+	}
+}
+
+// swaggerUI loads Swagger UI from unpkg.com, the assets aren't served by the binary so the page needs the browser
+// to reach it. The API key or token is set with the Authorize button
+const swaggerUI = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Articles API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@3.52.5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@3.52.5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: %s, dom_id: '#swagger-ui'});
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,346 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxBodyBytes is the largest request body that is validated, the handlers reject larger ones
+const maxBodyBytes = 1 << 20
+
+// ValidationError is a part of a request or response that doesn't match the document
+type ValidationError struct {
+	// Path is the part, like body.title or path parameter id
+	Path   string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + " " + e.Reason
+}
+
+func invalid(path, format string, args ...interface{}) error {
+	return ValidationError{Path: path, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Validator checks requests and responses against a document. Only JSON bodies are validated,
+// the media types the handlers can't read or write are left to them
+type Validator struct {
+	doc *Document
+}
+
+// NewValidator is the Validator constructor
+func NewValidator(doc *Document) Validator {
+	return Validator{doc: doc}
+}
+
+// Operation returns the operation of the method and path template, false if the document doesn't describe it
+func (v Validator) Operation(method, path string) (*Operation, bool) {
+	op, ok := v.doc.Paths[path][strings.ToLower(method)]
+	return op, ok
+}
+
+// ValidateRequest checks the parameters and the body of a request to the operation, vars are its path parameters.
+// The body is read and replaced, so the handler reads it again
+func (v Validator) ValidateRequest(op *Operation, r *http.Request, vars map[string]string) error {
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = vars[p.Name]
+		case "query":
+			values, ok := r.URL.Query()[p.Name]
+			present = ok
+			if ok {
+				raw = values[0]
+			}
+		case "header":
+			raw = r.Header.Get(p.Name)
+			present = raw != ""
+		}
+
+		name := p.In + " parameter " + p.Name
+		if !present {
+			if p.Required {
+				return invalid(name, "is required")
+			}
+			continue
+		}
+		if err := v.validate(p.Schema, parameterValue(p.Schema, raw), name); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	mediaType, ok := jsonMediaType(r.Header.Get("Content-Type"))
+	if !ok {
+		return nil
+	}
+	content, ok := op.RequestBody.Content[mediaType]
+	if !ok {
+		return nil
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(b), r.Body))
+	if err != nil || len(b) > maxBodyBytes {
+		return nil
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		if op.RequestBody.Required {
+			return invalid("body", "is required")
+		}
+		return nil
+	}
+	value, err := decodeJSON(b)
+	if err != nil {
+		// malformed bodies are reported by the handlers, which know the position of the error
+		return nil
+	}
+	return v.validate(content.Schema, value, "body")
+}
+
+// ValidateResponse checks the status and the body of a response of the operation
+func (v Validator) ValidateResponse(op *Operation, status int, header http.Header, body []byte) error {
+	res, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if res, ok = op.Responses["default"]; !ok {
+			return invalid("response status", "%d is not documented", status)
+		}
+	}
+	res = v.response(res)
+
+	if len(res.Content) == 0 {
+		if len(body) > 0 {
+			return invalid("response body", "must be empty")
+		}
+		return nil
+	}
+
+	contentType := header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return invalid("response Content-Type", "%q is not a media type", contentType)
+	}
+	content, ok := res.Content[mediaType]
+	if !ok {
+		return invalid("response Content-Type", "%s is not documented for status %d", mediaType, status)
+	}
+	if _, ok := jsonMediaType(mediaType); !ok {
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return invalid("response body", "is not valid JSON")
+	}
+	return v.validate(content.Schema, value, "response body")
+}
+
+// Streams reports whether the operation streams its response or switches protocols,
+// so the response can't be buffered to be validated
+func (op *Operation) Streams() bool {
+	for status, res := range op.Responses {
+		if status == strconv.Itoa(http.StatusSwitchingProtocols) {
+			return true
+		}
+		if _, ok := res.Content["text/event-stream"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a decoded JSON value against the schema, path names the value in the errors
+func (v Validator) validate(s *Schema, value interface{}, path string) error {
+	s = v.schema(s)
+	if value == nil {
+		if s.Type == "" {
+			return nil
+		}
+		return invalid(path, "must be %s", article(s.Type))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid(path, "must be an object")
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return invalid(path+"."+name, "is required")
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return invalid(path+"."+name, "is not allowed")
+				}
+				continue
+			}
+			if err := v.validate(prop, obj[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return invalid(path, "must be an array")
+		}
+		for i, item := range items {
+			if err := v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalid(path, "must be a string")
+		}
+		return validateString(s, str, path)
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return invalid(path, "must be an integer")
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return invalid(path, "must be an integer")
+		}
+		if s.Minimum != nil && float64(i) < *s.Minimum {
+			return invalid(path, "must be at least %v", *s.Minimum)
+		}
+	case "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return invalid(path, "must be a number")
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return invalid(path, "must be a number")
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return invalid(path, "must be at least %v", *s.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid(path, "must be a boolean")
+		}
+	}
+	return nil
+}
+
+func validateString(s *Schema, str, path string) error {
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		return invalid(path, "must be at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return invalid(path, "must be at most %d characters", *s.MaxLength)
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			found = found || e == str
+		}
+		if !found {
+			return invalid(path, "must be one of %s", strings.Join(s.Enum, ", "))
+		}
+	}
+
+	var err error
+	switch s.Format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339Nano, str)
+	case "uuid":
+		_, err = uuid.Parse(str)
+	case "byte":
+		_, err = base64.StdEncoding.DecodeString(str)
+	}
+	if err != nil {
+		return invalid(path, "must be a %s", s.Format)
+	}
+	return nil
+}
+
+// schema follows the reference of a schema to the components
+func (v Validator) schema(s *Schema) *Schema {
+	for s.Ref != "" {
+		s = v.doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// response follows the reference of a response to the components
+func (v Validator) response(res *Response) *Response {
+	for res.Ref != "" {
+		res = v.doc.Components.Responses[strings.TrimPrefix(res.Ref, "#/components/responses/")]
+	}
+	return res
+}
+
+// parameterValue is the value of a raw parameter as if it was decoded from JSON
+func parameterValue(s *Schema, raw string) interface{} {
+	switch s.Type {
+	case "integer", "number":
+		return json.Number(raw)
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// jsonMediaType returns the media type of a Content-Type, if it's JSON
+func jsonMediaType(contentType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	return mediaType, mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeJSON decodes a single JSON value, keeping the numbers as json.Number
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// article prefixes a type with its article, as "an object"
+func article(typ string) string {
+	switch typ {
+	case "array", "object", "integer":
+		return "an " + typ
+	default:
+		return "a " + typ
+	}
+}
@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Tags    []string  `json:"tags,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func testDocument() *Document {
+	item := SchemaOf(testItem{})
+	maxName := 5
+	item.Properties["name"].MaxLength = &maxName
+	item.AdditionalProperties = new(bool)
+
+	return &Document{
+		OpenAPI: Version,
+		Paths: map[string]PathItem{
+			"/items/{id}": {
+				"put": {
+					OperationID: "putItem",
+					Parameters: []Parameter{
+						{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Minimum: new(float64)}},
+						{Name: "dry", In: "query", Schema: &Schema{Type: "boolean"}},
+					},
+					RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: SchemaRef("Item")}}},
+					Responses: map[string]*Response{
+						"200": {Description: "ok", Content: map[string]MediaType{"application/json": {Schema: SchemaRef("Item")}}},
+						"204": ResponseRef("Empty"),
+					},
+				},
+			},
+		},
+		Components: Components{
+			Schemas:   map[string]*Schema{"Item": item},
+			Responses: map[string]*Response{"Empty": {Description: "nothing"}},
+		},
+	}
+}
+
+func TestValidator_ValidateRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		id          string
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{
+			name:        "valid",
+			target:      "/items/1?dry=true",
+			id:          "1",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"id": 1, "name": "abc", "tags": ["a"], "created": "2021-01-02T03:04:05Z"}`,
+		},
+		{
+			name:    "path parameter of another type",
+			target:  "/items/one",
+			id:      "one",
+			wantErr: "path parameter id must be an integer",
+		},
+		{
+			name:    "path parameter under the minimum",
+			target:  "/items/-1",
+			id:      "-1",
+			wantErr: "path parameter id must be at least 0",
+		},
+		{
+			name:    "query parameter of another type",
+			target:  "/items/1?dry=maybe",
+			id:      "1",
+			wantErr: "query parameter dry must be a boolean",
+		},
+		{
+			name:        "missing body",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/json",
+			wantErr:     "body is required",
+		},
+		{
+			name:        "missing field",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/json",
+			body:        `{"id": 1, "created": "2021-01-02T03:04:05Z"}`,
+			wantErr:     "body.name is required",
+		},
+		{
+			name:        "unknown field",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/json",
+			body:        `{"id": 1, "name": "abc", "created": "2021-01-02T03:04:05Z", "extra": true}`,
+			wantErr:     "body.extra is not allowed",
+		},
+		{
+			name:        "too long",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/json",
+			body:        `{"id": 1, "name": "abcdef", "created": "2021-01-02T03:04:05Z"}`,
+			wantErr:     "body.name must be at most 5 characters",
+		},
+		{
+			name:        "item of another type",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/json",
+			body:        `{"id": 1, "name": "abc", "tags": [1], "created": "2021-01-02T03:04:05Z"}`,
+			wantErr:     "body.tags[0] must be a string",
+		},
+		{
+			name:        "bad date",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/json",
+			body:        `{"id": 1, "name": "abc", "created": "yesterday"}`,
+			wantErr:     "body.created must be a date-time",
+		},
+		{
+			name:        "other media types are left to the handler",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/xml",
+			body:        `<item></item>`,
+		},
+		{
+			name:        "malformed JSON is left to the handler",
+			target:      "/items/1",
+			id:          "1",
+			contentType: "application/json",
+			body:        `{"id":`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(testDocument())
+			op, ok := v.Operation(http.MethodPut, "/items/{id}")
+			require.True(t, ok)
+
+			req := httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			err := v.ValidateRequest(op, req, map[string]string{"id": tt.id})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			// the handler reads the whole body again
+			body, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestValidator_ValidateResponse(t *testing.T) {
+	jsonType := http.Header{"Content-Type": {"application/json"}}
+	tests := []struct {
+		name    string
+		status  int
+		header  http.Header
+		body    string
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			status: http.StatusOK,
+			header: jsonType,
+			body:   `{"id": 1, "name": "abc", "created": "2021-01-02T03:04:05Z"}`,
+		},
+		{
+			name:   "referenced response without body",
+			status: http.StatusNoContent,
+		},
+		{
+			name:    "undocumented status",
+			status:  http.StatusTeapot,
+			wantErr: "response status 418 is not documented",
+		},
+		{
+			name:    "undocumented media type",
+			status:  http.StatusOK,
+			header:  http.Header{"Content-Type": {"text/plain"}},
+			body:    `ok`,
+			wantErr: "response Content-Type text/plain is not documented for status 200",
+		},
+		{
+			name:    "missing field",
+			status:  http.StatusOK,
+			header:  jsonType,
+			body:    `{"id": 1, "created": "2021-01-02T03:04:05Z"}`,
+			wantErr: "response body.name is required",
+		},
+		{
+			name:    "body where none is documented",
+			status:  http.StatusNoContent,
+			body:    `{}`,
+			wantErr: "response body must be empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(testDocument())
+			op, _ := v.Operation(http.MethodPut, "/items/{id}")
+
+			err := v.ValidateResponse(op, tt.status, tt.header, []byte(tt.body))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
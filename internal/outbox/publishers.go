@@ -0,0 +1,195 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	v1 "github.com/nachogoca/golang-example-rest-api-layout/internal/transports/v1"
+)
+
+// natsTimeout bounds the connection and each publication to the NATS server
+const natsTimeout = 5 * time.Second
+
+// File appends the events to a file, one JSON object per line
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFile is the File constructor, the file is created if it doesn't exist
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open outbox file: %w", err)
+	}
+	return &File{file: f}, nil
+}
+
+// Publish writes the event
+func (f *File) Publish(ctx context.Context, event entities.ArticleEvent) error {
+	b, err := json.Marshal(v1.NewEvent(event))
+	if err != nil {
+		return fmt.Errorf("could not encode event: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("could not write event: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.file.Close()
+}
+
+// NATS publishes the events to a NATS server, speaking its text protocol, on the subject
+// <prefix>.<event type>. Each publication is followed by a PING, and is only done once the
+// server answers, so the events the server didn't get are published again
+type NATS struct {
+	addr    string
+	prefix  string
+	connect []byte
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// natsConnect are the options sent to the server when connecting
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+}
+
+// NewNATS is the NATS constructor, the url is like nats://[user:password@]host:4222
+// It connects on the first publication
+func NewNATS(rawURL, prefix string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse nats url: %w", err)
+	}
+	if u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("nats url must be like nats://host:4222")
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	opts := natsConnect{Name: "articles"}
+	if u.User != nil {
+		opts.User = u.User.Username()
+		opts.Pass, _ = u.User.Password()
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode nats options: %w", err)
+	}
+	return &NATS{addr: addr, prefix: prefix, connect: []byte("CONNECT " + string(connect) + "\r\n")}, nil
+}
+
+// Publish sends the event as JSON and waits for the server to process it
+func (n *NATS) Publish(ctx context.Context, event entities.ArticleEvent) error {
+	payload, err := json.Marshal(v1.NewEvent(event))
+	if err != nil {
+		return fmt.Errorf("could not encode event: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.dial(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(natsTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	n.conn.SetDeadline(deadline)
+
+	msg := fmt.Sprintf("PUB %s.%s %d\r\n%s\r\nPING\r\n", n.prefix, event.Type, len(payload), payload)
+	if _, err := n.conn.Write([]byte(msg)); err != nil {
+		n.disconnect()
+		return fmt.Errorf("could not write to nats: %w", err)
+	}
+	if err := n.waitPong(); err != nil {
+		n.disconnect()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	return n.disconnect()
+}
+
+func (n *NATS) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: natsTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("could not connect to nats: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(natsTimeout))
+
+	// the server starts with its INFO
+	n.conn, n.reader = conn, bufio.NewReader(conn)
+	line, err := n.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		n.disconnect()
+		return fmt.Errorf("could not read nats info: %q %v", line, err)
+	}
+	if _, err := conn.Write(n.connect); err != nil {
+		n.disconnect()
+		return fmt.Errorf("could not write to nats: %w", err)
+	}
+	return nil
+}
+
+// waitPong reads until the PONG of the server, answering its pings
+func (n *NATS) waitPong() error {
+	for {
+		line, err := n.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("could not read from nats: %w", err)
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("could not write to nats: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats error: %s", line)
+		}
+	}
+}
+
+func (n *NATS) disconnect() error {
+	err := n.conn.Close()
+	n.conn, n.reader = nil, nil
+	return err
+}
@@ -0,0 +1,216 @@
+// Package outbox relays the article events written to the outbox of the database to the publishers,
+// in order and at least once
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+)
+
+// relayBatch is how many events are read from the outbox at once
+const relayBatch = 100
+
+// pruneInterval is how often the events every destination got are pruned
+const pruneInterval = time.Hour
+
+// Source is the outbox, with the offset of each publisher
+type Source interface {
+	Since(ctx context.Context, afterID int64, limit int) ([]entities.ArticleEvent, error)
+	Offset(ctx context.Context, publisher string) (int64, error)
+	SetOffset(ctx context.Context, publisher string, eventID int64) error
+	Prune(ctx context.Context, upToID int64, before time.Time) (int64, error)
+}
+
+// Publisher gets the events of the outbox. An event can be published again if the relay stops
+// before recording it was published, so publishers must tolerate duplicates, the ids tell them apart
+type Publisher interface {
+	Publish(ctx context.Context, event entities.ArticleEvent) error
+}
+
+// PublisherFunc adapts a function to a Publisher
+type PublisherFunc func(ctx context.Context, event entities.ArticleEvent) error
+
+// Publish calls f
+func (f PublisherFunc) Publish(ctx context.Context, event entities.ArticleEvent) error {
+	return f(ctx, event)
+}
+
+// Destination is a publisher and the name its offset is recorded with, renaming it publishes
+// the whole outbox again
+type Destination struct {
+	Name      string
+	Publisher Publisher
+}
+
+// Relay publishes the events of the outbox to each destination
+type Relay struct {
+	source       Source
+	destinations []Destination
+	interval     time.Duration
+	retention    time.Duration
+	wakes        []chan struct{}
+	done         chan struct{}
+}
+
+// NewRelay is the Relay constructor, interval is how often the outbox is checked for events
+// that were not notified, and how long a failing destination waits before trying again. The events every
+// destination got are pruned once they are older than retention, none are if it's 0
+func NewRelay(source Source, interval, retention time.Duration, destinations ...Destination) *Relay {
+	wakes := make([]chan struct{}, len(destinations))
+	for i := range wakes {
+		wakes[i] = make(chan struct{}, 1)
+	}
+	return &Relay{
+		source:       source,
+		destinations: destinations,
+		interval:     interval,
+		retention:    retention,
+		wakes:        wakes,
+		done:         make(chan struct{}),
+	}
+}
+
+// Notify wakes the relay up, once new events are committed to the outbox
+func (r *Relay) Notify() {
+	for _, wake := range r.wakes {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run relays the events until ctx is canceled. Each destination goes at its own pace,
+// one that fails gets the same event again after the interval, without holding the others back
+func (r *Relay) Run(ctx context.Context) {
+	defer close(r.done)
+
+	var wg sync.WaitGroup
+	if r.retention > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.runPrune(ctx)
+		}()
+	}
+	for i, d := range r.destinations {
+		wg.Add(1)
+		go func(d Destination, wake chan struct{}) {
+			defer wg.Done()
+			r.run(ctx, d, wake)
+		}(d, r.wakes[i])
+	}
+	wg.Wait()
+}
+
+// Wait blocks until Run has finished, or ctx is done
+func (r *Relay) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) run(ctx context.Context, d Destination, wake chan struct{}) {
+	log := logging.FromContext(ctx).WithField("publisher", d.Name)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		relayed, err := r.relay(ctx, d)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.WithError(err).Error("could not relay article events")
+		}
+		// a full batch means there may be more events waiting
+		if err == nil && relayed == relayBatch {
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// relay publishes the events after the offset of the destination, and records the offset after each one
+func (r *Relay) relay(ctx context.Context, d Destination) (int, error) {
+	offset, err := r.source.Offset(ctx, d.Name)
+	if err != nil {
+		return 0, err
+	}
+	events, err := r.source.Since(ctx, offset, relayBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, event := range events {
+		if err := d.Publisher.Publish(ctx, event); err != nil {
+			return i, fmt.Errorf("could not publish article event %d: %w", event.ID, err)
+		}
+		if err := r.source.SetOffset(ctx, d.Name, event.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+func (r *Relay) runPrune(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.prune(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).WithError(err).Error("could not prune outbox")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// prune deletes the events older than the retention that every destination got, the ones a destination
+// that falls behind still needs are kept
+func (r *Relay) prune(ctx context.Context, now time.Time) error {
+	if len(r.destinations) == 0 {
+		return nil
+	}
+
+	var upTo int64
+	for i, d := range r.destinations {
+		offset, err := r.source.Offset(ctx, d.Name)
+		if err != nil {
+			return err
+		}
+		if i == 0 || offset < upTo {
+			upTo = offset
+		}
+	}
+	if upTo == 0 {
+		return nil
+	}
+
+	pruned, err := r.source.Prune(ctx, upTo, now.Add(-r.retention))
+	if err != nil {
+		return err
+	}
+	if pruned > 0 {
+		logging.FromContext(ctx).WithField("pruned", pruned).WithField("upTo", upTo).Info("pruned outbox")
+	}
+	return nil
+}
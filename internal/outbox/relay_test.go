@@ -0,0 +1,249 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// memorySource is an outbox in memory
+type memorySource struct {
+	mu      sync.Mutex
+	events  []entities.ArticleEvent
+	offsets map[string]int64
+}
+
+func newMemorySource(n int) *memorySource {
+	s := &memorySource{offsets: map[string]int64{}}
+	for i := 0; i < n; i++ {
+		s.add()
+	}
+	return s
+}
+
+func (s *memorySource) add() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, entities.ArticleEvent{ID: int64(len(s.events) + 1), Type: "created"})
+}
+
+func (s *memorySource) Since(ctx context.Context, afterID int64, limit int) ([]entities.ArticleEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []entities.ArticleEvent
+	for _, e := range s.events {
+		if e.ID > afterID && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (s *memorySource) Offset(ctx context.Context, publisher string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offsets[publisher], nil
+}
+
+func (s *memorySource) SetOffset(ctx context.Context, publisher string, eventID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offsets[publisher] = eventID
+	return nil
+}
+
+func (s *memorySource) Prune(ctx context.Context, upToID int64, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept []entities.ArticleEvent
+	for i, e := range s.events {
+		if e.ID > upToID || !e.OccurredAt.Before(before) || i == len(s.events)-1 {
+			kept = append(kept, e)
+		}
+	}
+	pruned := len(s.events) - len(kept)
+	s.events = kept
+	return int64(pruned), nil
+}
+
+func (s *memorySource) ids() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []int64
+	for _, e := range s.events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// recorder is a publisher that records the ids it got, and fails while fail is set
+type recorder struct {
+	mu   sync.Mutex
+	ids  []int64
+	fail bool
+}
+
+func (r *recorder) Publish(ctx context.Context, event entities.ArticleEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("unavailable")
+	}
+	r.ids = append(r.ids, event.ID)
+	return nil
+}
+
+func (r *recorder) got() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.ids...)
+}
+
+func (r *recorder) setFail(fail bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fail = fail
+}
+
+func ids(from, to int64) []int64 {
+	var ids []int64
+	for id := from; id <= to; id++ {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestRelay_Run(t *testing.T) {
+	source := newMemorySource(150)
+	source.offsets["up"] = 20
+	up, down := &recorder{}, &recorder{fail: true}
+	// the interval is long so only Notify and full batches move the relay after the first pass
+	relay := NewRelay(source, time.Hour, 0, Destination{Name: "up", Publisher: up}, Destination{Name: "down", Publisher: down})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go relay.Run(ctx)
+
+	// a destination starts after its offset, and goes through more than a batch
+	assert.Eventually(t, func() bool { return len(up.got()) == 130 }, time.Second, time.Millisecond)
+	assert.Equal(t, ids(21, 150), up.got())
+	// the failing destination doesn't hold the other back, and keeps its offset
+	assert.Empty(t, down.got())
+	offset, _ := source.Offset(ctx, "down")
+	assert.Equal(t, int64(0), offset)
+
+	source.add()
+	relay.Notify()
+	assert.Eventually(t, func() bool { return len(up.got()) == 131 }, time.Second, time.Millisecond)
+	offset, _ = source.Offset(ctx, "up")
+	assert.Equal(t, int64(151), offset)
+
+	// once it recovers, it gets every event from the first it missed
+	down.setFail(false)
+	relay.Notify()
+	assert.Eventually(t, func() bool { return len(down.got()) == 151 }, time.Second, time.Millisecond)
+	assert.Equal(t, ids(1, 151), down.got())
+
+	cancel()
+	assert.NoError(t, relay.Wait(context.Background()))
+}
+
+func TestRelay_RetriesAfterInterval(t *testing.T) {
+	source := newMemorySource(3)
+	calls := 0
+	var mu sync.Mutex
+	var published []int64
+	flaky := PublisherFunc(func(ctx context.Context, event entities.ArticleEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		// the second event fails the first time
+		if event.ID == 2 && calls == 2 {
+			return errors.New("timeout")
+		}
+		published = append(published, event.ID)
+		return nil
+	})
+	relay := NewRelay(source, 10*time.Millisecond, 0, Destination{Name: "flaky", Publisher: flaky})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Run(ctx)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(published) == 3
+	}, time.Second, time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []int64{1, 2, 3}, published)
+	mu.Unlock()
+}
+
+func TestRelay_Prune(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		destinations []Destination
+		offsets      map[string]int64
+		retention    time.Duration
+		want         []int64
+	}{
+		{
+			name:         "events every destination got",
+			destinations: []Destination{{Name: "up"}, {Name: "behind"}},
+			offsets:      map[string]int64{"up": 5, "behind": 3},
+			retention:    time.Hour,
+			want:         []int64{4, 5},
+		},
+		{
+			name:         "a destination that got none keeps them all",
+			destinations: []Destination{{Name: "up"}, {Name: "new"}},
+			offsets:      map[string]int64{"up": 5},
+			retention:    time.Hour,
+			want:         ids(1, 5),
+		},
+		{
+			name:         "offsets of removed destinations don't count",
+			destinations: []Destination{{Name: "up"}},
+			offsets:      map[string]int64{"up": 4, "removed": 1},
+			retention:    time.Hour,
+			want:         []int64{5},
+		},
+		{
+			name:         "the last event is kept",
+			destinations: []Destination{{Name: "up"}},
+			offsets:      map[string]int64{"up": 5},
+			retention:    time.Hour,
+			want:         []int64{5},
+		},
+		{
+			name:         "events within the retention are kept",
+			destinations: []Destination{{Name: "up"}},
+			offsets:      map[string]int64{"up": 5},
+			retention:    3 * time.Hour,
+			want:         []int64{3, 4, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// event i occurred 6-i hours ago
+			source := newMemorySource(0)
+			for id := int64(1); id <= 5; id++ {
+				source.events = append(source.events, entities.ArticleEvent{ID: id, OccurredAt: now.Add(time.Duration(id-6) * time.Hour)})
+			}
+			source.offsets = tt.offsets
+			relay := NewRelay(source, time.Hour, tt.retention, tt.destinations...)
+
+			assert.NoError(t, relay.prune(context.Background(), now))
+			assert.Equal(t, tt.want, source.ids())
+		})
+	}
+}
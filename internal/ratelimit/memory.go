@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the idle buckets are removed from memory
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+// Memory keeps the buckets in the process memory
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemory is the Memory constructor
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: map[string]memoryBucket{},
+		now:     time.Now,
+	}
+}
+
+// Take takes a token of the bucket of key
+func (m *Memory) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	bucket, res := m.buckets[key].Take(limit, now)
+	m.buckets[key] = memoryBucket{Bucket: bucket, limit: limit}
+	return res, nil
+}
+
+// sweep removes the buckets that are full again, a missing bucket is the same as a full one
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if now.Sub(b.Updated) >= b.limit.Period {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package ratelimit implements token buckets, kept in memory or in a shared store
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilled at Burst requests every Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses a limit like 100/1m, which allows 100 requests per minute
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("limit %q must look like <requests>/<period>", s)
+	}
+
+	burst, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must have a positive number of requests", s)
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must have a positive period", s)
+	}
+
+	return Limit{Burst: burst, Period: period}, nil
+}
+
+// String formats the limit as ParseLimit expects it
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// rate returns the tokens refilled per second
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the state of a bucket after taking a token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, zero if allowed
+	RetryAfter time.Duration
+}
+
+// Bucket is the persisted state of a token bucket
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take refills the bucket up to now and takes a token if there's one available
+// A zero bucket is a full one
+func (b Bucket) Take(limit Limit, now time.Time) (Bucket, Result) {
+	burst := float64(limit.Burst)
+	tokens := burst
+	if !b.Updated.IsZero() {
+		elapsed := now.Sub(b.Updated).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(burst, b.Tokens+elapsed*limit.rate())
+	}
+
+	res := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((burst - tokens) / limit.rate())
+	return Bucket{Tokens: tokens, Updated: now}, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps the buckets, Memory for a single instance
+// or one backed by a shared database for several instances
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
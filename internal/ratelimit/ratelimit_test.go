@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket_Take(t *testing.T) {
+	limit := Limit{Burst: 2, Period: 2 * time.Second}
+	start := time.Unix(1000, 0)
+
+	tests := []struct {
+		name   string
+		bucket Bucket
+		now    time.Time
+		want   Result
+	}{
+		{
+			name:   "Success: New bucket is full",
+			bucket: Bucket{},
+			now:    start,
+			want:   Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second},
+		},
+		{
+			name:   "Success: Refilled since last request",
+			bucket: Bucket{Tokens: 0, Updated: start},
+			now:    start.Add(time.Second),
+			want:   Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second},
+		},
+		{
+			name:   "Success: Refill is capped at burst",
+			bucket: Bucket{Tokens: 0, Updated: start},
+			now:    start.Add(time.Hour),
+			want:   Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second},
+		},
+		{
+			name:   "Failure: Empty bucket",
+			bucket: Bucket{Tokens: 0.5, Updated: start},
+			now:    start,
+			want:   Result{Allowed: false, Limit: 2, Remaining: 0, Reset: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := tt.bucket.Take(limit, tt.now)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMemory_Take(t *testing.T) {
+	m := NewMemory()
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+	limit := Limit{Burst: 1, Period: time.Minute}
+
+	res, _ := m.Take(context.Background(), "a", limit)
+	assert.True(t, res.Allowed)
+	res, _ = m.Take(context.Background(), "a", limit)
+	assert.False(t, res.Allowed)
+	res, _ = m.Take(context.Background(), "b", limit)
+	assert.True(t, res.Allowed, "buckets are independent")
+
+	now = now.Add(time.Minute)
+	res, _ = m.Take(context.Background(), "a", limit)
+	assert.True(t, res.Allowed, "bucket is refilled")
+}
+
+func TestRules_For(t *testing.T) {
+	routes, err := ParseLimits("POST /articles=10/1m; PUT /articles/{id}=20/1m")
+	if err != nil {
+		t.Fatalf("ParseLimits() error = %v", err)
+	}
+	principals, err := ParseLimits("user:alice=1000/1h")
+	if err != nil {
+		t.Fatalf("ParseLimits() error = %v", err)
+	}
+	rules := Rules{Default: Limit{Burst: 100, Period: time.Minute}, Routes: routes, Principals: principals}
+
+	assert.Equal(t, Limit{Burst: 10, Period: time.Minute}, rules.For("POST /articles", "user:bob"))
+	assert.Equal(t, Limit{Burst: 20, Period: time.Minute}, rules.For("PUT /articles/{id}", ""))
+	assert.Equal(t, Limit{Burst: 1000, Period: time.Hour}, rules.For("POST /articles", "user:alice"))
+	assert.Equal(t, Limit{Burst: 10, Period: time.Minute}, rules.For("POST /articles", "api_key:alice"))
+	assert.Equal(t, Limit{Burst: 100, Period: time.Minute}, rules.For("GET /articles", ""))
+
+	_, err = ParseLimits("POST /articles=ten/1m")
+	assert.Error(t, err)
+}
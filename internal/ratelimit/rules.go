@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rules choose the limit of a request
+// The limit of the principal wins over the one of the route, which wins over the default one
+type Rules struct {
+	Default    Limit
+	Routes     map[string]Limit
+	Principals map[string]Limit
+}
+
+// For returns the limit of a route, like "POST /articles", requested by a principal, its ID like api_key:frontend
+// principal is empty for anonymous requests
+func (r Rules) For(route, principal string) Limit {
+	if l, ok := r.Principals[principal]; ok && principal != "" {
+		return l
+	}
+	if l, ok := r.Routes[route]; ok {
+		return l
+	}
+	return r.Default
+}
+
+// ParseLimits parses a list like "POST /articles=10/1m;PUT /articles/{id}=20/1m"
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("limit %q must look like <name>=<requests>/<period>", entry)
+		}
+
+		limit, err := ParseLimit(entry[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(entry[:i])] = limit
+	}
+	return limits, nil
+}
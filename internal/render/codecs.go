@@ -0,0 +1,351 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v4"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrUnsupported is returned by codecs that can't encode or decode a value, e.g. CSV only encodes lists
+var ErrUnsupported = errors.New("unsupported by the media type")
+
+// ErrTrailingData is returned when a JSON body has more than one value
+var ErrTrailingData = errors.New("request body must only contain a single JSON object")
+
+// Codec encodes responses and decodes requests of some media types
+type Codec interface {
+	// MediaTypes lists the media types of the codec, the first one is used in the Content-Type of responses
+	MediaTypes() []string
+	Encode(w io.Writer, v interface{}) error
+	// Decode must reject unknown fields
+	Decode(r io.Reader, v interface{}) error
+}
+
+// JSONCodec encodes and decodes JSON
+type JSONCodec struct{}
+
+// MediaTypes of JSON
+func (JSONCodec) MediaTypes() []string { return []string{"application/json"} }
+
+// Encode writes v as JSON
+func (JSONCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode reads a single JSON value into v, unknown fields are an error
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// XMLCodec encodes and decodes XML, named after the Go types:
+// an article is <article> and a list of them <articles><article>...</article></articles>.
+// Request bodies must be flat, one child element per field
+type XMLCodec struct{}
+
+// MediaTypes of XML
+func (XMLCodec) MediaTypes() []string { return []string{"application/xml", "text/xml"} }
+
+// Encode writes v as XML
+func (XMLCodec) Encode(w io.Writer, v interface{}) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+
+	name, itemName := xmlNames(reflect.TypeOf(v))
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeXML(enc, name, itemName, tree); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func writeXML(enc *xml.Encoder, name, itemName string, v interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case object:
+		for _, f := range t {
+			if err := writeXML(enc, f.key, "item", f.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, e := range t {
+			if err := writeXML(enc, itemName, "item", e); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(scalar(t))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlNames returns the element names of a type and of the items of a list
+func xmlNames(t reflect.Type) (string, string) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return "response", "item"
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		item, _ := xmlNames(t.Elem())
+		return item + "s", item
+	}
+	if t.Name() == "" {
+		return "response", "item"
+	}
+	return strings.ToLower(t.Name()[:1]) + t.Name()[1:], "item"
+}
+
+// Decode reads the child elements of the root as the fields of v
+func (XMLCodec) Decode(r io.Reader, v interface{}) error {
+	dec := xml.NewDecoder(r)
+	fields := map[string]interface{}{}
+	depth, elements := 0, 0
+	var name string
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			elements++
+			if depth > 2 {
+				return fmt.Errorf("element %s: nested elements are not supported", t.Name.Local)
+			}
+			name = t.Name.Local
+			text.Reset()
+		case xml.CharData:
+			if depth == 2 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				fields[name] = text.String()
+			}
+			depth--
+		}
+	}
+	if elements == 0 {
+		return io.EOF
+	}
+	if depth != 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return fromTree(fields, v)
+}
+
+// YAMLCodec encodes and decodes YAML
+type YAMLCodec struct{}
+
+// MediaTypes of YAML
+func (YAMLCodec) MediaTypes() []string {
+	return []string{"application/yaml", "application/x-yaml", "text/yaml"}
+}
+
+// Encode writes v as YAML
+func (YAMLCodec) Encode(w io.Writer, v interface{}) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(yamlValue(tree))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// yamlValue converts a tree to the types yaml marshals in order and without quoting numbers
+func yamlValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case object:
+		ms := make(yaml.MapSlice, len(t))
+		for i, f := range t {
+			ms[i] = yaml.MapItem{Key: f.key, Value: yamlValue(f.value)}
+		}
+		return ms
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, e := range t {
+			list[i] = yamlValue(e)
+		}
+		return list
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	default:
+		return t
+	}
+}
+
+// Decode reads a YAML document into v
+func (YAMLCodec) Decode(r io.Reader, v interface{}) error {
+	var doc interface{}
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	tree, err := jsonValue(doc)
+	if err != nil {
+		return err
+	}
+	return fromTree(tree, v)
+}
+
+// jsonValue converts the maps decoded by yaml, which have interface{} keys, to maps that can be encoded as JSON
+func jsonValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v must be a string", k)
+			}
+			value, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, e := range t {
+			value, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	default:
+		return t, nil
+	}
+}
+
+// MsgPackCodec encodes and decodes MessagePack, with the json tags as field names
+type MsgPackCodec struct{}
+
+// MediaTypes of MessagePack
+func (MsgPackCodec) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+// Encode writes v as MessagePack
+func (MsgPackCodec) Encode(w io.Writer, v interface{}) error {
+	return msgpack.NewEncoder(w).UseJSONTag(true).Encode(v)
+}
+
+// Decode reads a MessagePack value into v, unknown fields are an error
+func (MsgPackCodec) Decode(r io.Reader, v interface{}) error {
+	dec := msgpack.NewDecoder(r).UseJSONTag(true)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// CSVCodec encodes lists of objects as CSV, with a header row of the field names
+type CSVCodec struct{}
+
+// MediaTypes of CSV
+func (CSVCodec) MediaTypes() []string { return []string{"text/csv"} }
+
+// Encode writes a list as CSV, any other value is ErrUnsupported
+func (CSVCodec) Encode(w io.Writer, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Slice {
+		return ErrUnsupported
+	}
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	list, ok := tree.([]interface{})
+	if !ok {
+		return ErrUnsupported
+	}
+
+	// the header of an empty list comes from the zero value of its items
+	sample, err := toTree(reflect.Zero(t.Elem()).Interface())
+	if err != nil {
+		return err
+	}
+	header, ok := sample.(object)
+	if !ok {
+		return ErrUnsupported
+	}
+
+	cw := csv.NewWriter(w)
+	row := make([]string, len(header))
+	for i, f := range header {
+		row[i] = f.key
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, e := range list {
+		obj, ok := e.(object)
+		if !ok {
+			return ErrUnsupported
+		}
+		values := make(map[string]interface{}, len(obj))
+		for _, f := range obj {
+			values[f.key] = f.value
+		}
+		for i, f := range header {
+			row[i] = scalar(values[f.key])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Decode is ErrUnsupported, request bodies are objects
+func (CSVCodec) Decode(r io.Reader, v interface{}) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,153 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+)
+
+// Negotiator chooses the codec of responses from the Accept header, and of requests from the Content-Type header
+type Negotiator struct {
+	codecs []Codec
+}
+
+// NewNegotiator is the Negotiator constructor, the first codec is used when the client accepts any media type
+func NewNegotiator(codecs ...Codec) Negotiator {
+	return Negotiator{codecs: codecs}
+}
+
+// Render writes v with the status, encoded with the codec the client prefers among those that can encode it.
+// It responds 406 when there is none
+func (n Negotiator) Render(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Add("Vary", "Accept")
+	for _, c := range n.acceptable(r.Header.Get("Accept")) {
+		var buf bytes.Buffer
+		err := c.Encode(&buf, v)
+		if errors.Is(err, ErrUnsupported) {
+			continue
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).WithError(err).Error("could not encode response")
+			WriteProblem(w, r, http.StatusInternalServerError, "")
+			return
+		}
+		write(w, status, c.MediaTypes()[0], buf.Bytes())
+		return
+	}
+
+	WriteProblem(w, r, http.StatusNotAcceptable, "response can be "+strings.Join(n.MediaTypes(), ", "))
+}
+
+// Codec returns the codec of a Content-Type header
+func (n Negotiator) Codec(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	for _, c := range n.codecs {
+		for _, mt := range c.MediaTypes() {
+			if mt == mediaType {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// MediaTypes lists the main media type of each codec
+func (n Negotiator) MediaTypes() []string {
+	types := make([]string, len(n.codecs))
+	for i, c := range n.codecs {
+		types[i] = c.MediaTypes()[0]
+	}
+	return types
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// acceptable returns the codecs the Accept header allows, preferred first.
+// Each codec gets the quality of the most specific range that matches it, ties keep the order of the codecs
+func (n Negotiator) acceptable(accept string) []Codec {
+	if strings.TrimSpace(accept) == "" {
+		return n.codecs
+	}
+	ranges := parseAccept(accept)
+
+	type candidate struct {
+		codec Codec
+		q     float64
+	}
+	var candidates []candidate
+	for _, c := range n.codecs {
+		best, specificity := 0.0, -1
+		for _, mt := range c.MediaTypes() {
+			typ := strings.SplitN(mt, "/", 2)
+			for _, mr := range ranges {
+				s := mr.matches(typ[0], typ[1])
+				if s > specificity || (s == specificity && mr.q > best) {
+					best, specificity = mr.q, s
+				}
+			}
+		}
+		if specificity >= 0 && best > 0 {
+			candidates = append(candidates, candidate{codec: c, q: best})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	codecs := make([]Codec, len(candidates))
+	for i, c := range candidates {
+		codecs[i] = c.codec
+	}
+	return codecs
+}
+
+// matches returns how specific the range is for the media type, or -1 if it doesn't match
+func (mr mediaRange) matches(typ, subtype string) int {
+	switch {
+	case mr.typ == typ && mr.subtype == subtype:
+		return 2
+	case mr.typ == typ && mr.subtype == "*":
+		return 1
+	case mr.typ == "*" && mr.subtype == "*":
+		return 0
+	default:
+		return -1
+	}
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == "*" {
+			// sent by some clients for */*
+			mediaType = "*/*"
+		}
+		typ := strings.SplitN(mediaType, "/", 2)
+		if len(typ) != 2 {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ[0], subtype: typ[1], q: q})
+	}
+	return ranges
+}
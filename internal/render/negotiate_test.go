@@ -0,0 +1,208 @@
+package render
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Count int    `json:"count"`
+}
+
+func TestNegotiator_Render(t *testing.T) {
+	n := NewNegotiator(JSONCodec{}, XMLCodec{}, YAMLCodec{}, MsgPackCodec{}, CSVCodec{})
+
+	tests := []struct {
+		name            string
+		accept          string
+		value           interface{}
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "no accept header",
+			value:           item{ID: "1", Title: "title", Count: 2},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"id":"1","title":"title","count":2}` + "\n",
+		},
+		{
+			name:            "xml",
+			accept:          "application/xml",
+			value:           item{ID: "1", Title: "a < b", Count: 2},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<item><id>1</id><title>a &lt; b</title><count>2</count></item>` + "\n",
+		},
+		{
+			name:            "xml list",
+			accept:          "text/xml",
+			value:           []item{{ID: "1"}, {ID: "2"}},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<items><item><id>1</id><title></title><count>0</count></item><item><id>2</id><title></title><count>0</count></item></items>` + "\n",
+		},
+		{
+			name:            "yaml keeps the field order",
+			accept:          "application/x-yaml",
+			value:           item{ID: "1", Title: "title", Count: 2},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/yaml",
+			wantBody:        "id: \"1\"\ntitle: title\ncount: 2\n",
+		},
+		{
+			name:            "csv list",
+			accept:          "text/csv",
+			value:           []item{{ID: "1", Title: "a, b", Count: 2}},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv",
+			wantBody:        "id,title,count\n1,\"a, b\",2\n",
+		},
+		{
+			name:            "csv empty list has a header",
+			accept:          "text/csv",
+			value:           []item{},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv",
+			wantBody:        "id,title,count\n",
+		},
+		{
+			name:            "quality values",
+			accept:          "application/json;q=0.5, application/yaml",
+			value:           item{ID: "1"},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/yaml",
+			wantBody:        "id: \"1\"\ntitle: \"\"\ncount: 0\n",
+		},
+		{
+			name:            "most specific range wins",
+			accept:          "*/*;q=0.1, application/json;q=0",
+			value:           item{ID: "1"},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<item><id>1</id><title></title><count>0</count></item>` + "\n",
+		},
+		{
+			name:            "csv falls back for single objects",
+			accept:          "text/csv, application/json;q=0.5",
+			value:           item{ID: "1"},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"id":"1","title":"","count":0}` + "\n",
+		},
+		{
+			name:            "csv only for single objects",
+			accept:          "text/csv",
+			value:           item{ID: "1"},
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Not Acceptable","status":406,"detail":"response can be application/json, application/xml, application/yaml, application/msgpack, text/csv","instance":"/articles"}`,
+		},
+		{
+			name:            "unsupported media type",
+			accept:          "text/html",
+			value:           item{ID: "1"},
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Not Acceptable","status":406,"detail":"response can be application/json, application/xml, application/yaml, application/msgpack, text/csv","instance":"/articles"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/articles", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			n.Render(w, r, http.StatusOK, tt.value)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestCodecs_Decode(t *testing.T) {
+	msgpackBody := func() string {
+		var buf bytes.Buffer
+		assert.NoError(t, MsgPackCodec{}.Encode(&buf, map[string]interface{}{"id": "1", "title": "title", "count": 2}))
+		return buf.String()
+	}
+
+	tests := []struct {
+		name    string
+		codec   Codec
+		body    string
+		want    item
+		wantErr bool
+	}{
+		{
+			name:  "json",
+			codec: JSONCodec{},
+			body:  `{"id":"1","title":"title","count":2}`,
+			want:  item{ID: "1", Title: "title", Count: 2},
+		},
+		{
+			name:    "json unknown field",
+			codec:   JSONCodec{},
+			body:    `{"id":"1","other":true}`,
+			wantErr: true,
+		},
+		{
+			name:  "xml",
+			codec: XMLCodec{},
+			body:  `<item><id>1</id><title>a &lt; b</title></item>`,
+			want:  item{ID: "1", Title: "a < b"},
+		},
+		{
+			name:    "xml nested elements",
+			codec:   XMLCodec{},
+			body:    `<item><id><value>1</value></id></item>`,
+			wantErr: true,
+		},
+		{
+			name:  "yaml",
+			codec: YAMLCodec{},
+			body:  "id: \"1\"\ntitle: title\ncount: 2\n",
+			want:  item{ID: "1", Title: "title", Count: 2},
+		},
+		{
+			name:    "yaml unknown field",
+			codec:   YAMLCodec{},
+			body:    "id: \"1\"\nother: true\n",
+			wantErr: true,
+		},
+		{
+			name:  "msgpack",
+			codec: MsgPackCodec{},
+			body:  msgpackBody(),
+			want:  item{ID: "1", Title: "title", Count: 2},
+		},
+		{
+			name:    "csv",
+			codec:   CSVCodec{},
+			body:    "id,title,count\n1,title,2\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got item
+			err := tt.codec.Decode(bytes.NewBufferString(tt.body), &got)
+			assert.Equal(t, tt.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Package render writes HTTP responses
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+)
+
+// Problem is an error response body, as defined by RFC 7807
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// WriteProblem writes a problem response with the status and a human readable detail
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	body, err := json.Marshal(Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).WithError(err).Error("could not encode problem")
+		Status(w, status)
+		return
+	}
+	write(w, status, "application/problem+json", body)
+}
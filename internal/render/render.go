@@ -0,0 +1,40 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+)
+
+// JSON writes v as the JSON body of a response with the status.
+// The body is encoded before anything is written, so an encoding failure is a 500 problem
+// instead of a truncated response with the original status
+func JSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		logging.FromContext(r.Context()).WithError(err).Error("could not encode response")
+		WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+	write(w, status, "application/json", buf.Bytes())
+}
+
+// Status writes a response without body
+func Status(w http.ResponseWriter, status int) {
+	write(w, status, "", nil)
+}
+
+// write sends a fully serialized body, with its length
+func write(w http.ResponseWriter, status int, contentType string, body []byte) {
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if len(body) > 0 {
+		w.Write(body)
+	}
+}
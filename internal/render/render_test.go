@@ -0,0 +1,60 @@
+package render
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		value           interface{}
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "encoded value",
+			status:          http.StatusCreated,
+			value:           map[string]string{"id": "1"},
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/json",
+			wantBody:        `{"id":"1"}` + "\n",
+		},
+		{
+			name:            "value that can not be encoded",
+			status:          http.StatusOK,
+			value:           map[string]float64{"n": math.Inf(1)},
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/articles"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			JSON(w, httptest.NewRequest(http.MethodGet, "/articles", nil), tt.status, tt.value)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, strconv.Itoa(len(tt.wantBody)), w.Header().Get("Content-Length"))
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	Status(w, http.StatusNoContent)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "0", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The formats other than JSON and MessagePack go through the JSON encoding of the values,
+// so the json tags are the only ones the entities need, and field names are the same in every format.
+
+// object is a JSON object that keeps the order of its fields
+type object []field
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// toTree encodes v as JSON and parses it back into objects, []interface{}, strings, numbers, bools and nils
+func toTree(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return parseValue(dec)
+}
+
+func parseValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			var obj object
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := parseValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, field{key: key.(string), value: value})
+			}
+			_, err := dec.Token()
+			return obj, err
+		}
+
+		list := []interface{}{}
+		for dec.More() {
+			value, err := parseValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	default:
+		return t, nil
+	}
+}
+
+// fromTree decodes a tree of maps, slices and scalars into v with the strict JSON decoding
+func fromTree(tree interface{}, v interface{}) error {
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Errorf("could not convert request body: %w", err)
+	}
+	return JSONCodec{}.Decode(bytes.NewReader(b), v)
+}
+
+// scalar formats a leaf of the tree as text, nested values are written as JSON
+func scalar(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return fmt.Sprint(t)
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
+
+// MarshalJSON writes the object keeping the order of its fields
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
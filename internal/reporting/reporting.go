@@ -0,0 +1,234 @@
+package reporting
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+// queueSize bounds the events waiting to be sent, more are dropped instead of blocking the requests
+const queueSize = 100
+
+// fingerprintFrames is the number of frames of the origin used to group the errors
+const fingerprintFrames = 5
+
+// Event is a reported error
+type Event struct {
+	ID          string    `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Level       string    `json:"level"`
+	Message     string    `json:"message"`
+	Error       string    `json:"error"`
+	Type        string    `json:"type"`
+	Fingerprint string    `json:"fingerprint"`
+	// Count is the number of occurrences of the fingerprint since the last report
+	Count   int                    `json:"count"`
+	Release string                 `json:"release,omitempty"`
+	Stack   []stacktrace.Frame     `json:"stack,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Sink is where the events are sent
+type Sink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// Group is the summary of the errors that share a fingerprint
+type Group struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	Error       string    `json:"error"`
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	// pending counts the occurrences not reported yet
+	pending      int
+	lastReported time.Time
+}
+
+// Reporter is a logrus hook that reports the error entries to the sinks.
+// Errors with the same fingerprint are reported once per interval, with the count of occurrences
+type Reporter struct {
+	sinks    []Sink
+	interval time.Duration
+	release  string
+	queue    chan Event
+	done     chan struct{}
+
+	mu     sync.Mutex
+	groups map[string]*Group
+}
+
+// NewReporter is the Reporter constructor
+func NewReporter(interval time.Duration, release string, sinks ...Sink) *Reporter {
+	return &Reporter{
+		sinks:    sinks,
+		interval: interval,
+		release:  release,
+		queue:    make(chan Event, queueSize),
+		done:     make(chan struct{}),
+		groups:   make(map[string]*Group),
+	}
+}
+
+// Levels returns the levels that are reported
+func (r *Reporter) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire groups the entry, and queues it to be sent if its group was not reported during the interval
+func (r *Reporter) Fire(entry *logrus.Entry) error {
+	event := r.event(entry)
+
+	r.mu.Lock()
+	group, ok := r.groups[event.Fingerprint]
+	if !ok {
+		group = &Group{Fingerprint: event.Fingerprint, Message: event.Message, Error: event.Error, FirstSeen: event.Timestamp}
+		r.groups[event.Fingerprint] = group
+	}
+	group.Count++
+	group.pending++
+	group.LastSeen = event.Timestamp
+	report := event.Timestamp.Sub(group.lastReported) >= r.interval
+	if report {
+		event.Count = group.pending
+		group.pending = 0
+		group.lastReported = event.Timestamp
+	}
+	r.mu.Unlock()
+
+	if !report {
+		return nil
+	}
+	select {
+	case r.queue <- event:
+	default:
+		// logging here would fire the hook again
+	}
+	return nil
+}
+
+// Groups returns the errors seen since the start, most recent first
+func (r *Reporter) Groups() []Group {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := make([]Group, 0, len(r.groups))
+	for _, g := range r.groups {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].LastSeen.After(groups[j].LastSeen) })
+	return groups
+}
+
+// Run sends the queued events until ctx is canceled, then sends the ones left
+func (r *Reporter) Run(ctx context.Context) {
+	defer close(r.done)
+	for {
+		select {
+		case event := <-r.queue:
+			r.send(ctx, event)
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-r.queue:
+					r.send(context.Background(), event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Wait blocks until Run has sent the events left, or ctx is done
+func (r *Reporter) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Reporter) send(ctx context.Context, event Event) {
+	for _, sink := range r.sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			// not logged at error level, that would be reported again
+			logrus.WithField("fingerprint", event.Fingerprint).WithField("reason", err.Error()).Warn("could not report error")
+		}
+	}
+}
+
+func (r *Reporter) event(entry *logrus.Entry) Event {
+	event := Event{
+		ID:        eventID(),
+		Timestamp: entry.Time,
+		Level:     entry.Level.String(),
+		Message:   entry.Message,
+		Release:   r.release,
+		Fields:    make(map[string]interface{}, len(entry.Data)),
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	for k, v := range entry.Data {
+		if k == logrus.ErrorKey {
+			continue
+		}
+		event.Fields[k] = fmt.Sprint(v)
+	}
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		event.Error = err.Error()
+		event.Type = fmt.Sprintf("%T", cause(err))
+		event.Stack = stacktrace.Frames(err)
+	}
+	event.Fingerprint = fingerprint(event)
+	return event
+}
+
+// cause returns the innermost error of the chain
+func cause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
+
+// fingerprint groups the errors by where they were created and logged.
+// Line numbers and error messages are left out, so changes in unrelated code or in ids don't split the groups
+func fingerprint(event Event) string {
+	h := sha256.New()
+	fmt.Fprintln(h, event.Message)
+	fmt.Fprintln(h, event.Type)
+	for i, f := range event.Stack {
+		if i == fingerprintFrames {
+			break
+		}
+		fmt.Fprintln(h, f.Function)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// eventID returns 32 hex characters, as Sentry event ids
+func eventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,134 @@
+package reporting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *recordingSink) Send(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func storeError(id string) error {
+	return stacktrace.Errorf("could not scan row of %s: %w", id, errors.New("disk I/O error"))
+}
+
+func TestReporter_Fire(t *testing.T) {
+	sink := &recordingSink{}
+	reporter := NewReporter(time.Minute, "1.0.0", sink)
+	ctx, cancel := context.WithCancel(context.Background())
+	go reporter.Run(ctx)
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(reporter)
+
+	// same origin and message with different ids are grouped
+	logger.WithError(storeError("1")).WithField("id", "1").Error("could not get article")
+	logger.WithError(storeError("2")).WithField("id", "2").Error("could not get article")
+	logger.WithError(storeError("3")).Error("could not delete article")
+	logger.WithError(storeError("4")).Warn("not reported")
+
+	cancel()
+	assert.NoError(t, reporter.Wait(context.Background()))
+
+	if assert.Len(t, sink.events, 2) {
+		first := sink.events[0]
+		assert.Equal(t, "could not get article", first.Message)
+		assert.Equal(t, "could not scan row of 1: disk I/O error", first.Error)
+		assert.Equal(t, "*errors.errorString", first.Type)
+		assert.Equal(t, "1.0.0", first.Release)
+		assert.Equal(t, "1", first.Fields["id"])
+		assert.Equal(t, 1, first.Count)
+		if assert.NotEmpty(t, first.Stack) {
+			assert.Contains(t, first.Stack[0].Function, "storeError")
+		}
+		assert.NotEqual(t, first.Fingerprint, sink.events[1].Fingerprint)
+	}
+
+	groups := reporter.Groups()
+	if assert.Len(t, groups, 2) {
+		counts := map[string]int{}
+		for _, g := range groups {
+			counts[g.Message] = g.Count
+		}
+		assert.Equal(t, map[string]int{"could not get article": 2, "could not delete article": 1}, counts)
+	}
+}
+
+func TestHTTPSink_Send(t *testing.T) {
+	var got sentryEvent
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("X-Sentry-Auth")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	sink, err := NewHTTPSink("http://public@" + srv.Listener.Addr().String() + "/42")
+	assert.NoError(t, err)
+
+	err = sink.Send(context.Background(), Event{
+		ID:          "0123456789abcdef0123456789abcdef",
+		Level:       "error",
+		Message:     "could not get article",
+		Error:       "disk I/O error",
+		Type:        "*errors.errorString",
+		Fingerprint: "abc",
+		Count:       3,
+		Stack: []stacktrace.Frame{
+			{Function: "main.inner", File: "inner.go", Line: 1},
+			{Function: "main.outer", File: "outer.go", Line: 2},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "/api/42/store/", gotPath)
+	assert.Contains(t, gotAuth, "sentry_key=public")
+	assert.Equal(t, []string{"abc"}, got.Fingerprint)
+	assert.Equal(t, float64(3), got.Extra["count"])
+	if assert.NotNil(t, got.Exception) && assert.Len(t, got.Exception.Values, 1) {
+		frames := got.Exception.Values[0].Stacktrace.Frames
+		assert.Equal(t, "main.outer", frames[0].Function, "frames are oldest first")
+		assert.Equal(t, "main.inner", frames[1].Function)
+	}
+}
+
+func TestNewHTTPSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		wantErr bool
+	}{
+		{name: "valid", dsn: "https://key@sentry.example.com/1"},
+		{name: "without key", dsn: "https://sentry.example.com/1", wantErr: true},
+		{name: "without project", dsn: "https://key@sentry.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPSink(tt.dsn)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sendTimeout bounds each request to the HTTP sink
+const sendTimeout = 5 * time.Second
+
+// FileSink appends the events to a file, one JSON object per line
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink is the FileSink constructor, the file is created if it doesn't exist
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open error report file: %w", err)
+	}
+	return &FileSink{file: f}, nil
+}
+
+// Send writes the event
+func (s *FileSink) Send(ctx context.Context, event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not encode event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("could not write event: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink sends the events to the store endpoint of a Sentry compatible server
+type HTTPSink struct {
+	endpoint string
+	auth     string
+	client   *http.Client
+	inApp    string
+}
+
+// NewHTTPSink is the HTTPSink constructor, dsn is like http://<key>@<host>/<project id>
+func NewHTTPSink(dsn string) (HTTPSink, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return HTTPSink{}, fmt.Errorf("could not parse dsn: %w", err)
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || project == "" || u.Host == "" {
+		return HTTPSink{}, fmt.Errorf("dsn must be like http://<key>@<host>/<project id>")
+	}
+
+	var inApp string
+	if info, ok := debug.ReadBuildInfo(); ok {
+		inApp = info.Main.Path
+	}
+
+	return HTTPSink{
+		endpoint: fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, project),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=articles/1.0, sentry_key=%s", u.User.Username()),
+		client:   &http.Client{Timeout: sendTimeout},
+		inApp:    inApp,
+	}, nil
+}
+
+type sentryEvent struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger"`
+	Platform    string                 `json:"platform"`
+	Release     string                 `json:"release,omitempty"`
+	Message     string                 `json:"message"`
+	Fingerprint []string               `json:"fingerprint"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Exception   *sentryExceptions      `json:"exception,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// Send posts the event
+func (s HTTPSink) Send(ctx context.Context, event Event) error {
+	b, err := json.Marshal(s.sentryEvent(event))
+	if err != nil {
+		return fmt.Errorf("could not encode event: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("could not build request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send event: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("event rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s HTTPSink) sentryEvent(event Event) sentryEvent {
+	extra := make(map[string]interface{}, len(event.Fields)+1)
+	for k, v := range event.Fields {
+		extra[k] = v
+	}
+	extra["count"] = event.Count
+
+	se := sentryEvent{
+		EventID:     event.ID,
+		Timestamp:   event.Timestamp.UTC().Format(time.RFC3339),
+		Level:       event.Level,
+		Logger:      "logrus",
+		Platform:    "go",
+		Release:     event.Release,
+		Message:     event.Message,
+		Fingerprint: []string{event.Fingerprint},
+		Extra:       extra,
+	}
+	if event.Error == "" {
+		return se
+	}
+
+	exception := sentryException{Type: event.Type, Value: event.Error}
+	if len(event.Stack) > 0 {
+		// Sentry lists the frames oldest first
+		frames := make([]sentryFrame, len(event.Stack))
+		for i, f := range event.Stack {
+			frames[len(frames)-1-i] = sentryFrame{
+				Function: f.Function,
+				Filename: f.File,
+				Lineno:   f.Line,
+				InApp:    strings.HasPrefix(f.Function, "main.") || (s.inApp != "" && strings.HasPrefix(f.Function, s.inApp)),
+			}
+		}
+		exception.Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+	se.Exception = &sentryExceptions{Values: []sentryException{exception}}
+	return se
+}
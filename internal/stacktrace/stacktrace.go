@@ -0,0 +1,110 @@
+package stacktrace
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// maxDepth is the number of frames kept of each stack
+const maxDepth = 32
+
+// Frame is a function call of a stack
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Error is an error with the stack of where it was created
+type Error struct {
+	err   error
+	stack []uintptr
+}
+
+// Error returns the message of the wrapped error
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error, so errors.Is and errors.As see through the stack
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Frames returns the stack, innermost call first
+func (e *Error) Frames() []Frame {
+	frames := runtime.CallersFrames(e.stack)
+	var res []Frame
+	for {
+		f, more := frames.Next()
+		res = append(res, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			return res
+		}
+	}
+}
+
+// New returns an error with the message and the stack of the caller
+func New(msg string) error {
+	return wrap(errors.New(msg), 3)
+}
+
+// Errorf formats the error as fmt.Errorf does, and records the stack of the caller
+// unless a wrapped error already has one, as the stack of the origin is the interesting one
+func Errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if _, ok := Find(err); ok {
+		return err
+	}
+	return wrap(err, 3)
+}
+
+// Wrap records the stack of the caller in err, unless it already has one
+func Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := Find(err); ok {
+		return err
+	}
+	return wrap(err, 3)
+}
+
+// Find returns the first error of the chain that has a stack
+func Find(err error) (*Error, bool) {
+	var stacked *Error
+	if errors.As(err, &stacked) {
+		return stacked, true
+	}
+	return nil, false
+}
+
+// Frames returns the stack of the first error of the chain that has one
+func Frames(err error) []Frame {
+	if stacked, ok := Find(err); ok {
+		return stacked.Frames()
+	}
+	return nil
+}
+
+// Format prints the stack with %+v, as the usual panic output
+func (e *Error) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		var b strings.Builder
+		b.WriteString(e.Error())
+		for _, f := range e.Frames() {
+			fmt.Fprintf(&b, "\n%s\n\t%s:%d", f.Function, f.File, f.Line)
+		}
+		fmt.Fprint(s, b.String())
+		return
+	}
+	fmt.Fprint(s, e.Error())
+}
+
+func wrap(err error, skip int) error {
+	pcs := make([]uintptr, maxDepth)
+	n := runtime.Callers(skip, pcs)
+	return &Error{err: err, stack: pcs[:n]}
+}
@@ -0,0 +1,53 @@
+package stacktrace
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errSentinel = errors.New("sentinel")
+
+func origin() error {
+	return Errorf("could not do it: %w", errSentinel)
+}
+
+func TestErrorf(t *testing.T) {
+	err := fmt.Errorf("caller: %w", Errorf("wrapped again: %w", origin()))
+
+	assert.True(t, errors.Is(err, errSentinel))
+	assert.Equal(t, "caller: wrapped again: could not do it: sentinel", err.Error())
+
+	frames := Frames(err)
+	if assert.NotEmpty(t, frames) {
+		assert.True(t, strings.HasSuffix(frames[0].Function, ".origin"), "stack starts at the origin, got %s", frames[0].Function)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantStack bool
+	}{
+		{name: "nil stays nil", err: nil},
+		{name: "plain error gets a stack", err: errSentinel, wantStack: true},
+		{name: "stack is kept", err: origin(), wantStack: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Wrap(tt.err)
+			if tt.err == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.err))
+			_, ok := Find(err)
+			assert.Equal(t, tt.wantStack, ok)
+		})
+	}
+}
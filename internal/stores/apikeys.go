@@ -0,0 +1,192 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+var apiKeyColumns = []string{"id", "created_at", "updated_at", "revoked_at", "name", "prefix", "hash", "scopes", "roles"}
+
+// APIKeys is the api keys store that connects with sqlite
+type APIKeys struct {
+	db *sql.DB
+}
+
+// NewAPIKeys is the store constructor
+func NewAPIKeys(db DB) APIKeys {
+	return APIKeys{db.db}
+}
+
+// GetAll returns all api keys, including the revoked ones
+func (a APIKeys) GetAll(ctx context.Context) ([]entities.APIKey, error) {
+	log := logging.FromContext(ctx)
+
+	query, _, err := sq.Select(apiKeyColumns...).
+		From("api_keys").
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build getall query: %w", err)
+	}
+
+	log.WithField("query", query).Debug("query to get all api keys")
+	rows, err := a.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute get all api keys query: %w", err)
+	}
+
+	var keys []entities.APIKey
+	defer rows.Close()
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// GetOne returns one api key by id
+func (a APIKeys) GetOne(ctx context.Context, id string) (entities.APIKey, error) {
+	return a.getBy(ctx, "id", id)
+}
+
+// GetByHash returns the api key that matches the hash
+func (a APIKeys) GetByHash(ctx context.Context, hash string) (entities.APIKey, error) {
+	return a.getBy(ctx, "hash", hash)
+}
+
+// GetByName returns the api key with the name
+func (a APIKeys) GetByName(ctx context.Context, name string) (entities.APIKey, error) {
+	return a.getBy(ctx, "name", name)
+}
+
+func (a APIKeys) getBy(ctx context.Context, column, value string) (entities.APIKey, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Select(apiKeyColumns...).
+		From("api_keys").
+		Where(sq.Eq{column: value}).
+		ToSql()
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not build getone query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to get one api key")
+
+	key, err := scanAPIKey(a.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entities.APIKey{}, fmt.Errorf("api key not found: %s: %w", err.Error(), consts.ErrEntityNotFound)
+		}
+
+		return entities.APIKey{}, stacktrace.Errorf("could not scan row: %w", err)
+	}
+
+	return key, nil
+}
+
+// Create inserts an api key row
+func (a APIKeys) Create(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Insert("api_keys").
+		Columns(apiKeyColumns...).
+		Values(key.ID, key.CreatedAt, key.UpdatedAt, key.RevokedAt, key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, " "), strings.Join(key.Roles, " ")).
+		ToSql()
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not build query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to insert api key")
+
+	res, err := a.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entities.APIKey{}, fmt.Errorf("api key name %s already exists: %s: %w", key.Name, err.Error(), consts.ErrConflict)
+		}
+		return entities.APIKey{}, stacktrace.Errorf("could not exec insert query: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not verify insertion: %w", err)
+	}
+	if affected != 1 {
+		return entities.APIKey{}, stacktrace.Errorf("row was not inserted")
+	}
+
+	return a.GetOne(ctx, key.ID)
+}
+
+// Update looks for the row with the api key id, and updates the mutable columns
+func (a APIKeys) Update(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Update("api_keys").SetMap(map[string]interface{}{
+		"updated_at": key.UpdatedAt,
+		"revoked_at": key.RevokedAt,
+		"prefix":     key.Prefix,
+		"hash":       key.Hash,
+		"scopes":     strings.Join(key.Scopes, " "),
+		"roles":      strings.Join(key.Roles, " "),
+	}).Where("id = ?", key.ID).
+		ToSql()
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not build query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to update api key")
+
+	res, err := a.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not exec update query: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not verify update: %w", err)
+	}
+	if affected != 1 {
+		return entities.APIKey{}, stacktrace.Errorf("row was not updated")
+	}
+
+	return a.GetOne(ctx, key.ID)
+}
+
+func scanAPIKey(s scanner) (entities.APIKey, error) {
+	var key entities.APIKey
+	var revokedAt sql.NullTime
+	var scopes, roles string
+	err := s.Scan(&key.ID,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+		&revokedAt,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&scopes,
+		&roles)
+	if err != nil {
+		return entities.APIKey{}, err
+	}
+
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	key.Scopes = strings.Fields(scopes)
+	key.Roles = strings.Fields(roles)
+	return key, nil
+}
@@ -4,15 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
-	_ "github.com/mattn/go-sqlite3" // sqlite driver
-	"github.com/sirupsen/logrus"
 
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
-	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
 )
 
 // Articles is the articles store that connects with sqlite
@@ -21,64 +21,89 @@ type Articles struct {
 }
 
 // NewArticles is the store constructor
-func NewArticles() (Articles, error) {
-
-	os.Remove("./articles.db")
-
-	db, err := sql.Open("sqlite3", "./articles.db")
-	if err != nil {
-		logrus.WithError(err).Error("could not open sqlite file")
-		return Articles{}, fmt.Errorf("could not open sqlite file: %w", err)
-	}
-
-	sqlStmt := `create table articles (	
-		id text not null primary key, 
-		created_at datetime not null,
-		updated_at datetime not null,
-		title text,
-		content text,
-		author text);`
-	_, err = db.Exec(sqlStmt)
-	if err != nil {
-		logrus.WithError(err).WithField("statement", sqlStmt).Error("could not execute init statement")
-		return Articles{}, fmt.Errorf("could not execute init statement %w: %s", err, sqlStmt)
-	}
-
-	if err := db.Ping(); err != nil {
-		logrus.WithError(err).Error("could not ping database")
-		return Articles{}, fmt.Errorf("could not ping database: %w", err)
-	}
-
-	return Articles{db}, nil
+func NewArticles(db DB) Articles {
+	return Articles{db.db}
 }
 
-// Close cleans the database files
-func (a Articles) Close() error {
-	logrus.Warn("Deleting sqlite file")
-	if err := os.Remove("./articles.db"); err != nil {
-		logrus.WithError(err).Warn("could not delete sqlite db file")
-		return err
+// Ping checks that the database is reachable
+func (a Articles) Ping(ctx context.Context) error {
+	if err := a.db.PingContext(ctx); err != nil {
+		return stacktrace.Errorf("could not ping database: %w", err)
 	}
-	return a.db.Close()
+	return nil
 }
 
 // GetAll returns all articles
 func (a Articles) GetAll(ctx context.Context) ([]entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	query, _, err := sq.Select("id", "created_at", "updated_at", "title", "content", "author").
 		From("articles").
 		ToSql()
 	if err != nil {
-		return nil, fmt.Errorf("could not build getall query: %w", err)
+		return nil, stacktrace.Errorf("could not build getall query: %w", err)
 	}
 
 	log.WithField("query", query).Debug("query to get all articles")
 	rows, err := a.db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("could not execute get all articles query: %w", err)
+		return nil, stacktrace.Errorf("could not execute get all articles query: %w", err)
+	}
+	return scanArticles(rows)
+}
+
+// Find returns the articles that match the filter, sorted by creation date and id
+func (a Articles) Find(ctx context.Context, filter entities.ArticleFilter) ([]entities.Article, error) {
+	log := logging.FromContext(ctx)
+
+	columns := []string{"id", "created_at", "updated_at", "title", "content", "author"}
+	q := sq.Select(columns...).From("articles")
+	if filter.IDs != nil {
+		q = q.Where(sq.Eq{"id": filter.IDs})
+	}
+	if filter.Authors != nil {
+		q = q.Where(sq.Eq{"author": filter.Authors})
+	}
+	if filter.TitleContains != "" {
+		q = q.Where(`title like ? escape '\'`, "%"+likeEscaper.Replace(filter.TitleContains)+"%")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q = q.Where(sq.Gt{"created_at": filter.CreatedAfter.UTC()})
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q = q.Where(sq.Lt{"created_at": filter.CreatedBefore.UTC()})
+	}
+	if !filter.AfterCreatedAt.IsZero() {
+		after := filter.AfterCreatedAt.UTC()
+		q = q.Where("(created_at > ? or (created_at = ? and id > ?))", after, after, filter.AfterID)
+	}
+	if filter.LimitPerAuthor > 0 {
+		// ranks the articles of each author that match the filter, to keep the first ones
+		ranked := q.Column("row_number() over (partition by author order by created_at, id) as author_rank")
+		q = sq.Select(columns...).FromSelect(ranked, "ranked").Where(sq.LtOrEq{"author_rank": filter.LimitPerAuthor})
+	}
+	q = q.OrderBy("created_at", "id")
+	if filter.Limit > 0 {
+		q = q.Limit(uint64(filter.Limit))
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build find query: %w", err)
 	}
 
+	log.WithField("query", query).Debug("query to find articles")
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute find articles query: %w", err)
+	}
+	return scanArticles(rows)
+}
+
+// likeEscaper escapes the wildcards of a like pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func scanArticles(rows *sql.Rows) ([]entities.Article, error) {
 	var articles []entities.Article
 	defer rows.Close()
 	for rows.Next() {
@@ -91,14 +116,14 @@ func (a Articles) GetAll(ctx context.Context) ([]entities.Article, error) {
 			&article.Content,
 			&article.Author)
 		if err != nil {
-			return nil, fmt.Errorf("could not scan rows: %w", err)
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
 		}
 		articles = append(articles, article)
 
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("got err while reading rows: %w", err)
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
 	}
 
 	return articles, nil
@@ -106,20 +131,24 @@ func (a Articles) GetAll(ctx context.Context) ([]entities.Article, error) {
 
 // GetOne returns one article by id
 func (a Articles) GetOne(ctx context.Context, id string) (entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	return getArticle(ctx, a.db, id)
+}
+
+func getArticle(ctx context.Context, db execer, id string) (entities.Article, error) {
+	log := logging.FromContext(ctx)
 
 	query, args, err := sq.Select("id", "created_at", "updated_at", "title", "content", "author").
 		From("articles").
 		Where("id = ?", id).
 		ToSql()
 	if err != nil {
-		return entities.Article{}, fmt.Errorf("could not build getone query: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not build getone query: %w", err)
 	}
 	log.WithField("query", query).
-		WithField("args", args).
+		WithField("id", id).
 		Debug("query to get one")
 
-	row := a.db.QueryRowContext(ctx, query, args...)
+	row := db.QueryRowContext(ctx, query, args...)
 	var article entities.Article
 	err = row.Scan(&article.ID,
 		&article.CreatedAt,
@@ -132,103 +161,174 @@ func (a Articles) GetOne(ctx context.Context, id string) (entities.Article, erro
 			return entities.Article{}, fmt.Errorf("article not found: %s: %w", err.Error(), consts.ErrEntityNotFound)
 		}
 
-		return entities.Article{}, fmt.Errorf("could not scan row: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not scan row: %w", err)
 	}
 
 	return article, nil
 
 }
 
-// Create inserts an article row
+// Create inserts an article row, and its created event in the outbox in the same transaction
 func (a Articles) Create(ctx context.Context, article entities.Article) (entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	query, args, err := sq.Insert("articles").
 		Columns("id", "created_at", "updated_at", "title", "content", "author").
 		Values(article.ID, article.CreatedAt, article.UpdatedAt, article.Title, article.Content, article.Author).
 		ToSql()
 	if err != nil {
-		return entities.Article{}, fmt.Errorf("could not build query: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not build query: %w", err)
 	}
 
 	log.WithField("query", query).
-		WithField("args", args).
+		WithField("id", article.ID).
 		Debug("query to insert")
 
-	res, err := a.db.ExecContext(ctx, query, args...)
+	tx, err := a.db.BeginTx(ctx, nil)
 	if err != nil {
-		return entities.Article{}, fmt.Errorf("could not exec insert query: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return entities.Article{}, stacktrace.Errorf("could not exec insert query: %w", err)
 	}
 
 	affected, err := res.RowsAffected()
 	if err != nil {
-		return entities.Article{}, fmt.Errorf("could not verify insertion: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not verify insertion: %w", err)
 	}
 	if affected != 1 {
-		return entities.Article{}, fmt.Errorf("row was not inserted")
+		return entities.Article{}, stacktrace.Errorf("row was not inserted")
 	}
 
-	return a.GetOne(ctx, article.ID)
+	created, err := getArticle(ctx, tx, article.ID)
+	if err != nil {
+		return entities.Article{}, err
+	}
+	event := entities.ArticleEvent{Type: consts.EventArticleCreated, Article: created, OccurredAt: created.CreatedAt}
+	if err := appendEvent(ctx, tx, event); err != nil {
+		return entities.Article{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return entities.Article{}, stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+
+	return created, nil
 }
 
-// Update looks for the row with the article id, and updates all the columns
-func (a Articles) Update(ctx context.Context, article entities.Article) (entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+// Update reads the row with the id, and updates all the columns with the article modify returns for it.
+// The read, the update and the updated event in the outbox are one transaction, so modify decides on the
+// article as it is written over. An error of modify is returned as is, and nothing is written
+func (a Articles) Update(ctx context.Context, id string, modify func(entities.Article) (entities.Article, error)) (entities.Article, error) {
+	log := logging.FromContext(ctx)
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.Article{}, stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := getArticle(ctx, tx, id)
+	if err != nil {
+		return entities.Article{}, err
+	}
+	article, err := modify(current)
+	if err != nil {
+		return entities.Article{}, err
+	}
 
 	query, args, err := sq.Update("articles").SetMap(map[string]interface{}{
 		"updated_at": article.UpdatedAt,
 		"title":      article.Title,
 		"content":    article.Content,
 		"author":     article.Author,
-	}).Where("id = ?", article.ID).
+	}).Where("id = ?", id).
 		ToSql()
 
 	if err != nil {
-		return entities.Article{}, fmt.Errorf("could not build query: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not build query: %w", err)
 	}
 	log.WithField("query", query).
-		WithField("args", args).
+		WithField("id", id).
 		Debug("query to update")
 
-	res, err := a.db.ExecContext(ctx, query, args...)
+	res, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		return entities.Article{}, fmt.Errorf("could not exec insert query: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not exec insert query: %w", err)
 	}
 
 	affected, err := res.RowsAffected()
 	if err != nil {
-		return entities.Article{}, fmt.Errorf("could not verify insertion: %w", err)
+		return entities.Article{}, stacktrace.Errorf("could not verify insertion: %w", err)
 	}
 	if affected != 1 {
-		return entities.Article{}, fmt.Errorf("row was not inserted")
+		return entities.Article{}, stacktrace.Errorf("row was not inserted")
 	}
 
-	return a.GetOne(ctx, article.ID)
+	updated, err := getArticle(ctx, tx, id)
+	if err != nil {
+		return entities.Article{}, err
+	}
+	event := entities.ArticleEvent{Type: consts.EventArticleUpdated, Article: updated, OccurredAt: updated.UpdatedAt}
+	if err := appendEvent(ctx, tx, event); err != nil {
+		return entities.Article{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return entities.Article{}, stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+
+	return updated, nil
 }
 
-// Delete deletes the row
-func (a Articles) Delete(ctx context.Context, id string) error {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+// Delete deletes the row if check allows it, and writes its deleted event in the outbox in the same transaction.
+// check gets the article read in the transaction, an error of check is returned as is, and nothing is deleted
+func (a Articles) Delete(ctx context.Context, id string, check func(entities.Article) error) error {
+	log := logging.FromContext(ctx)
 
 	query, args, err := sq.Delete("articles").Where("id = ?", id).ToSql()
 	if err != nil {
-		return fmt.Errorf("could not build query: %w", err)
+		return stacktrace.Errorf("could not build query: %w", err)
 	}
 	log.WithField("query", query).
-		WithField("args", args).
+		WithField("id", id).
 		Debug("query to delete")
 
-	res, err := a.db.ExecContext(ctx, query, args...)
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// the event carries the article as it was deleted
+	deleted, err := getArticle(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := check(deleted); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("could not exec insert query: %w", err)
+		return stacktrace.Errorf("could not exec insert query: %w", err)
 	}
 
 	affected, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("could not verify insertion: %w", err)
+		return stacktrace.Errorf("could not verify insertion: %w", err)
 	}
 	if affected != 1 {
-		return fmt.Errorf("row was not inserted")
+		return stacktrace.Errorf("row was not inserted")
+	}
+
+	event := entities.ArticleEvent{Type: consts.EventArticleDeleted, Article: deleted, OccurredAt: time.Now().UTC()}
+	if err := appendEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return stacktrace.Errorf("could not commit transaction: %w", err)
 	}
 
 	return nil
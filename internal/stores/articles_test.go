@@ -0,0 +1,62 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+func TestArticles_UpdateDelete(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDB(tempDB(t), false)
+	assert.NoError(t, err)
+	defer db.Close()
+	store, outbox := NewArticles(db), NewOutbox(db)
+	now := time.Now().UTC()
+	_, err = store.Create(ctx, entities.Article{ID: "1", CreatedAt: now, UpdatedAt: now, Title: "title", Author: "user:alice"})
+	assert.NoError(t, err)
+	denied := errors.New("denied")
+
+	// modify gets the stored article, and its error is returned without writing anything
+	var got entities.Article
+	_, err = store.Update(ctx, "1", func(a entities.Article) (entities.Article, error) {
+		got = a
+		return entities.Article{}, denied
+	})
+	assert.Equal(t, denied, err)
+	assert.Equal(t, "title", got.Title)
+	assert.Equal(t, denied, store.Delete(ctx, "1", func(a entities.Article) error { return denied }))
+	article, err := store.GetOne(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "title", article.Title)
+	last, err := outbox.Last(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), last)
+
+	// the article modify returns is written with its event
+	updated, err := store.Update(ctx, "1", func(a entities.Article) (entities.Article, error) {
+		a.Title = "new title"
+		return a, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "new title", updated.Title)
+	assert.Equal(t, "user:alice", updated.Author)
+	assert.NoError(t, store.Delete(ctx, "1", func(a entities.Article) error { return nil }))
+	events, err := outbox.Since(ctx, 1, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, consts.EventArticleUpdated, events[0].Type)
+		assert.Equal(t, consts.EventArticleDeleted, events[1].Type)
+	}
+
+	// a missing article is not found, modify and check are not called
+	_, err = store.Update(ctx, "1", nil)
+	assert.True(t, errors.Is(err, consts.ErrEntityNotFound))
+	assert.True(t, errors.Is(store.Delete(ctx, "1", nil), consts.ErrEntityNotFound))
+}
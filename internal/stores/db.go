@@ -0,0 +1,226 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+
+	"github.com/mattn/go-sqlite3" // sqlite driver
+	"github.com/sirupsen/logrus"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+// transactions take the write lock when they begin, so concurrent read-modify-write transactions wait
+// for each other, instead of failing when they try to upgrade a read lock
+const dbOptions = "?_txlock=immediate&_busy_timeout=5000"
+
+// migrations are the statements that build the schema, applied in order
+// append new statements at the end, never modify the applied ones
+var migrations = []string{
+	`create table articles (
+		id text not null primary key,
+		created_at datetime not null,
+		updated_at datetime not null,
+		title text,
+		content text,
+		author text);`,
+	`create table api_keys (
+		id text not null primary key,
+		created_at datetime not null,
+		updated_at datetime not null,
+		revoked_at datetime,
+		name text not null unique,
+		prefix text not null,
+		hash text not null unique,
+		scopes text not null);`,
+	`alter table api_keys add column roles text not null default '';`,
+	`create table roles (name text not null primary key);
+	create table permissions (
+		role text not null references roles(name),
+		permission text not null,
+		primary key (role, permission));
+	insert into roles (name) values ('reader'), ('author'), ('editor'), ('admin');
+	insert into permissions (role, permission) values
+		('reader', 'articles:read'),
+		('author', 'articles:read'),
+		('author', 'articles:write'),
+		('editor', 'articles:read'),
+		('editor', 'articles:write'),
+		('editor', 'articles:edit_any'),
+		('admin', 'articles:read'),
+		('admin', 'articles:write'),
+		('admin', 'articles:edit_any'),
+		('admin', 'keys:admin'),
+		('admin', 'users:admin');`,
+	`create table users (
+		id text not null primary key,
+		created_at datetime not null,
+		updated_at datetime not null,
+		username text not null unique,
+		password_hash text not null);
+	create table user_roles (
+		user_id text not null references users(id) on delete cascade,
+		role text not null references roles(name),
+		primary key (user_id, role));`,
+	`create table rate_limits (
+		key text not null primary key,
+		tokens real not null,
+		updated_at integer not null);`,
+	`create table article_events (
+		id integer not null primary key autoincrement,
+		type text not null,
+		article_id text not null,
+		article text not null,
+		occurred_at datetime not null);`,
+	`create table webhooks (
+		id text not null primary key,
+		created_at datetime not null,
+		updated_at datetime not null,
+		url text not null,
+		events text not null,
+		secret text not null);
+	create table webhook_deliveries (
+		id text not null primary key,
+		created_at datetime not null,
+		updated_at datetime not null,
+		webhook_id text not null references webhooks(id),
+		event_id integer not null,
+		event_type text not null,
+		payload text not null,
+		status text not null,
+		attempts integer not null,
+		next_attempt_at datetime not null,
+		response_status integer not null,
+		error text not null);
+	create index webhook_deliveries_due on webhook_deliveries (status, next_attempt_at);
+	create index webhook_deliveries_webhook on webhook_deliveries (webhook_id, created_at);
+	create table webhook_cursor (id integer not null primary key check (id = 1), event_id integer not null);
+	insert into webhook_cursor (id, event_id) values (1, 0);
+	insert into permissions (role, permission) values ('admin', 'webhooks:admin');`,
+	`alter table article_events rename to outbox;
+	create table outbox_offsets (
+		publisher text not null primary key,
+		event_id integer not null);
+	insert into outbox_offsets (publisher, event_id) select 'bus', coalesce(max(id), 0) from outbox;
+	insert into outbox_offsets (publisher, event_id) select 'webhooks', event_id from webhook_cursor;`,
+}
+
+// DB is the sqlite database shared by all the stores
+type DB struct {
+	db *sql.DB
+}
+
+// NewDB opens the sqlite file, creating it if it doesn't exist, and applies the migrations.
+// With reset the file is deleted first, so the database starts empty
+func NewDB(file string, reset bool) (DB, error) {
+	if reset {
+		logrus.WithField("file", file).Warn("Deleting sqlite file")
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			logrus.WithError(err).Error("could not delete sqlite file")
+			return DB{}, stacktrace.Errorf("could not delete sqlite file: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", file+dbOptions)
+	if err != nil {
+		logrus.WithError(err).Error("could not open sqlite file")
+		return DB{}, stacktrace.Errorf("could not open sqlite file: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		logrus.WithError(err).Error("could not migrate database")
+		return DB{}, stacktrace.Errorf("could not migrate database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		logrus.WithError(err).Error("could not ping database")
+		return DB{}, stacktrace.Errorf("could not ping database: %w", err)
+	}
+
+	return DB{db}, nil
+}
+
+// Close closes the connections, the file is kept for the next start
+func (d DB) Close() error {
+	if err := d.db.Close(); err != nil {
+		logrus.WithError(err).Warn("could not close database")
+		return err
+	}
+	return nil
+}
+
+// Migrated checks that all the migrations were applied
+func (d DB) Migrated(ctx context.Context) error {
+	var applied int
+	if err := d.db.QueryRowContext(ctx, `select count(*) from schema_migrations;`).Scan(&applied); err != nil {
+		return stacktrace.Errorf("could not count applied migrations: %w", err)
+	}
+
+	if applied != len(migrations) {
+		return stacktrace.Errorf("%d of %d migrations applied", applied, len(migrations))
+	}
+	return nil
+}
+
+// migrate applies the migrations that are not recorded in the schema_migrations table
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`create table if not exists schema_migrations (version integer not null primary key);`)
+	if err != nil {
+		return stacktrace.Errorf("could not create migrations table: %w", err)
+	}
+
+	var applied int
+	if err := db.QueryRow(`select count(*) from schema_migrations;`).Scan(&applied); err != nil {
+		return stacktrace.Errorf("could not count applied migrations: %w", err)
+	}
+
+	for version := applied; version < len(migrations); version++ {
+		logrus.WithField("version", version+1).Debug("applying migration")
+		if err := applyMigration(db, version+1, migrations[version]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration executes the statements of a migration and records it in one transaction, so a migration
+// that fails halfway leaves no changes behind and is applied again on the next start
+func applyMigration(db *sql.DB, version int, stmt string) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return stacktrace.Errorf("could not begin migration %d: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(stmt); err != nil {
+		return stacktrace.Errorf("could not execute migration %d %w: %s", version, err, stmt)
+	}
+	if _, err := tx.Exec(`insert into schema_migrations (version) values (?);`, version); err != nil {
+		return stacktrace.Errorf("could not record migration %d: %w", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return stacktrace.Errorf("could not commit migration %d: %w", version, err)
+	}
+	return nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer is implemented by both sql.DB and sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// isUniqueViolation reports whether the error comes from a unique constraint
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
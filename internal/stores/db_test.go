@@ -0,0 +1,32 @@
+package stores
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyMigration(t *testing.T) {
+	db, err := sql.Open("sqlite3", tempDB(t)+dbOptions)
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`create table schema_migrations (version integer not null primary key);`)
+	assert.NoError(t, err)
+
+	// a migration that fails halfway leaves no changes behind
+	err = applyMigration(db, 1, `create table a (id text); insert into missing (id) values ('1');`)
+	assert.Error(t, err)
+	var tables, versions int
+	assert.NoError(t, db.QueryRow(`select count(*) from sqlite_master where name = 'a';`).Scan(&tables))
+	assert.NoError(t, db.QueryRow(`select count(*) from schema_migrations;`).Scan(&versions))
+	assert.Equal(t, 0, tables)
+	assert.Equal(t, 0, versions)
+
+	// once fixed it's applied and recorded
+	assert.NoError(t, applyMigration(db, 1, `create table a (id text); insert into a (id) values ('1');`))
+	assert.NoError(t, db.QueryRow(`select count(*) from sqlite_master where name = 'a';`).Scan(&tables))
+	assert.NoError(t, db.QueryRow(`select count(*) from schema_migrations;`).Scan(&versions))
+	assert.Equal(t, 1, tables)
+	assert.Equal(t, 1, versions)
+}
@@ -0,0 +1,132 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+// Outbox is the change log of the articles. The events are written in the transaction of the change
+// so none is lost, and the ids come from an autoincrement column so they grow in the order they were committed.
+// Each publisher keeps the offset of the last event it got
+type Outbox struct {
+	db *sql.DB
+}
+
+// NewOutbox is the store constructor
+func NewOutbox(db DB) Outbox {
+	return Outbox{db.db}
+}
+
+// appendEvent stores the event, with the article as JSON, in the transaction of the change
+func appendEvent(ctx context.Context, tx *sql.Tx, event entities.ArticleEvent) error {
+	article, err := json.Marshal(event.Article)
+	if err != nil {
+		return stacktrace.Errorf("could not encode article: %w", err)
+	}
+
+	query, args, err := sq.Insert("outbox").
+		Columns("type", "article_id", "article", "occurred_at").
+		Values(event.Type, event.Article.ID, string(article), event.OccurredAt).
+		ToSql()
+	if err != nil {
+		return stacktrace.Errorf("could not build append query: %w", err)
+	}
+
+	logging.FromContext(ctx).WithField("query", query).Debug("query to append article event")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return stacktrace.Errorf("could not execute append article event query: %w", err)
+	}
+	return nil
+}
+
+// Since returns up to limit events after the id, oldest first
+func (o Outbox) Since(ctx context.Context, afterID int64, limit int) ([]entities.ArticleEvent, error) {
+	builder := sq.Select("id", "type", "article", "occurred_at").
+		From("outbox").
+		Where(sq.Gt{"id": afterID}).
+		OrderBy("id")
+	if limit > 0 {
+		builder = builder.Limit(uint64(limit))
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build since query: %w", err)
+	}
+
+	logging.FromContext(ctx).WithField("query", query).Debug("query to get article events")
+	rows, err := o.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute get article events query: %w", err)
+	}
+
+	var events []entities.ArticleEvent
+	defer rows.Close()
+	for rows.Next() {
+		var event entities.ArticleEvent
+		var article string
+		if err := rows.Scan(&event.ID, &event.Type, &article, &event.OccurredAt); err != nil {
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
+		}
+		if err := json.Unmarshal([]byte(article), &event.Article); err != nil {
+			return nil, stacktrace.Errorf("could not decode article of event %d: %w", event.ID, err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
+	}
+	return events, nil
+}
+
+// Last returns the id of the last event, 0 if there are none
+func (o Outbox) Last(ctx context.Context) (int64, error) {
+	var last int64
+	if err := o.db.QueryRowContext(ctx, `select coalesce(max(id), 0) from outbox;`).Scan(&last); err != nil {
+		return 0, stacktrace.Errorf("could not get last article event id: %w", err)
+	}
+	return last, nil
+}
+
+// Offset returns the id of the last event the publisher got, 0 if it got none
+func (o Outbox) Offset(ctx context.Context, publisher string) (int64, error) {
+	var offset int64
+	err := o.db.QueryRowContext(ctx, `select event_id from outbox_offsets where publisher = ?;`, publisher).Scan(&offset)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, stacktrace.Errorf("could not get outbox offset of %s: %w", publisher, err)
+	}
+	return offset, nil
+}
+
+// SetOffset records the id of the last event the publisher got
+func (o Outbox) SetOffset(ctx context.Context, publisher string, eventID int64) error {
+	_, err := o.db.ExecContext(ctx, `insert into outbox_offsets (publisher, event_id) values (?, ?)
+		on conflict (publisher) do update set event_id = excluded.event_id;`, publisher, eventID)
+	if err != nil {
+		return stacktrace.Errorf("could not set outbox offset of %s: %w", publisher, err)
+	}
+	return nil
+}
+
+// Prune deletes the events up to the id that occurred before the time, except the last one, so Last
+// still returns the id the next event follows. It returns how many were deleted
+func (o Outbox) Prune(ctx context.Context, upToID int64, before time.Time) (int64, error) {
+	res, err := o.db.ExecContext(ctx, `delete from outbox where id <= ? and occurred_at < ?
+		and id < (select max(id) from outbox);`, upToID, before.UTC())
+	if err != nil {
+		return 0, stacktrace.Errorf("could not prune outbox: %w", err)
+	}
+	pruned, err := res.RowsAffected()
+	if err != nil {
+		return 0, stacktrace.Errorf("could not count pruned events: %w", err)
+	}
+	return pruned, nil
+}
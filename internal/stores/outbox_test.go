@@ -0,0 +1,108 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/outbox"
+)
+
+// tempDB returns the path of a sqlite file in a new temporary directory
+func tempDB(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "stores")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "articles.db")
+}
+
+// relayFor runs a relay of the outbox of db to publisher for a while
+func relayFor(t *testing.T, db DB, publisher outbox.PublisherFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	relay := outbox.NewRelay(NewOutbox(db), 10*time.Millisecond, 0, outbox.Destination{Name: "test", Publisher: publisher})
+	go relay.Run(ctx)
+	<-ctx.Done()
+	assert.NoError(t, relay.Wait(context.Background()))
+}
+
+func TestOutbox_Restart(t *testing.T) {
+	file := tempDB(t)
+	ctx := context.Background()
+	article := entities.Article{ID: "1", CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(), Title: "title", Content: "content", Author: "jwt:alice"}
+
+	// the relay stops before it could publish the event
+	db, err := NewDB(file, false)
+	assert.NoError(t, err)
+	_, err = NewArticles(db).Create(ctx, article)
+	assert.NoError(t, err)
+	relayFor(t, db, func(ctx context.Context, event entities.ArticleEvent) error {
+		return errors.New("destination is down")
+	})
+	assert.NoError(t, db.Close())
+
+	// the event is published once the database is opened again
+	db, err = NewDB(file, false)
+	assert.NoError(t, err)
+	var published []entities.ArticleEvent
+	relayFor(t, db, func(ctx context.Context, event entities.ArticleEvent) error {
+		published = append(published, event)
+		return nil
+	})
+	assert.NoError(t, db.Close())
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, consts.EventArticleCreated, published[0].Type)
+		assert.Equal(t, "1", published[0].Article.ID)
+	}
+
+	// a reset starts with an empty outbox
+	db, err = NewDB(file, true)
+	assert.NoError(t, err)
+	defer db.Close()
+	last, err := NewOutbox(db).Last(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), last)
+}
+
+func TestOutbox_Prune(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDB(tempDB(t), false)
+	assert.NoError(t, err)
+	defer db.Close()
+	store := NewOutbox(db)
+
+	// event i occurred 4-i hours ago
+	now := time.Now()
+	for id := 1; id <= 3; id++ {
+		tx, err := db.db.BeginTx(ctx, nil)
+		assert.NoError(t, err)
+		event := entities.ArticleEvent{Type: consts.EventArticleCreated, Article: entities.Article{ID: "1"}, OccurredAt: now.Add(time.Duration(id-4) * time.Hour)}
+		assert.NoError(t, appendEvent(ctx, tx, event))
+		assert.NoError(t, tx.Commit())
+	}
+
+	// the events within the retention and the last one are kept
+	pruned, err := store.Prune(ctx, 3, now.Add(-150*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+	pruned, err = store.Prune(ctx, 3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	events, err := store.Since(ctx, 0, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, int64(3), events[0].ID)
+	}
+	last, err := store.Last(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), last)
+}
@@ -0,0 +1,54 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+// RateLimits keeps the rate limit buckets in sqlite, so instances that share the database share the limits
+type RateLimits struct {
+	db *sql.DB
+}
+
+// NewRateLimits is the store constructor
+func NewRateLimits(db DB) RateLimits {
+	return RateLimits{db.db}
+}
+
+// Take takes a token of the bucket of key, in a transaction so concurrent requests don't take the same token
+func (r RateLimits) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var bucket ratelimit.Bucket
+	var updated int64
+	err = tx.QueryRowContext(ctx, `select tokens, updated_at from rate_limits where key = ?`, key).
+		Scan(&bucket.Tokens, &updated)
+	if err != nil && err != sql.ErrNoRows {
+		return ratelimit.Result{}, stacktrace.Errorf("could not get bucket: %w", err)
+	}
+	if err == nil {
+		bucket.Updated = time.Unix(0, updated)
+	}
+
+	bucket, res := bucket.Take(limit, time.Now())
+
+	_, err = tx.ExecContext(ctx, `insert into rate_limits (key, tokens, updated_at) values (?, ?, ?)
+		on conflict (key) do update set tokens = excluded.tokens, updated_at = excluded.updated_at`,
+		key, bucket.Tokens, bucket.Updated.UnixNano())
+	if err != nil {
+		return ratelimit.Result{}, stacktrace.Errorf("could not save bucket: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+	return res, nil
+}
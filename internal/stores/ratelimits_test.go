@@ -0,0 +1,74 @@
+package stores
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+)
+
+func TestRateLimits_Take(t *testing.T) {
+	twoPerMinute := ratelimit.Limit{Burst: 2, Period: time.Minute}
+	onePerInstant := ratelimit.Limit{Burst: 1, Period: 50 * time.Millisecond}
+
+	type take struct {
+		key         string
+		limit       ratelimit.Limit
+		wait        time.Duration
+		wantAllowed bool
+		wantLeft    int
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "bucket empties after the burst",
+			takes: []take{
+				{key: "a", limit: twoPerMinute, wantAllowed: true, wantLeft: 1},
+				{key: "a", limit: twoPerMinute, wantAllowed: true, wantLeft: 0},
+				{key: "a", limit: twoPerMinute, wantAllowed: false, wantLeft: 0},
+			},
+		},
+		{
+			name: "keys have their own buckets",
+			takes: []take{
+				{key: "a", limit: ratelimit.Limit{Burst: 1, Period: time.Minute}, wantAllowed: true, wantLeft: 0},
+				{key: "b", limit: ratelimit.Limit{Burst: 1, Period: time.Minute}, wantAllowed: true, wantLeft: 0},
+				{key: "a", limit: ratelimit.Limit{Burst: 1, Period: time.Minute}, wantAllowed: false, wantLeft: 0},
+			},
+		},
+		{
+			name: "bucket refills over the period",
+			takes: []take{
+				{key: "a", limit: onePerInstant, wantAllowed: true, wantLeft: 0},
+				{key: "a", limit: onePerInstant, wantAllowed: false, wantLeft: 0},
+				{key: "a", limit: onePerInstant, wait: 60 * time.Millisecond, wantAllowed: true, wantLeft: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewDB(tempDB(t), false)
+			assert.NoError(t, err)
+			defer db.Close()
+			store := NewRateLimits(db)
+
+			for i, take := range tt.takes {
+				time.Sleep(take.wait)
+				res, err := store.Take(context.Background(), take.key, take.limit)
+				assert.NoError(t, err)
+				assert.Equal(t, take.wantAllowed, res.Allowed, "take %d", i)
+				assert.Equal(t, take.wantLeft, res.Remaining, "take %d", i)
+				assert.Equal(t, take.limit.Burst, res.Limit, "take %d", i)
+				if !take.wantAllowed {
+					assert.True(t, res.RetryAfter > 0, "take %d", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+// Roles is the roles and permissions store that connects with sqlite
+type Roles struct {
+	db *sql.DB
+}
+
+// NewRoles is the store constructor
+func NewRoles(db DB) Roles {
+	return Roles{db.db}
+}
+
+// GetPermissions returns the distinct permissions granted to any of the roles
+func (r Roles) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	log := logging.FromContext(ctx)
+
+	if len(roles) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sq.Select("distinct permission").
+		From("permissions").
+		Where(sq.Eq{"role": roles}).
+		OrderBy("permission").
+		ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build get permissions query: %w", err)
+	}
+
+	log.WithField("query", query).
+		WithField("roles", roles).
+		Debug("query to get permissions")
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute get permissions query: %w", err)
+	}
+
+	var permissions []string
+	defer rows.Close()
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
+	}
+
+	return permissions, nil
+}
@@ -0,0 +1,245 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+// Users is the users store that connects with sqlite
+type Users struct {
+	db *sql.DB
+}
+
+// NewUsers is the store constructor
+func NewUsers(db DB) Users {
+	return Users{db.db}
+}
+
+// GetAll returns all users with their roles
+func (u Users) GetAll(ctx context.Context) ([]entities.User, error) {
+	log := logging.FromContext(ctx)
+
+	query, _, err := sq.Select("id", "created_at", "updated_at", "username", "password_hash").
+		From("users").
+		OrderBy("username").
+		ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build getall query: %w", err)
+	}
+
+	log.WithField("query", query).Debug("query to get all users")
+	rows, err := u.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute get all users query: %w", err)
+	}
+
+	var users []entities.User
+	defer rows.Close()
+	for rows.Next() {
+		var user entities.User
+		err := rows.Scan(&user.ID,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.Username,
+			&user.PasswordHash)
+		if err != nil {
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
+	}
+
+	for i := range users {
+		if users[i].Roles, err = u.getRoles(ctx, users[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
+
+// GetOne returns one user by id
+func (u Users) GetOne(ctx context.Context, id string) (entities.User, error) {
+	return u.getBy(ctx, "id", id)
+}
+
+// GetByUsername returns one user by username
+func (u Users) GetByUsername(ctx context.Context, username string) (entities.User, error) {
+	return u.getBy(ctx, "username", username)
+}
+
+func (u Users) getBy(ctx context.Context, column, value string) (entities.User, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Select("id", "created_at", "updated_at", "username", "password_hash").
+		From("users").
+		Where(sq.Eq{column: value}).
+		ToSql()
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not build getone query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to get one user")
+
+	var user entities.User
+	err = u.db.QueryRowContext(ctx, query, args...).Scan(&user.ID,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.Username,
+		&user.PasswordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entities.User{}, fmt.Errorf("user not found: %s: %w", err.Error(), consts.ErrEntityNotFound)
+		}
+
+		return entities.User{}, stacktrace.Errorf("could not scan row: %w", err)
+	}
+
+	if user.Roles, err = u.getRoles(ctx, user.ID); err != nil {
+		return entities.User{}, err
+	}
+	return user, nil
+}
+
+// Create inserts a user row and its roles
+func (u Users) Create(ctx context.Context, user entities.User) (entities.User, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Insert("users").
+		Columns("id", "created_at", "updated_at", "username", "password_hash").
+		Values(user.ID, user.CreatedAt, user.UpdatedAt, user.Username, user.PasswordHash).
+		ToSql()
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not build query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to insert user")
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		if isUniqueViolation(err) {
+			return entities.User{}, fmt.Errorf("username %s already exists: %s: %w", user.Username, err.Error(), consts.ErrConflict)
+		}
+		return entities.User{}, stacktrace.Errorf("could not exec insert query: %w", err)
+	}
+
+	if err := setRoles(ctx, tx, user.ID, user.Roles); err != nil {
+		return entities.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entities.User{}, stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+
+	return u.GetOne(ctx, user.ID)
+}
+
+// UpdateRoles replaces the roles of a user
+func (u Users) UpdateRoles(ctx context.Context, user entities.User) (entities.User, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Update("users").
+		Set("updated_at", user.UpdatedAt).
+		Where("id = ?", user.ID).
+		ToSql()
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not build query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to update user")
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not exec update query: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not verify update: %w", err)
+	}
+	if affected != 1 {
+		return entities.User{}, fmt.Errorf("user id %s was not updated: %w", user.ID, consts.ErrEntityNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, `delete from user_roles where user_id = ?`, user.ID); err != nil {
+		return entities.User{}, stacktrace.Errorf("could not delete user roles: %w", err)
+	}
+	if err := setRoles(ctx, tx, user.ID, user.Roles); err != nil {
+		return entities.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entities.User{}, stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+
+	return u.GetOne(ctx, user.ID)
+}
+
+func (u Users) getRoles(ctx context.Context, userID string) ([]string, error) {
+	query, args, err := sq.Select("role").
+		From("user_roles").
+		Where("user_id = ?", userID).
+		OrderBy("role").
+		ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build get roles query: %w", err)
+	}
+
+	rows, err := u.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute get roles query: %w", err)
+	}
+
+	roles := []string{}
+	defer rows.Close()
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
+	}
+	return roles, nil
+}
+
+func setRoles(ctx context.Context, tx *sql.Tx, userID string, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	insert := sq.Insert("user_roles").Columns("user_id", "role")
+	for _, role := range roles {
+		insert = insert.Values(userID, role)
+	}
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return stacktrace.Errorf("could not build roles query: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return stacktrace.Errorf("could not exec roles insert query: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+var webhookColumns = []string{"id", "created_at", "updated_at", "url", "events", "secret"}
+
+var deliveryColumns = []string{"id", "created_at", "updated_at", "webhook_id", "event_id", "event_type", "payload",
+	"status", "attempts", "next_attempt_at", "response_status", "error"}
+
+// Webhooks is the store of the webhooks and their delivery queue, that connects with sqlite
+type Webhooks struct {
+	db *sql.DB
+}
+
+// NewWebhooks is the store constructor
+func NewWebhooks(db DB) Webhooks {
+	return Webhooks{db.db}
+}
+
+// GetAll returns all webhooks, oldest first
+func (w Webhooks) GetAll(ctx context.Context) ([]entities.Webhook, error) {
+	log := logging.FromContext(ctx)
+
+	query, _, err := sq.Select(webhookColumns...).
+		From("webhooks").
+		OrderBy("created_at", "id").
+		ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build getall query: %w", err)
+	}
+
+	log.WithField("query", query).Debug("query to get all webhooks")
+	rows, err := w.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute get all webhooks query: %w", err)
+	}
+
+	var hooks []entities.Webhook
+	defer rows.Close()
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
+	}
+	return hooks, nil
+}
+
+// GetOne returns the webhook with the id
+func (w Webhooks) GetOne(ctx context.Context, id string) (entities.Webhook, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Select(webhookColumns...).
+		From("webhooks").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return entities.Webhook{}, stacktrace.Errorf("could not build getone query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to get one webhook")
+
+	hook, err := scanWebhook(w.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entities.Webhook{}, fmt.Errorf("webhook not found: %s: %w", err.Error(), consts.ErrEntityNotFound)
+		}
+
+		return entities.Webhook{}, stacktrace.Errorf("could not scan row: %w", err)
+	}
+
+	return hook, nil
+}
+
+// Create inserts a webhook row
+func (w Webhooks) Create(ctx context.Context, hook entities.Webhook) (entities.Webhook, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Insert("webhooks").
+		Columns(webhookColumns...).
+		Values(hook.ID, hook.CreatedAt, hook.UpdatedAt, hook.URL, strings.Join(hook.Events, " "), hook.Secret).
+		ToSql()
+	if err != nil {
+		return entities.Webhook{}, stacktrace.Errorf("could not build query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to insert webhook")
+
+	if _, err := w.db.ExecContext(ctx, query, args...); err != nil {
+		return entities.Webhook{}, stacktrace.Errorf("could not exec insert query: %w", err)
+	}
+
+	return w.GetOne(ctx, hook.ID)
+}
+
+// Update looks for the row with the webhook id, and updates the mutable columns
+func (w Webhooks) Update(ctx context.Context, hook entities.Webhook) (entities.Webhook, error) {
+	log := logging.FromContext(ctx)
+
+	query, args, err := sq.Update("webhooks").SetMap(map[string]interface{}{
+		"updated_at": hook.UpdatedAt,
+		"url":        hook.URL,
+		"events":     strings.Join(hook.Events, " "),
+		"secret":     hook.Secret,
+	}).Where("id = ?", hook.ID).
+		ToSql()
+	if err != nil {
+		return entities.Webhook{}, stacktrace.Errorf("could not build query: %w", err)
+	}
+	log.WithField("query", query).Debug("query to update webhook")
+
+	res, err := w.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return entities.Webhook{}, stacktrace.Errorf("could not exec update query: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return entities.Webhook{}, stacktrace.Errorf("could not verify update: %w", err)
+	}
+	if affected != 1 {
+		return entities.Webhook{}, fmt.Errorf("webhook id %s was not updated: %w", hook.ID, consts.ErrEntityNotFound)
+	}
+
+	return w.GetOne(ctx, hook.ID)
+}
+
+// Delete removes the webhook and its deliveries
+func (w Webhooks) Delete(ctx context.Context, id string) error {
+	log := logging.FromContext(ctx)
+	log.WithField("id", id).Debug("query to delete webhook")
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `delete from webhook_deliveries where webhook_id = ?`, id); err != nil {
+		return stacktrace.Errorf("could not delete webhook deliveries: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `delete from webhooks where id = ?`, id)
+	if err != nil {
+		return stacktrace.Errorf("could not exec delete query: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return stacktrace.Errorf("could not verify delete: %w", err)
+	}
+	if affected != 1 {
+		return fmt.Errorf("webhook id %s was not deleted: %w", id, consts.ErrEntityNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Enqueue inserts the deliveries of an event and moves the cursor past it, in the same transaction
+// so every event is queued once. Events at or before the cursor are ignored
+func (w Webhooks) Enqueue(ctx context.Context, eventID int64, deliveries []entities.WebhookDelivery) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `update webhook_cursor set event_id = ? where id = 1 and event_id < ?;`, eventID, eventID)
+	if err != nil {
+		return stacktrace.Errorf("could not move webhook cursor: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return stacktrace.Errorf("could not verify webhook cursor: %w", err)
+	}
+	if affected != 1 {
+		logging.FromContext(ctx).WithField("event", eventID).Debug("article event already queued")
+		return nil
+	}
+
+	for _, d := range deliveries {
+		if err := insertDelivery(ctx, tx, d); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CreateDelivery inserts a delivery row
+func (w Webhooks) CreateDelivery(ctx context.Context, d entities.WebhookDelivery) (entities.WebhookDelivery, error) {
+	if err := insertDelivery(ctx, w.db, d); err != nil {
+		return entities.WebhookDelivery{}, err
+	}
+	return w.GetDelivery(ctx, d.ID)
+}
+
+// GetDelivery returns the delivery with the id
+func (w Webhooks) GetDelivery(ctx context.Context, id string) (entities.WebhookDelivery, error) {
+	query, args, err := sq.Select(deliveryColumns...).
+		From("webhook_deliveries").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return entities.WebhookDelivery{}, stacktrace.Errorf("could not build get delivery query: %w", err)
+	}
+	logging.FromContext(ctx).WithField("query", query).Debug("query to get one webhook delivery")
+
+	d, err := scanDelivery(w.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entities.WebhookDelivery{}, fmt.Errorf("webhook delivery not found: %s: %w", err.Error(), consts.ErrEntityNotFound)
+		}
+
+		return entities.WebhookDelivery{}, stacktrace.Errorf("could not scan row: %w", err)
+	}
+	return d, nil
+}
+
+// Deliveries returns up to limit deliveries of the webhook, newest first
+func (w Webhooks) Deliveries(ctx context.Context, webhookID string, limit int) ([]entities.WebhookDelivery, error) {
+	query, args, err := sq.Select(deliveryColumns...).
+		From("webhook_deliveries").
+		Where("webhook_id = ?", webhookID).
+		OrderBy("created_at desc", "id desc").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build deliveries query: %w", err)
+	}
+
+	logging.FromContext(ctx).WithField("query", query).Debug("query to get webhook deliveries")
+	return queryDeliveries(ctx, w.db, query, args)
+}
+
+// Claim returns up to limit pending deliveries that are due, and postpones them until leaseUntil
+// so they are not claimed again while they are sent. A delivery that is never saved, because
+// the process died while sending it, is retried once the lease is over
+func (w Webhooks) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entities.WebhookDelivery, error) {
+	query, args, err := sq.Select(deliveryColumns...).
+		From("webhook_deliveries").
+		Where(sq.Eq{"status": consts.DeliveryPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at", "id").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, stacktrace.Errorf("could not build claim query: %w", err)
+	}
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deliveries, err := queryDeliveries(ctx, tx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deliveries {
+		if _, err := tx.ExecContext(ctx, `update webhook_deliveries set next_attempt_at = ? where id = ?;`, leaseUntil, deliveries[i].ID); err != nil {
+			return nil, stacktrace.Errorf("could not claim webhook delivery: %w", err)
+		}
+		deliveries[i].NextAttemptAt = leaseUntil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, stacktrace.Errorf("could not commit transaction: %w", err)
+	}
+	return deliveries, nil
+}
+
+// SaveAttempt updates the delivery with the result of an attempt
+func (w Webhooks) SaveAttempt(ctx context.Context, d entities.WebhookDelivery) error {
+	query, args, err := sq.Update("webhook_deliveries").SetMap(map[string]interface{}{
+		"updated_at":      d.UpdatedAt,
+		"status":          d.Status,
+		"attempts":        d.Attempts,
+		"next_attempt_at": d.NextAttemptAt,
+		"response_status": d.ResponseStatus,
+		"error":           d.Error,
+	}).Where("id = ?", d.ID).
+		ToSql()
+	if err != nil {
+		return stacktrace.Errorf("could not build query: %w", err)
+	}
+	logging.FromContext(ctx).WithField("query", query).Debug("query to save webhook delivery attempt")
+
+	res, err := w.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return stacktrace.Errorf("could not exec update query: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return stacktrace.Errorf("could not verify update: %w", err)
+	}
+	if affected != 1 {
+		// the webhook was deleted while the delivery was sent
+		return fmt.Errorf("webhook delivery id %s was not updated: %w", d.ID, consts.ErrEntityNotFound)
+	}
+	return nil
+}
+
+func insertDelivery(ctx context.Context, db execer, d entities.WebhookDelivery) error {
+	query, args, err := sq.Insert("webhook_deliveries").
+		Columns(deliveryColumns...).
+		Values(d.ID, d.CreatedAt, d.UpdatedAt, d.WebhookID, d.EventID, d.EventType, string(d.Payload),
+			d.Status, d.Attempts, d.NextAttemptAt, d.ResponseStatus, d.Error).
+		ToSql()
+	if err != nil {
+		return stacktrace.Errorf("could not build query: %w", err)
+	}
+	logging.FromContext(ctx).WithField("query", query).Debug("query to insert webhook delivery")
+
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return stacktrace.Errorf("could not exec insert query: %w", err)
+	}
+	return nil
+}
+
+func queryDeliveries(ctx context.Context, db execer, query string, args []interface{}) ([]entities.WebhookDelivery, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, stacktrace.Errorf("could not execute get webhook deliveries query: %w", err)
+	}
+
+	var deliveries []entities.WebhookDelivery
+	defer rows.Close()
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, stacktrace.Errorf("could not scan rows: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Errorf("got err while reading rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+func scanWebhook(s scanner) (entities.Webhook, error) {
+	var hook entities.Webhook
+	var events string
+	err := s.Scan(&hook.ID,
+		&hook.CreatedAt,
+		&hook.UpdatedAt,
+		&hook.URL,
+		&events,
+		&hook.Secret)
+	if err != nil {
+		return entities.Webhook{}, err
+	}
+
+	hook.Events = strings.Fields(events)
+	return hook, nil
+}
+
+func scanDelivery(s scanner) (entities.WebhookDelivery, error) {
+	var d entities.WebhookDelivery
+	var payload string
+	err := s.Scan(&d.ID,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+		&d.WebhookID,
+		&d.EventID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.ResponseStatus,
+		&d.Error)
+	if err != nil {
+		return entities.WebhookDelivery{}, err
+	}
+
+	d.Payload = []byte(payload)
+	return d, nil
+}
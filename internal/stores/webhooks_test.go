@@ -0,0 +1,51 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+func TestWebhooks_Restart(t *testing.T) {
+	file := tempDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	lease := now.Add(time.Minute)
+
+	// the delivery is claimed, and the process stops while it's sent
+	db, err := NewDB(file, false)
+	assert.NoError(t, err)
+	hooks := NewWebhooks(db)
+	_, err = hooks.Create(ctx, entities.Webhook{ID: "hook", CreatedAt: now, UpdatedAt: now, URL: "http://example.com", Events: []string{consts.EventArticleCreated}, Secret: "secret"})
+	assert.NoError(t, err)
+	err = hooks.Enqueue(ctx, 1, []entities.WebhookDelivery{{
+		ID: "delivery", CreatedAt: now, UpdatedAt: now, WebhookID: "hook", EventID: 1, EventType: consts.EventArticleCreated,
+		Payload: json.RawMessage(`{}`), Status: consts.DeliveryPending, NextAttemptAt: now,
+	}})
+	assert.NoError(t, err)
+	claimed, err := hooks.Claim(ctx, now, lease, 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 1)
+	assert.NoError(t, db.Close())
+
+	// once opened again the event is not queued twice, and the delivery is sent when the lease is over
+	db, err = NewDB(file, false)
+	assert.NoError(t, err)
+	defer db.Close()
+	hooks = NewWebhooks(db)
+	assert.NoError(t, hooks.Enqueue(ctx, 1, []entities.WebhookDelivery{{ID: "again", WebhookID: "hook", EventID: 1}}))
+	claimed, err = hooks.Claim(ctx, now, lease, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, claimed)
+	claimed, err = hooks.Claim(ctx, lease, lease.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, "delivery", claimed[0].ID)
+	}
+}
@@ -0,0 +1,222 @@
+// Package tlsconfig builds the server TLS configuration, reloading the certificates when they change
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Options are the files and settings of the TLS configuration
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile verifies the client certificates, mutual TLS is disabled if empty
+	ClientCAFile string
+	// ClientAuth is request, to verify client certificates when given, or require
+	ClientAuth   string
+	MinVersion   uint16
+	CipherSuites []uint16
+}
+
+// Reloader keeps the certificate and the client CAs read from the files
+// New handshakes use the reloaded files, open connections are not affected
+type Reloader struct {
+	opts Options
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// NewReloader reads the files, it fails if they are not valid
+func NewReloader(opts Options) (*Reloader, error) {
+	r := &Reloader{opts: opts}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again, the previous ones are kept if they are not valid
+func (r *Reloader) Reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("could not load certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("could not read client ca file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in client ca file %s", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.modTimes = &cert, pool, modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate returns the current certificate, it's used as tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Config returns the server TLS configuration
+func (r *Reloader) Config() *tls.Config {
+	base := &tls.Config{
+		MinVersion:     r.opts.MinVersion,
+		CipherSuites:   r.opts.CipherSuites,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if r.opts.ClientCAFile == "" {
+		return base
+	}
+
+	base.ClientAuth = tls.VerifyClientCertIfGiven
+	if r.opts.ClientAuth == "require" {
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	// the client CAs are read on each handshake, so they are reloaded too
+	cfg := base.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		r.mu.RLock()
+		c.ClientCAs = r.clientCAs
+		r.mu.RUnlock()
+		return c, nil
+	}
+	return cfg
+}
+
+// Watch reloads the files when they are modified or when the process receives SIGHUP, until the context is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		force := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-hup:
+			force = true
+		}
+
+		if !force && !r.changed() {
+			continue
+		}
+
+		if err := r.Reload(); err != nil {
+			logrus.WithError(err).Error("could not reload tls certificates, keeping previous ones")
+			continue
+		}
+		logrus.WithField("cert", r.opts.CertFile).Info("tls certificates reloaded")
+	}
+}
+
+// changed reports whether any file was modified since the last reload
+func (r *Reloader) changed() bool {
+	modTimes, err := r.stat()
+	if err != nil {
+		logrus.WithError(err).Warn("could not stat tls files")
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for file, t := range modTimes {
+		if !t.Equal(r.modTimes[file]) {
+			// remembered even if the reload fails, so a broken file is not reloaded on every tick
+			r.modTimes = modTimes
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) stat() (map[string]time.Time, error) {
+	modTimes := map[string]time.Time{}
+	for _, file := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not stat %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// ParseVersion parses a TLS version like 1.2
+func ParseVersion(s string) (uint16, error) {
+	switch s {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown tls version %q", s)
+	}
+}
+
+// ParseCipherSuites parses a comma separated list of cipher suite names, like TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+// Only the secure suites of crypto/tls are accepted, TLS 1.3 suites are not configurable
+func ParseCipherSuites(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	known := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
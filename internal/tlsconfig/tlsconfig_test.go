@@ -0,0 +1,275 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// authority is a throwaway CA that signs the test certificates
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T, name string) authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return authority{cert: cert, key: key}
+}
+
+// writeCA writes the certificate of the CA to the file
+func (a authority) writeCA(t *testing.T, file string) {
+	writePEM(t, file, "CERTIFICATE", a.cert.Raw)
+}
+
+// issue writes a certificate signed by the CA, with the serial number, and its key to the files
+func (a authority) issue(t *testing.T, serial int64, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+}
+
+// certificate returns the certificate and key of the files, for a client
+func certificate(t *testing.T, certFile, keyFile string) tls.Certificate {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	return cert
+}
+
+// writePEM writes the block, and moves the modification time forward so the change is seen
+// even when the file system only keeps seconds
+func writePEM(t *testing.T, file, kind string, der []byte) {
+	assert.NoError(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600))
+	later := time.Now().Add(time.Duration(len(der)) * time.Second)
+	assert.NoError(t, os.Chtimes(file, later, later))
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// handshake connects a client with the certificates to the server configuration, and returns the serial number of
+// the certificate the server presented
+func handshake(t *testing.T, cfg *tls.Config, clientCerts ...tls.Certificate) (int64, error) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	server := tls.Server(serverConn, cfg)
+	done := make(chan error, 1)
+	go func() { done <- server.Handshake() }()
+
+	client := tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true, Certificates: clientCerts})
+	err := client.Handshake()
+	if err == nil {
+		// the client finishes first with TLS 1.3, the server checks its certificate after and sends an alert
+		go io.Copy(ioutil.Discard, client)
+		err = <-done
+	}
+	if err != nil {
+		return 0, err
+	}
+	return client.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := tempDir(t)
+	ca := newAuthority(t, "ca")
+	opts := Options{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	ca.issue(t, 1, opts.CertFile, opts.KeyFile)
+
+	r, err := NewReloader(opts)
+	assert.NoError(t, err)
+	serial, err := handshake(t, r.Config())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), serial)
+	assert.False(t, r.changed())
+
+	// the rewritten certificate is served once reloaded
+	ca.issue(t, 2, opts.CertFile, opts.KeyFile)
+	assert.True(t, r.changed())
+	assert.False(t, r.changed())
+	assert.NoError(t, r.Reload())
+	serial, err = handshake(t, r.Config())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), serial)
+
+	// a broken certificate keeps the previous one
+	assert.NoError(t, ioutil.WriteFile(opts.CertFile, []byte("not a certificate"), 0600))
+	assert.Error(t, r.Reload())
+	serial, err = handshake(t, r.Config())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), serial)
+
+	// the files must exist
+	_, err = NewReloader(Options{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: opts.KeyFile})
+	assert.Error(t, err)
+}
+
+func TestReloader_ClientCAs(t *testing.T) {
+	dir := tempDir(t)
+	ca, other := newAuthority(t, "ca"), newAuthority(t, "other")
+	opts := Options{
+		CertFile:     filepath.Join(dir, "cert.pem"),
+		KeyFile:      filepath.Join(dir, "key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+		ClientAuth:   "require",
+	}
+	ca.issue(t, 1, opts.CertFile, opts.KeyFile)
+	ca.writeCA(t, opts.ClientCAFile)
+	ca.issue(t, 10, filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+	client := certificate(t, filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+
+	r, err := NewReloader(opts)
+	assert.NoError(t, err)
+	cfg := r.Config()
+	clientCfg, err := cfg.GetConfigForClient(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, clientCfg.ClientAuth)
+	assert.Equal(t, [][]byte{ca.cert.RawSubject}, clientCfg.ClientCAs.Subjects())
+
+	// a client certificate of the CA is accepted, none is rejected
+	_, err = handshake(t, cfg, client)
+	assert.NoError(t, err)
+	_, err = handshake(t, cfg)
+	assert.Error(t, err)
+
+	// once the CA file is replaced, the handshakes of the same configuration use the new pool
+	other.writeCA(t, opts.ClientCAFile)
+	assert.True(t, r.changed())
+	assert.NoError(t, r.Reload())
+	clientCfg, err = cfg.GetConfigForClient(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{other.cert.RawSubject}, clientCfg.ClientCAs.Subjects())
+	_, err = handshake(t, cfg, client)
+	assert.Error(t, err)
+}
+
+func TestReloader_Config(t *testing.T) {
+	dir := tempDir(t)
+	ca := newAuthority(t, "ca")
+	opts := Options{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem"), ClientCAFile: filepath.Join(dir, "ca.pem")}
+	ca.issue(t, 1, opts.CertFile, opts.KeyFile)
+	ca.writeCA(t, opts.ClientCAFile)
+
+	r, err := NewReloader(opts)
+	assert.NoError(t, err)
+	clientCfg, err := r.Config().GetConfigForClient(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, clientCfg.ClientAuth)
+
+	// without client CAs there is no mutual TLS
+	r, err = NewReloader(Options{CertFile: opts.CertFile, KeyFile: opts.KeyFile, MinVersion: tls.VersionTLS12})
+	assert.NoError(t, err)
+	cfg := r.Config()
+	assert.Nil(t, cfg.GetConfigForClient)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+
+	// a CA file without certificates is rejected
+	assert.NoError(t, ioutil.WriteFile(opts.ClientCAFile, []byte("nothing"), 0600))
+	_, err = NewReloader(opts)
+	assert.Error(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint16
+		wantErr string
+	}{
+		{in: "1.0", want: tls.VersionTLS10},
+		{in: "1.1", want: tls.VersionTLS11},
+		{in: "1.2", want: tls.VersionTLS12},
+		{in: "1.3", want: tls.VersionTLS13},
+		{in: "1.4", wantErr: `unknown tls version "1.4"`},
+		{in: "", wantErr: `unknown tls version ""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseVersion(tt.in)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []uint16
+		wantErr string
+	}{
+		{name: "empty", in: ""},
+		{
+			name: "names with spaces",
+			in:   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+			want: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+		},
+		{name: "unknown", in: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_FAKE", wantErr: `unknown or insecure cipher suite "TLS_FAKE"`},
+		{name: "insecure", in: "TLS_RSA_WITH_RC4_128_SHA", wantErr: `unknown or insecure cipher suite "TLS_RSA_WITH_RC4_128_SHA"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCipherSuites(tt.in)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package transports
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/reporting"
+)
+
+// BuildInfo identifies the running binary, it's set at build time with -ldflags
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+type runtimeResponse struct {
+	Uptime     string `json:"uptime"`
+	Goroutines int    `json:"goroutines"`
+	CPUs       int    `json:"cpus"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	HeapAlloc  uint64 `json:"heapAlloc"`
+	HeapInUse  uint64 `json:"heapInUse"`
+	Sys        uint64 `json:"sys"`
+	NumGC      uint32 `json:"numGC"`
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// ErrorGroups describes the function we need to list the reported errors
+type ErrorGroups interface {
+	Groups() []reporting.Group
+}
+
+// Admin is the transport struct of the admin endpoints, served on a private address
+type Admin struct {
+	build   BuildInfo
+	config  interface{}
+	errors  ErrorGroups
+	started time.Time
+}
+
+// NewAdmin is the Admin transport constructor, config must already have its secrets redacted
+func NewAdmin(build BuildInfo, config interface{}, errors ErrorGroups) Admin {
+	build.GoVersion = runtime.Version()
+	return Admin{build: build, config: config, errors: errors, started: time.Now()}
+}
+
+// Version returns the build info
+func (a Admin) Version(w http.ResponseWriter, r *http.Request) {
+	writeAdmin(w, r, a.build)
+}
+
+// Config returns the current configuration
+func (a Admin) Config(w http.ResponseWriter, r *http.Request) {
+	writeAdmin(w, r, a.config)
+}
+
+// Runtime returns goroutine counts and memory stats
+func (a Admin) Runtime(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeAdmin(w, r, runtimeResponse{
+		Uptime:     time.Since(a.started).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		CPUs:       runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		HeapAlloc:  mem.HeapAlloc,
+		HeapInUse:  mem.HeapInuse,
+		Sys:        mem.Sys,
+		NumGC:      mem.NumGC,
+	})
+}
+
+// Errors returns the logged errors grouped by fingerprint
+func (a Admin) Errors(w http.ResponseWriter, r *http.Request) {
+	writeAdmin(w, r, a.errors.Groups())
+}
+
+// GetLogLevel returns the current log level
+func (a Admin) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeAdmin(w, r, logLevel{Level: logrus.GetLevel().String()})
+}
+
+// SetLogLevel changes the log level until the next restart
+func (a Admin) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevel
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	level, err := logrus.ParseLevel(req.Level)
+	if err != nil {
+		logrus.WithError(err).Warn("could not parse log level")
+		render.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	previous := logrus.GetLevel()
+	logrus.SetLevel(level)
+	logrus.WithField("from", previous.String()).WithField("to", level.String()).Warn("log level changed")
+	writeAdmin(w, r, logLevel{Level: level.String()})
+}
+
+func writeAdmin(w http.ResponseWriter, r *http.Request, resp interface{}) {
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,109 @@
+package transports
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/config"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/tlsconfig"
+)
+
+func TestAdmin_Version(t *testing.T) {
+	a := NewAdmin(BuildInfo{Version: "1.2.0", Commit: "abc123", BuildDate: "2026-10-19"}, nil, nil)
+
+	rec := httptest.NewRecorder()
+	a.Version(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"version": "1.2.0", "commit": "abc123", "buildDate": "2026-10-19", "goVersion": "`+runtime.Version()+`"}`, rec.Body.String())
+}
+
+func TestAdmin_Config(t *testing.T) {
+	cfg := config.Config{
+		HTTPAddr:        "0.0.0.0:8080",
+		TokenSecret:     "a-secret-of-at-least-32-characters",
+		BootstrapAPIKey: "abcdefghijklmnopqrstuvwxyz0123456789",
+		TLS:             tlsconfig.Options{CertFile: "cert.pem"},
+	}
+	a := NewAdmin(BuildInfo{}, cfg.Redacted(), nil)
+
+	rec := httptest.NewRecorder()
+	a.Config(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), cfg.TokenSecret)
+	assert.NotContains(t, rec.Body.String(), cfg.BootstrapAPIKey)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "0.0.0.0:8080", got["HTTPAddr"])
+	assert.Equal(t, "[REDACTED]", got["TokenSecret"])
+	assert.Equal(t, "[REDACTED]", got["BootstrapAPIKey"])
+	assert.Equal(t, "", got["NATSURL"])
+	assert.Equal(t, "cert.pem", got["TLS"].(map[string]interface{})["CertFile"])
+}
+
+func TestAdmin_SetLogLevel(t *testing.T) {
+	previous := logrus.GetLevel()
+	defer logrus.SetLevel(previous)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantLevel  logrus.Level
+	}{
+		{
+			name:       "valid level",
+			body:       `{"level": "debug"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"level": "debug"}`,
+			wantLevel:  logrus.DebugLevel,
+		},
+		{
+			name:       "invalid level",
+			body:       `{"level": "loud"}`,
+			wantStatus: http.StatusBadRequest,
+			wantLevel:  logrus.InfoLevel,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"lvl": "debug"}`,
+			wantStatus: http.StatusBadRequest,
+			wantLevel:  logrus.InfoLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logrus.SetLevel(logrus.InfoLevel)
+			a := NewAdmin(BuildInfo{}, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			a.SetLogLevel(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantLevel, logrus.GetLevel())
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			} else {
+				assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			}
+
+			// the level is read back by GetLogLevel
+			rec = httptest.NewRecorder()
+			a.GetLogLevel(rec, httptest.NewRequest(http.MethodGet, "/log-level", nil))
+			assert.JSONEq(t, `{"level": "`+tt.wantLevel.String()+`"}`, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package transports
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+//go:generate mockgen -destination=./mocks/apikeys_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/transports APIKeysUsecase
+
+// APIKeysUsecase describes all the functions we need from usecase layer to manage api keys
+type APIKeysUsecase interface {
+	GetAll(ctx context.Context) ([]entities.APIKey, error)
+	Create(ctx context.Context, key entities.APIKey) (entities.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+	Rotate(ctx context.Context, id string) (entities.APIKey, error)
+}
+
+// APIKeys is the transport struct of the api keys admin endpoints
+type APIKeys struct {
+	usecase APIKeysUsecase
+}
+
+// NewAPIKeys is the APIKeys transport constructor
+func NewAPIKeys(ku APIKeysUsecase) APIKeys {
+	return APIKeys{usecase: ku}
+}
+
+// GetAll returns all api keys
+func (a APIKeys) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	keys, err := a.usecase.GetAll(ctx)
+	if err != nil {
+		log.WithError(err).Error("could not get all api keys")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	// To respond empty array instead of nil
+	if keys == nil {
+		keys = []entities.APIKey{}
+	}
+	render.JSON(w, r, http.StatusOK, keys)
+}
+
+// Create creates an api key, the response is the only one that contains the plain key
+func (a APIKeys) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	var key entities.APIKey
+	if !decodeJSON(w, r, &key) {
+		return
+	}
+
+	created, err := a.usecase.Create(ctx, key)
+	if err != nil {
+		log.WithError(err).Error("could not create api key")
+		if errors.Is(err, consts.ErrInvalidArgument) {
+			render.WriteProblem(w, r, http.StatusBadRequest, "")
+			return
+		}
+		if errors.Is(err, consts.ErrConflict) {
+			render.WriteProblem(w, r, http.StatusConflict, "")
+			return
+		}
+
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	render.JSON(w, r, http.StatusCreated, created)
+}
+
+// Revoke revokes an api key
+func (a APIKeys) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		log.WithField("vars", vars).Error("id not provided")
+		render.WriteProblem(w, r, http.StatusBadRequest, "")
+		return
+	}
+
+	if err := a.usecase.Revoke(ctx, id); err != nil {
+		log.WithError(err).Error("could not revoke api key")
+		render.WriteProblem(w, r, apiKeyErrorStatus(err), "")
+		return
+	}
+
+	render.Status(w, http.StatusNoContent)
+}
+
+// Rotate replaces the secret of an api key, the response contains the new plain key
+func (a APIKeys) Rotate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		log.WithField("vars", vars).Error("id not provided")
+		render.WriteProblem(w, r, http.StatusBadRequest, "")
+		return
+	}
+
+	rotated, err := a.usecase.Rotate(ctx, id)
+	if err != nil {
+		log.WithError(err).Error("could not rotate api key")
+		render.WriteProblem(w, r, apiKeyErrorStatus(err), "")
+		return
+	}
+
+	render.JSON(w, r, http.StatusOK, rotated)
+}
+
+// apiKeyErrorStatus maps the errors of revoke and rotate to a status code
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrEntityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrInvalidArgument):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,16 +2,15 @@ package transports
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
 
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
-	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
 )
 
 // run go generate ./... and the mocks will be generated
@@ -36,164 +35,172 @@ type ArticlesUsecase interface {
 // Articles is the transport struct
 type Articles struct {
 	usecase ArticlesUsecase
+	codecs  render.Negotiator
+	version ArticlesVersion
 }
 
-// NewArticles is the Articles transport constructor
-func NewArticles(au ArticlesUsecase) Articles {
-	return Articles{usecase: au}
+// NewArticles is the Articles transport constructor, the version has the bodies of the requests and responses
+// and the negotiator picks their representation
+func NewArticles(au ArticlesUsecase, codecs render.Negotiator, version ArticlesVersion) Articles {
+	return Articles{usecase: au, codecs: codecs, version: version}
 }
 
 // GetAll returns all articles
 func (a Articles) GetAll(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	articles, err := a.usecase.GetAll(ctx)
 	if err != nil {
 		log.WithError(err).Error("could not get all articles")
-		w.WriteHeader(http.StatusInternalServerError)
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
-	// To respond empty array instead of nil
-	if articles == nil {
-		articles = []entities.Article{}
-	}
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(articles); err != nil {
-		log.WithError(err).Error("could not encode response")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	a.codecs.Render(w, r, http.StatusOK, a.version.articles(articles))
 }
 
 // GetOne returns one article
 func (a Articles) GetOne(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
 	if !ok {
 		log.WithField("vars", vars).Error("id not provided")
-		w.WriteHeader(http.StatusBadRequest)
+		render.WriteProblem(w, r, http.StatusBadRequest, "")
 		return
 	}
 
 	article, err := a.usecase.GetOne(ctx, id)
 	if err != nil {
 		if errors.Is(err, consts.ErrEntityNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+			render.WriteProblem(w, r, http.StatusNotFound, "")
 			return
 		}
 
 		log.WithError(err).Error("could not find article")
-		w.WriteHeader(http.StatusInternalServerError)
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(article); err != nil {
-		log.WithError(err).Error("could not encode response")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	a.codecs.Render(w, r, http.StatusOK, a.version.article(article))
 }
 
 // Create creates an article
 func (a Articles) Create(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
-	var article entities.Article
-	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
-		log.WithError(err).Error("could not decode request body into article entity")
-		w.WriteHeader(http.StatusBadRequest)
+	in := a.version.input()
+	if !decode(w, r, a.codecs, in) {
 		return
 	}
 
-	created, err := a.usecase.Create(ctx, article)
+	created, err := a.usecase.Create(ctx, in.Entity(""))
 	if err != nil {
+		if errors.Is(err, consts.ErrInvalidArgument) {
+			log.WithError(err).Warn("invalid article")
+			render.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, consts.ErrUnauthenticated) {
+			log.WithError(err).Warn("could not create article")
+			render.WriteProblem(w, r, http.StatusUnauthorized, "")
+			return
+		}
+		if errors.Is(err, consts.ErrForbidden) {
+			log.WithError(err).Warn("could not create article")
+			render.WriteProblem(w, r, http.StatusForbidden, "")
+			return
+		}
+
 		log.WithError(err).Error("could not create article")
-		w.WriteHeader(http.StatusInternalServerError)
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(created); err != nil {
-		log.WithError(err).Error("could not encode article response")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	a.codecs.Render(w, r, http.StatusCreated, a.version.article(created))
 }
 
 // Update updates an article
 func (a Articles) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
 	if !ok {
 		log.WithField("vars", vars).Error("id not provided")
-		w.WriteHeader(http.StatusBadRequest)
+		render.WriteProblem(w, r, http.StatusBadRequest, "")
 		return
 	}
 
-	var article entities.Article
-	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
-		log.WithError(err).Error("could not decode request body into article entity")
-		w.WriteHeader(http.StatusBadRequest)
+	in := a.version.input()
+	if !decode(w, r, a.codecs, in) {
 		return
 	}
-	article.ID = id
 
-	created, err := a.usecase.Update(ctx, article)
+	created, err := a.usecase.Update(ctx, in.Entity(id))
 	if err != nil {
-		log.WithError(err).Error("could not update article")
 		if errors.Is(err, consts.ErrEntityNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+			log.WithError(err).Warn("could not update article")
+			render.WriteProblem(w, r, http.StatusNotFound, "")
+			return
+		}
+		if errors.Is(err, consts.ErrUnauthenticated) {
+			log.WithError(err).Warn("could not update article")
+			render.WriteProblem(w, r, http.StatusUnauthorized, "")
+			return
+		}
+		if errors.Is(err, consts.ErrForbidden) {
+			log.WithError(err).Warn("could not update article")
+			render.WriteProblem(w, r, http.StatusForbidden, "")
 			return
 		}
 
-		w.WriteHeader(http.StatusInternalServerError)
+		log.WithError(err).Error("could not update article")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(created); err != nil {
-		log.WithError(err).Error("could not encode article response")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	a.codecs.Render(w, r, http.StatusOK, a.version.article(created))
 }
 
 // Delete deletes an article
 func (a Articles) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
 	if !ok {
 		log.WithField("vars", vars).Error("id not provided")
-		w.WriteHeader(http.StatusBadRequest)
+		render.WriteProblem(w, r, http.StatusBadRequest, "")
 		return
 	}
 	if err := a.usecase.Delete(ctx, id); err != nil {
-		log.WithError(err).Error("could not delete article")
 		if errors.Is(err, consts.ErrEntityNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+			log.WithError(err).Warn("could not delete article")
+			render.WriteProblem(w, r, http.StatusNotFound, "")
+			return
+		}
+		if errors.Is(err, consts.ErrUnauthenticated) {
+			log.WithError(err).Warn("could not delete article")
+			render.WriteProblem(w, r, http.StatusUnauthorized, "")
+			return
+		}
+		if errors.Is(err, consts.ErrForbidden) {
+			log.WithError(err).Warn("could not delete article")
+			render.WriteProblem(w, r, http.StatusForbidden, "")
 			return
 		}
 
-		w.WriteHeader(http.StatusInternalServerError)
+		log.WithError(err).Error("could not delete article")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	render.Status(w, http.StatusOK)
 }
@@ -0,0 +1,122 @@
+package transports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// maxBodyBytes bounds the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// jsonOnly is the negotiator of the endpoints that only speak JSON
+var jsonOnly = render.NewNegotiator(render.JSONCodec{})
+
+// unknownField matches the unknown field errors of the codecs, which have no error type for them
+var unknownField = regexp.MustCompile(`unknown field "([^"]*)"`)
+
+// decodeError is a request body that can't be decoded, with the status and detail of its response
+type decodeError struct {
+	status int
+	detail string
+}
+
+func (e decodeError) Error() string {
+	return e.detail
+}
+
+// decodeJSON decodes the JSON body of the request into v, see decode
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	return decode(w, r, jsonOnly, v)
+}
+
+// decode decodes the body of the request into v, with the codec of its Content-Type.
+// The body must be a single object of at most maxBodyBytes, without unknown fields.
+// On failure the problem response is written and false is returned
+func decode(w http.ResponseWriter, r *http.Request, n render.Negotiator, v interface{}) bool {
+	err := decodeBody(w, r, n, v)
+	if err == nil {
+		return true
+	}
+
+	var de decodeError
+	if !errors.As(err, &de) {
+		de = decodeError{status: http.StatusBadRequest, detail: "could not read request body"}
+	}
+	logging.FromContext(r.Context()).WithError(err).Warn("could not decode request body")
+	render.WriteProblem(w, r, de.status, de.detail)
+	return false
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, n render.Negotiator, v interface{}) error {
+	codec, ok := n.Codec(r.Header.Get("Content-Type"))
+	if !ok {
+		types := n.MediaTypes()
+		if len(types) == 1 {
+			return decodeError{status: http.StatusUnsupportedMediaType, detail: "Content-Type must be " + types[0]}
+		}
+		return decodeError{status: http.StatusUnsupportedMediaType, detail: "Content-Type must be one of " + strings.Join(types, ", ")}
+	}
+	mediaType := codec.MediaTypes()[0]
+	isJSON := mediaType == "application/json"
+
+	err := codec.Decode(http.MaxBytesReader(w, r.Body, maxBodyBytes), v)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, render.ErrUnsupported):
+		return decodeError{status: http.StatusUnsupportedMediaType, detail: "request body can not be " + mediaType}
+	case err.Error() == "http: request body too large" || strings.HasSuffix(err.Error(), ": http: request body too large"):
+		return decodeError{status: http.StatusRequestEntityTooLarge, detail: fmt.Sprintf("request body must not be larger than %d bytes", maxBodyBytes)}
+	case errors.Is(err, render.ErrTrailingData):
+		return decodeError{status: http.StatusBadRequest, detail: err.Error()}
+	case isJSON && errors.As(err, &syntaxErr):
+		return decodeError{status: http.StatusBadRequest, detail: fmt.Sprintf("request body has malformed JSON at position %d", syntaxErr.Offset)}
+	case isJSON && errors.Is(err, io.ErrUnexpectedEOF):
+		return decodeError{status: http.StatusBadRequest, detail: "request body has malformed JSON"}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return decodeError{status: http.StatusBadRequest, detail: fmt.Sprintf("field %s must be %s", typeErr.Field, jsonType(typeErr.Type))}
+	case isJSON && errors.As(err, &typeErr):
+		return decodeError{status: http.StatusBadRequest, detail: "request body must be a JSON object"}
+	case errors.As(err, &typeErr):
+		return decodeError{status: http.StatusBadRequest, detail: "request body must be an object"}
+	case unknownField.MatchString(err.Error()):
+		return decodeError{status: http.StatusBadRequest, detail: "request body has " + unknownField.FindString(err.Error())}
+	case errors.Is(err, io.EOF):
+		return decodeError{status: http.StatusBadRequest, detail: "request body is empty"}
+	default:
+		return decodeError{status: http.StatusBadRequest, detail: "request body is not valid " + mediaType}
+	}
+}
+
+// jsonType names the JSON type of a Go type
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a " + t.String()
+	}
+}
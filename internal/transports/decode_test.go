@@ -0,0 +1,203 @@
+package transports
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+	v1 "github.com/nachogoca/golang-example-rest-api-layout/internal/transports/v1"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        v1.ArticleInput
+		wantStatus  int
+		wantDetail  string
+	}{
+		{
+			name:        "valid body",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"title": "title", "content": "content"}`,
+			want:        v1.ArticleInput{Title: "title", Content: "content"},
+		},
+		{
+			name:        "wrong content type",
+			contentType: "text/plain",
+			body:        `{"title": "title"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantDetail:  "Content-Type must be application/json",
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/json",
+			body:        `{"title": "title", "createdAt": "2020-01-01T00:00:00Z"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  `request body has unknown field "createdAt"`,
+		},
+		{
+			name:        "malformed",
+			contentType: "application/json",
+			body:        `{"title": "title",}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body has malformed JSON at position 19",
+		},
+		{
+			name:        "truncated",
+			contentType: "application/json",
+			body:        `{"title": "tit`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body has malformed JSON",
+		},
+		{
+			name:        "wrong type",
+			contentType: "application/json",
+			body:        `{"title": 1}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "field title must be a string",
+		},
+		{
+			name:        "not an object",
+			contentType: "application/json",
+			body:        `["title"]`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body must be a JSON object",
+		},
+		{
+			name:        "empty",
+			contentType: "application/json",
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body is empty",
+		},
+		{
+			name:        "trailing data",
+			contentType: "application/json",
+			body:        `{"title": "title"} {"title": "other"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body must only contain a single JSON object",
+		},
+		{
+			name:        "too large",
+			contentType: "application/json",
+			body:        `{"content": "` + strings.Repeat("a", maxBodyBytes) + `"}`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantDetail:  "request body must not be larger than 1048576 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			var got v1.ArticleInput
+			ok := decodeJSON(w, r, &got)
+			assert.Equal(t, tt.wantStatus == 0, ok)
+			if ok {
+				assert.Equal(t, tt.want, got)
+				return
+			}
+
+			var problem render.Problem
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantDetail, problem.Detail)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	n := render.NewNegotiator(render.JSONCodec{}, render.XMLCodec{}, render.YAMLCodec{}, render.CSVCodec{})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        v1.ArticleInput
+		wantStatus  int
+		wantDetail  string
+	}{
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body:        `<article><title>title</title><content>content</content></article>`,
+			want:        v1.ArticleInput{Title: "title", Content: "content"},
+		},
+		{
+			name:        "yaml",
+			contentType: "text/yaml",
+			body:        "title: title\ncontent: content\n",
+			want:        v1.ArticleInput{Title: "title", Content: "content"},
+		},
+		{
+			name:        "yaml unknown field",
+			contentType: "application/yaml",
+			body:        "title: title\nauthor: someone\n",
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  `request body has unknown field "author"`,
+		},
+		{
+			name:        "yaml wrong type",
+			contentType: "application/yaml",
+			body:        "title: [a, b]\n",
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "field title must be a string",
+		},
+		{
+			name:        "malformed xml",
+			contentType: "application/xml",
+			body:        `<article><title>title</article>`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body is not valid application/xml",
+		},
+		{
+			name:        "empty xml",
+			contentType: "application/xml",
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body is empty",
+		},
+		{
+			name:        "csv",
+			contentType: "text/csv",
+			body:        "title,content\ntitle,content\n",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantDetail:  "request body can not be text/csv",
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        "title",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantDetail:  "Content-Type must be one of application/json, application/xml, application/yaml, text/csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			var got v1.ArticleInput
+			ok := decode(w, r, n, &got)
+			assert.Equal(t, tt.wantStatus == 0, ok)
+			if ok {
+				assert.Equal(t, tt.want, got)
+				return
+			}
+
+			var problem render.Problem
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantDetail, problem.Detail)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package transports
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// checkTimeout bounds each readiness check, so a hung dependency doesn't hang the probe
+const checkTimeout = 2 * time.Second
+
+// HealthCheck is a dependency checked by the readiness endpoint
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// checkResult is the outcome of a HealthCheck
+type checkResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+type healthResponse struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks,omitempty"`
+}
+
+// Health is the transport struct of the liveness and readiness endpoints
+type Health struct {
+	checks   []HealthCheck
+	draining *int32
+}
+
+// NewHealth is the Health transport constructor
+func NewHealth(checks ...HealthCheck) Health {
+	return Health{checks: checks, draining: new(int32)}
+}
+
+// Drain makes the readiness endpoint fail, so no new traffic is routed while the server shuts down
+func (h Health) Drain() {
+	atomic.StoreInt32(h.draining, 1)
+}
+
+// Live responds ok as long as the process is able to serve requests
+func (h Health) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Ready runs the checks concurrently and responds 503 if any of them fails, or if the server is draining
+func (h Health) Ready(w http.ResponseWriter, r *http.Request) {
+	results := make([]checkResult, len(h.checks)+1)
+
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = runCheck(r.Context(), check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	results[len(h.checks)] = checkResult{Name: "draining", Status: "ok", Latency: "0s"}
+	if atomic.LoadInt32(h.draining) == 1 {
+		results[len(h.checks)].Status = "fail"
+		results[len(h.checks)].Error = "server is shutting down"
+	}
+
+	status, resp := http.StatusOK, healthResponse{Status: "ok", Checks: results}
+	for _, res := range results {
+		if res.Status != "ok" {
+			status, resp.Status = http.StatusServiceUnavailable, "fail"
+			logrus.WithField("check", res.Name).WithField("error", res.Error).Warn("readiness check failed")
+		}
+	}
+	writeHealth(w, r, status, resp)
+}
+
+func runCheck(ctx context.Context, check HealthCheck) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	res := checkResult{Name: check.Name, Status: "ok", Latency: time.Since(start).String()}
+	if err != nil {
+		res.Status, res.Error = "fail", err.Error()
+	}
+	return res
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, status int, resp healthResponse) {
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, status, resp)
+}
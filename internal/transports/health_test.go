@@ -0,0 +1,118 @@
+package transports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth_Live(t *testing.T) {
+	// liveness doesn't depend on the checks nor on draining
+	h := NewHealth(HealthCheck{Name: "database", Check: func(ctx context.Context) error { return errors.New("down") }})
+	h.Drain()
+
+	rec := httptest.NewRecorder()
+	h.Live(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"status": "ok"}`, rec.Body.String())
+}
+
+func TestHealth_Ready(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("database is locked") }
+
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		drain      bool
+		wantStatus int
+		want       healthResponse
+	}{
+		{
+			name:       "ready",
+			checks:     []HealthCheck{{Name: "database", Check: ok}, {Name: "migrations", Check: ok}},
+			wantStatus: http.StatusOK,
+			want: healthResponse{Status: "ok", Checks: []checkResult{
+				{Name: "database", Status: "ok"},
+				{Name: "migrations", Status: "ok"},
+				{Name: "draining", Status: "ok"},
+			}},
+		},
+		{
+			name:       "failing dependency",
+			checks:     []HealthCheck{{Name: "database", Check: down}, {Name: "migrations", Check: ok}},
+			wantStatus: http.StatusServiceUnavailable,
+			want: healthResponse{Status: "fail", Checks: []checkResult{
+				{Name: "database", Status: "fail", Error: "database is locked"},
+				{Name: "migrations", Status: "ok"},
+				{Name: "draining", Status: "ok"},
+			}},
+		},
+		{
+			name:       "draining",
+			checks:     []HealthCheck{{Name: "database", Check: ok}},
+			drain:      true,
+			wantStatus: http.StatusServiceUnavailable,
+			want: healthResponse{Status: "fail", Checks: []checkResult{
+				{Name: "database", Status: "ok"},
+				{Name: "draining", Status: "fail", Error: "server is shutting down"},
+			}},
+		},
+		{
+			name:       "check canceled with the request",
+			checks:     []HealthCheck{{Name: "database", Check: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }}},
+			wantStatus: http.StatusServiceUnavailable,
+			want: healthResponse{Status: "fail", Checks: []checkResult{
+				{Name: "database", Status: "fail", Error: "context canceled"},
+				{Name: "draining", Status: "ok"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealth(tt.checks...)
+			if tt.drain {
+				h.Drain()
+			}
+
+			// the request is canceled already, so a check that waits for its context returns at once
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			rec := httptest.NewRecorder()
+			h.Ready(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil).WithContext(ctx))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+			var got healthResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			for i := range got.Checks {
+				assert.NotEmpty(t, got.Checks[i].Latency)
+				got.Checks[i].Latency = ""
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHealth_Drain(t *testing.T) {
+	// copies of the transport share the draining state, like the handlers registered with its methods
+	h := NewHealth()
+	handler := http.HandlerFunc(h.Ready)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	h.Drain()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
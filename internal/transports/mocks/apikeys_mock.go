@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/nachogoca/golang-example-rest-api-layout/internal/transports (interfaces: APIKeysUsecase)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	reflect "reflect"
+)
+
+// MockAPIKeysUsecase is a mock of APIKeysUsecase interface
+type MockAPIKeysUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeysUsecaseMockRecorder
+}
+
+// MockAPIKeysUsecaseMockRecorder is the mock recorder for MockAPIKeysUsecase
+type MockAPIKeysUsecaseMockRecorder struct {
+	mock *MockAPIKeysUsecase
+}
+
+// NewMockAPIKeysUsecase creates a new mock instance
+func NewMockAPIKeysUsecase(ctrl *gomock.Controller) *MockAPIKeysUsecase {
+	mock := &MockAPIKeysUsecase{ctrl: ctrl}
+	mock.recorder = &MockAPIKeysUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAPIKeysUsecase) EXPECT() *MockAPIKeysUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockAPIKeysUsecase) Create(arg0 context.Context, arg1 entities.APIKey) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockAPIKeysUsecaseMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPIKeysUsecase)(nil).Create), arg0, arg1)
+}
+
+// GetAll mocks base method
+func (m *MockAPIKeysUsecase) GetAll(arg0 context.Context) ([]entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", arg0)
+	ret0, _ := ret[0].([]entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll
+func (mr *MockAPIKeysUsecaseMockRecorder) GetAll(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockAPIKeysUsecase)(nil).GetAll), arg0)
+}
+
+// Revoke mocks base method
+func (m *MockAPIKeysUsecase) Revoke(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke
+func (mr *MockAPIKeysUsecaseMockRecorder) Revoke(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockAPIKeysUsecase)(nil).Revoke), arg0, arg1)
+}
+
+// Rotate mocks base method
+func (m *MockAPIKeysUsecase) Rotate(arg0 context.Context, arg1 string) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rotate indicates an expected call of Rotate
+func (mr *MockAPIKeysUsecaseMockRecorder) Rotate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockAPIKeysUsecase)(nil).Rotate), arg0, arg1)
+}
@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/nachogoca/golang-example-rest-api-layout/internal/transports (interfaces: UsersUsecase)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	reflect "reflect"
+)
+
+// MockUsersUsecase is a mock of UsersUsecase interface
+type MockUsersUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsersUsecaseMockRecorder
+}
+
+// MockUsersUsecaseMockRecorder is the mock recorder for MockUsersUsecase
+type MockUsersUsecaseMockRecorder struct {
+	mock *MockUsersUsecase
+}
+
+// NewMockUsersUsecase creates a new mock instance
+func NewMockUsersUsecase(ctrl *gomock.Controller) *MockUsersUsecase {
+	mock := &MockUsersUsecase{ctrl: ctrl}
+	mock.recorder = &MockUsersUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockUsersUsecase) EXPECT() *MockUsersUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockUsersUsecase) Create(arg0 context.Context, arg1 entities.User, arg2 string) (entities.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(entities.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockUsersUsecaseMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUsersUsecase)(nil).Create), arg0, arg1, arg2)
+}
+
+// GetAll mocks base method
+func (m *MockUsersUsecase) GetAll(arg0 context.Context) ([]entities.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", arg0)
+	ret0, _ := ret[0].([]entities.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll
+func (mr *MockUsersUsecaseMockRecorder) GetAll(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockUsersUsecase)(nil).GetAll), arg0)
+}
+
+// Login mocks base method
+func (m *MockUsersUsecase) Login(arg0 context.Context, arg1 entities.Credentials) (entities.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", arg0, arg1)
+	ret0, _ := ret[0].(entities.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login
+func (mr *MockUsersUsecaseMockRecorder) Login(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUsersUsecase)(nil).Login), arg0, arg1)
+}
+
+// UpdateRoles mocks base method
+func (m *MockUsersUsecase) UpdateRoles(arg0 context.Context, arg1 entities.User) (entities.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRoles", arg0, arg1)
+	ret0, _ := ret[0].(entities.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRoles indicates an expected call of UpdateRoles
+func (mr *MockUsersUsecaseMockRecorder) UpdateRoles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRoles", reflect.TypeOf((*MockUsersUsecase)(nil).UpdateRoles), arg0, arg1)
+}
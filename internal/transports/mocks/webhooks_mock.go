@@ -0,0 +1,139 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/nachogoca/golang-example-rest-api-layout/internal/transports (interfaces: WebhooksUsecase)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	reflect "reflect"
+)
+
+// MockWebhooksUsecase is a mock of WebhooksUsecase interface
+type MockWebhooksUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhooksUsecaseMockRecorder
+}
+
+// MockWebhooksUsecaseMockRecorder is the mock recorder for MockWebhooksUsecase
+type MockWebhooksUsecaseMockRecorder struct {
+	mock *MockWebhooksUsecase
+}
+
+// NewMockWebhooksUsecase creates a new mock instance
+func NewMockWebhooksUsecase(ctrl *gomock.Controller) *MockWebhooksUsecase {
+	mock := &MockWebhooksUsecase{ctrl: ctrl}
+	mock.recorder = &MockWebhooksUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockWebhooksUsecase) EXPECT() *MockWebhooksUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockWebhooksUsecase) Create(arg0 context.Context, arg1 entities.Webhook) (entities.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(entities.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockWebhooksUsecaseMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWebhooksUsecase)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method
+func (m *MockWebhooksUsecase) Delete(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockWebhooksUsecaseMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhooksUsecase)(nil).Delete), arg0, arg1)
+}
+
+// Deliveries mocks base method
+func (m *MockWebhooksUsecase) Deliveries(arg0 context.Context, arg1 string) ([]entities.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deliveries", arg0, arg1)
+	ret0, _ := ret[0].([]entities.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Deliveries indicates an expected call of Deliveries
+func (mr *MockWebhooksUsecaseMockRecorder) Deliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deliveries", reflect.TypeOf((*MockWebhooksUsecase)(nil).Deliveries), arg0, arg1)
+}
+
+// GetAll mocks base method
+func (m *MockWebhooksUsecase) GetAll(arg0 context.Context) ([]entities.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", arg0)
+	ret0, _ := ret[0].([]entities.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll
+func (mr *MockWebhooksUsecaseMockRecorder) GetAll(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockWebhooksUsecase)(nil).GetAll), arg0)
+}
+
+// GetOne mocks base method
+func (m *MockWebhooksUsecase) GetOne(arg0 context.Context, arg1 string) (entities.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOne", arg0, arg1)
+	ret0, _ := ret[0].(entities.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOne indicates an expected call of GetOne
+func (mr *MockWebhooksUsecaseMockRecorder) GetOne(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOne", reflect.TypeOf((*MockWebhooksUsecase)(nil).GetOne), arg0, arg1)
+}
+
+// Redeliver mocks base method
+func (m *MockWebhooksUsecase) Redeliver(arg0 context.Context, arg1, arg2 string) (entities.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeliver", arg0, arg1, arg2)
+	ret0, _ := ret[0].(entities.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Redeliver indicates an expected call of Redeliver
+func (mr *MockWebhooksUsecaseMockRecorder) Redeliver(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeliver", reflect.TypeOf((*MockWebhooksUsecase)(nil).Redeliver), arg0, arg1, arg2)
+}
+
+// Update mocks base method
+func (m *MockWebhooksUsecase) Update(arg0 context.Context, arg1 entities.Webhook) (entities.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(entities.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *MockWebhooksUsecaseMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockWebhooksUsecase)(nil).Update), arg0, arg1)
+}
@@ -0,0 +1,235 @@
+package transports
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/openapi"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// problemType is the media type of the error responses
+const problemType = "application/problem+json"
+
+// OpenAPI describes the versions of the articles API, the bodies are in the media types of the negotiator
+func OpenAPI(version string, codecs render.Negotiator) *openapi.Document {
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title: "Articles API",
+			Description: "Clients authenticate with an API key, a bearer token, or a client certificate mapped to scopes by the server. " +
+				"Each version of the API is under its own prefix, the paths without one are served by the version of the " +
+				"`application/vnd.articles.<version>+json` media type of the Accept or Content-Type header, v1 by default.",
+			Version: version,
+		},
+		Security: []openapi.Requirement{{"apiKey": {}}, {"bearer": {}}},
+		Paths:    map[string]openapi.PathItem{},
+		Components: openapi.Components{
+			Schemas:   map[string]*openapi.Schema{"Problem": openapi.SchemaOf(render.Problem{})},
+			Responses: problemResponses(),
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	for _, v := range ArticlesVersions {
+		addVersion(doc, v, codecs)
+	}
+	return doc
+}
+
+// addVersion adds the paths of the version under its prefix, and its schemas named like v1.Article
+func addVersion(doc *openapi.Document, v ArticlesVersion, codecs render.Negotiator) {
+	article := openapi.SchemaOf(v.article(entities.Article{}))
+	for _, name := range []string{"id", "createdAt", "updatedAt", "author"} {
+		article.Properties[name].ReadOnly = true
+	}
+	article.Properties["id"].Format = "uuid"
+	article.Properties["author"].Description = "Kind and subject of the principal that created the article, like user:alice"
+
+	// fields left out of the input are empty, and unknown ones are rejected
+	input := openapi.SchemaOf(v.input())
+	input.Required = nil
+	input.AdditionalProperties = new(bool)
+	// longer content is rejected by the usecase
+	input.Properties[v.contentField].MaxLength = intPtr(1000)
+
+	event := openapi.SchemaOf(eventBody{})
+	event.Properties["type"].Enum = consts.EventTypes
+	event.Properties["article"] = openapi.SchemaRef(v.Name + ".Article")
+
+	doc.Components.Schemas[v.Name+".Article"] = article
+	doc.Components.Schemas[v.Name+".ArticleInput"] = input
+	doc.Components.Schemas[v.Name+".ArticleEvent"] = event
+	articleRef := openapi.SchemaRef(v.Name + ".Article")
+	inputRef := openapi.SchemaRef(v.Name + ".ArticleInput")
+
+	readScope := "Requires the `" + consts.ScopeArticlesRead + "` scope."
+	writeScope := "Requires the `" + consts.ScopeArticlesWrite + "` scope, and to be the author of the article or have the `" + consts.ScopeArticlesEditAny + "` scope."
+	id := openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}
+	operation := func(op *openapi.Operation) *openapi.Operation {
+		op.OperationID += strings.ToUpper(v.Name)
+		op.Deprecated = v.Deprecated
+		return op
+	}
+
+	prefix := "/" + v.Name
+	doc.Paths[prefix+"/articles"] = openapi.PathItem{
+		"get": operation(&openapi.Operation{
+			OperationID: "getArticles",
+			Summary:     "List the articles",
+			Description: readScope,
+			Tags:        []string{"articles"},
+			Responses: withErrors(map[string]*openapi.Response{
+				"200": body("The articles", codecs, &openapi.Schema{Type: "array", Items: articleRef}),
+			}, http.StatusNotAcceptable),
+		}),
+		"post": operation(&openapi.Operation{
+			OperationID: "createArticle",
+			Summary:     "Create an article",
+			Description: "Requires the `" + consts.ScopeArticlesWrite + "` scope, the principal is the author.",
+			Tags:        []string{"articles"},
+			RequestBody: requestBody(codecs, inputRef),
+			Responses: withErrors(map[string]*openapi.Response{
+				"201": body("The created article", codecs, articleRef),
+			}, http.StatusBadRequest, http.StatusNotAcceptable, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType),
+		}),
+	}
+	doc.Paths[prefix+"/articles/{id}"] = openapi.PathItem{
+		"get": operation(&openapi.Operation{
+			OperationID: "getArticle",
+			Summary:     "Get an article",
+			Description: readScope,
+			Tags:        []string{"articles"},
+			Parameters:  []openapi.Parameter{id},
+			Responses: withErrors(map[string]*openapi.Response{
+				"200": body("The article", codecs, articleRef),
+			}, http.StatusNotFound, http.StatusNotAcceptable),
+		}),
+		"put": operation(&openapi.Operation{
+			OperationID: "updateArticle",
+			Summary:     "Update an article",
+			Description: writeScope,
+			Tags:        []string{"articles"},
+			Parameters:  []openapi.Parameter{id},
+			RequestBody: requestBody(codecs, inputRef),
+			Responses: withErrors(map[string]*openapi.Response{
+				"200": body("The updated article", codecs, articleRef),
+			}, http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType),
+		}),
+		"delete": operation(&openapi.Operation{
+			OperationID: "deleteArticle",
+			Summary:     "Delete an article",
+			Description: writeScope,
+			Tags:        []string{"articles"},
+			Parameters:  []openapi.Parameter{id},
+			Responses: withErrors(map[string]*openapi.Response{
+				"200": {Description: "The article was deleted"},
+			}, http.StatusNotFound),
+		}),
+	}
+	doc.Paths[prefix+"/articles/stream"] = openapi.PathItem{
+		"get": operation(&openapi.Operation{
+			OperationID: "streamArticles",
+			Summary:     "Stream the changes of the articles",
+			Description: readScope + " Server-Sent Events, with the event id as `id`, the event type as `event` and the article as `data`.",
+			Tags:        []string{"events"},
+			Parameters: []openapi.Parameter{{
+				Name:        "Last-Event-ID",
+				In:          "header",
+				Description: "Id of the last event the client got, the stream starts with the ones after it",
+				Schema:      &openapi.Schema{Type: "integer", Format: "int64", Minimum: new(float64)},
+			}},
+			Responses: withErrors(map[string]*openapi.Response{
+				"200": {
+					Description: "The stream of events",
+					Content:     map[string]openapi.MediaType{"text/event-stream": {Schema: &openapi.Schema{Type: "string"}}},
+				},
+			}, http.StatusBadRequest, http.StatusServiceUnavailable),
+		}),
+	}
+	doc.Paths[prefix+"/articles/ws"] = openapi.PathItem{
+		"get": operation(&openapi.Operation{
+			OperationID: "subscribeArticles",
+			Summary:     "Subscribe to the changes of articles and authors over a WebSocket",
+			Description: readScope + " The messages are JSON objects, the `event` messages carry a " + v.Name + ".ArticleEvent.",
+			Tags:        []string{"events"},
+			Responses: withErrors(map[string]*openapi.Response{
+				"101": {Description: "Switched to the WebSocket protocol"},
+				"400": {Description: "Not a WebSocket handshake"},
+			}, http.StatusServiceUnavailable),
+		}),
+	}
+}
+
+// body is a response with the schema in the media types of the negotiator
+func body(description string, codecs render.Negotiator, schema *openapi.Schema) *openapi.Response {
+	content := map[string]openapi.MediaType{}
+	for _, t := range codecs.MediaTypes() {
+		content[t] = openapi.MediaType{Schema: schema}
+	}
+	return &openapi.Response{Description: description, Content: content}
+}
+
+func requestBody(codecs render.Negotiator, schema *openapi.Schema) *openapi.RequestBody {
+	content := map[string]openapi.MediaType{}
+	for _, t := range codecs.MediaTypes() {
+		content[t] = openapi.MediaType{Schema: schema}
+	}
+	return &openapi.RequestBody{Required: true, Content: content}
+}
+
+// withErrors adds the problems of the statuses, and the ones every route can respond
+func withErrors(responses map[string]*openapi.Response, statuses ...int) map[string]*openapi.Response {
+	statuses = append(statuses, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError)
+	for _, status := range statuses {
+		code := strconv.Itoa(status)
+		if _, ok := responses[code]; !ok {
+			responses[code] = openapi.ResponseRef(problemName(status))
+		}
+	}
+	return responses
+}
+
+// problemResponses are the responses of withErrors, named after their status
+func problemResponses() map[string]*openapi.Response {
+	statuses := []int{
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+		http.StatusNotAcceptable, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType,
+		http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable,
+	}
+	responses := map[string]*openapi.Response{}
+	for _, status := range statuses {
+		responses[problemName(status)] = &openapi.Response{
+			Description: http.StatusText(status),
+			Content:     map[string]openapi.MediaType{problemType: {Schema: openapi.SchemaRef("Problem")}},
+		}
+	}
+
+	responses[problemName(http.StatusUnauthorized)].Headers = map[string]openapi.Header{
+		"WWW-Authenticate": {Description: "The accepted credentials", Schema: &openapi.Schema{Type: "string"}},
+	}
+	responses[problemName(http.StatusTooManyRequests)].Headers = map[string]openapi.Header{
+		"Retry-After": {Description: "Seconds until the request is allowed", Schema: &openapi.Schema{Type: "integer"}},
+	}
+	return responses
+}
+
+// problemName is the status text without spaces, as TooManyRequests
+func problemName(status int) string {
+	name := []byte{}
+	for _, c := range []byte(http.StatusText(status)) {
+		if c != ' ' && c != '-' {
+			name = append(name, c)
+		}
+	}
+	return string(name)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
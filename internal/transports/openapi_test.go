@@ -0,0 +1,71 @@
+package transports
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+func TestOpenAPI(t *testing.T) {
+	doc := OpenAPI("test", render.NewNegotiator(render.JSONCodec{}, render.XMLCodec{}))
+	b, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &spec))
+
+	// every reference is to a component of the document
+	var refs []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				if k == "$ref" {
+					refs = append(refs, child.(string))
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(spec)
+	assert.NotEmpty(t, refs)
+	for _, ref := range refs {
+		var target interface{} = spec
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, _ := target.(map[string]interface{})
+			target = m[part]
+		}
+		assert.NotNil(t, target, ref)
+	}
+
+	// operations have unique ids, and bodies in the media types of the negotiator
+	ids := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			assert.False(t, ids[op.OperationID], "%s %s", method, path)
+			ids[op.OperationID] = true
+			if op.RequestBody != nil {
+				assert.Len(t, op.RequestBody.Content, 2, "%s %s", method, path)
+			}
+		}
+	}
+
+	// each version has its own bodies, the old ones are deprecated
+	v1 := doc.Components.Schemas["v1.Article"]
+	assert.Equal(t, []string{"id", "createdAt", "updatedAt", "title", "content", "author"}, v1.Required)
+	assert.Equal(t, "date-time", v1.Properties["createdAt"].Format)
+	v2 := doc.Components.Schemas["v2.Article"]
+	assert.Equal(t, []string{"id", "createdAt", "updatedAt", "title", "body", "author"}, v2.Required)
+	assert.Contains(t, doc.Components.Schemas["v2.ArticleInput"].Properties, "body")
+	assert.True(t, doc.Paths["/v1/articles"]["get"].Deprecated)
+	assert.False(t, doc.Paths["/v2/articles"]["get"].Deprecated)
+}
@@ -0,0 +1,128 @@
+package transports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+// streamRetry is how long clients wait to reconnect after a stream ends
+const streamRetry = time.Second
+
+// ArticleEvents describes the subscriptions we need to stream the changes of the articles
+type ArticleEvents interface {
+	Subscribe(ctx context.Context) (*events.Subscription, error)
+	Resume(ctx context.Context, afterID int64) (*events.Subscription, error)
+}
+
+// ArticlesStream streams the changes of the articles as Server-Sent Events
+type ArticlesStream struct {
+	events      ArticleEvents
+	version     ArticlesVersion
+	heartbeat   time.Duration
+	maxDuration time.Duration
+}
+
+// NewArticlesStream is the ArticlesStream constructor, the articles are sent in the bodies of the version.
+// A comment is sent every heartbeat so proxies keep idle streams open, and streams end after maxDuration,
+// before the write timeout of the server
+func NewArticlesStream(ae ArticleEvents, version ArticlesVersion, heartbeat, maxDuration time.Duration) ArticlesStream {
+	return ArticlesStream{events: ae, version: version, heartbeat: heartbeat, maxDuration: maxDuration}
+}
+
+// Stream sends an event for every created, updated and deleted article, with the article as data.
+// Clients that reconnect with the Last-Event-ID header get the events they missed first
+func (s ArticlesStream) Stream(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Error("response writer can not stream")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	var sub *events.Subscription
+	var err error
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		id, parseErr := strconv.ParseInt(lastEventID, 10, 64)
+		if parseErr != nil || id < 0 {
+			render.WriteProblem(w, r, http.StatusBadRequest, "Last-Event-ID must be the id of an event")
+			return
+		}
+		sub, err = s.events.Resume(r.Context(), id)
+	} else {
+		sub, err = s.events.Subscribe(r.Context())
+	}
+	if errors.Is(err, events.ErrClosed) {
+		render.WriteProblem(w, r, http.StatusServiceUnavailable, "server is shutting down")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("could not subscribe to article events")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	flusher.Flush()
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.maxDuration)
+	defer cancel()
+
+	sent := 0
+	for {
+		err := s.next(ctx, w, sub)
+		switch {
+		case err == nil:
+			sent++
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			// nothing happened since the last heartbeat
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, events.ErrClosed) {
+				log.WithError(err).Warn("article stream failed")
+			}
+			log.WithField("events", sent).Debug("article stream ended")
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// next waits up to a heartbeat for the next event and writes it
+func (s ArticlesStream) next(ctx context.Context, w http.ResponseWriter, sub *events.Subscription) error {
+	ctx, cancel := context.WithTimeout(ctx, s.heartbeat)
+	defer cancel()
+
+	event, err := sub.Next(ctx)
+	if err != nil {
+		return err
+	}
+	return writeEvent(w, event, s.version)
+}
+
+// writeEvent writes the event in the text/event-stream format, the JSON data is a single line
+func writeEvent(w http.ResponseWriter, event entities.ArticleEvent, version ArticlesVersion) error {
+	data, err := json.Marshal(version.article(event.Article))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
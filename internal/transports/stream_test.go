@@ -0,0 +1,84 @@
+package transports
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events/eventstest"
+)
+
+func TestArticlesStream_Stream(t *testing.T) {
+	bus, publish := newTestBus(t)
+	for _, id := range []string{"a", "b"} {
+		publish(entities.ArticleEvent{Type: "created", Article: entities.Article{ID: id}})
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(NewArticlesStream(bus, ArticlesV2, 20*time.Millisecond, time.Minute).Stream))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(resp.Body)
+	read := func(n int, heartbeats bool) []string {
+		var got []string
+		for len(got) < n && lines.Scan() {
+			if lines.Text() != "" && (heartbeats || lines.Text() != ": heartbeat") {
+				got = append(got, lines.Text())
+			}
+		}
+		return got
+	}
+
+	// the missed event, then a heartbeat while nothing happens
+	assert.Equal(t, []string{"retry: 1000", "id: 2", "event: created", `data: {"id":"b","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z","title":"","body":"","author":""}`}, read(4, true))
+	assert.Equal(t, []string{": heartbeat"}, read(1, true))
+
+	publish(entities.ArticleEvent{Type: "deleted", Article: entities.Article{ID: "a"}})
+	assert.Equal(t, []string{"id: 3", "event: deleted"}, read(2, false))
+
+	// the stream ends when the server shuts down
+	bus.Close()
+	for lines.Scan() {
+	}
+	assert.NoError(t, lines.Err())
+}
+
+func TestArticlesStream_Stream_Errors(t *testing.T) {
+	bus := events.NewBus(eventstest.NewMemory(), 10)
+	stream := NewArticlesStream(bus, ArticlesV2, time.Second, time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/stream", nil)
+	req.Header.Set("Last-Event-ID", "not an id")
+	rec := httptest.NewRecorder()
+	stream.Stream(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	bus.Close()
+	rec = httptest.NewRecorder()
+	stream.Stream(rec, httptest.NewRequest(http.MethodGet, "/articles/stream", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+// newTestBus returns a bus and a function that adds an event to its log and publishes it
+func newTestBus(t *testing.T) (*events.Bus, func(entities.ArticleEvent)) {
+	log := eventstest.NewMemory()
+	bus := events.NewBus(log, 10)
+	return bus, func(event entities.ArticleEvent) {
+		event, err := log.Append(context.Background(), event)
+		assert.NoError(t, err)
+		assert.NoError(t, bus.Publish(context.Background(), event))
+	}
+}
@@ -0,0 +1,146 @@
+package transports
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+//go:generate mockgen -destination=./mocks/users_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/transports UsersUsecase
+
+// UsersUsecase describes all the functions we need from usecase layer to manage users
+type UsersUsecase interface {
+	GetAll(ctx context.Context) ([]entities.User, error)
+	Create(ctx context.Context, user entities.User, password string) (entities.User, error)
+	UpdateRoles(ctx context.Context, user entities.User) (entities.User, error)
+	Login(ctx context.Context, credentials entities.Credentials) (entities.Token, error)
+}
+
+// Users is the transport struct of the login and users admin endpoints
+type Users struct {
+	usecase UsersUsecase
+}
+
+// NewUsers is the Users transport constructor
+func NewUsers(uu UsersUsecase) Users {
+	return Users{usecase: uu}
+}
+
+// Login exchanges a username and password for an access token
+func (u Users) Login(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	var credentials entities.Credentials
+	if !decodeJSON(w, r, &credentials) {
+		return
+	}
+
+	token, err := u.usecase.Login(ctx, credentials)
+	if err != nil {
+		if errors.Is(err, consts.ErrUnauthenticated) {
+			render.WriteProblem(w, r, http.StatusUnauthorized, "")
+			return
+		}
+
+		log.WithError(err).Error("could not log in")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, http.StatusOK, token)
+}
+
+// GetAll returns all users
+func (u Users) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	users, err := u.usecase.GetAll(ctx)
+	if err != nil {
+		log.WithError(err).Error("could not get all users")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	// To respond empty array instead of nil
+	if users == nil {
+		users = []entities.User{}
+	}
+	render.JSON(w, r, http.StatusOK, users)
+}
+
+// Create creates a user
+func (u Users) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	// the password is only part of the request, the entity keeps its hash
+	var req struct {
+		entities.User
+		Password string `json:"password"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	created, err := u.usecase.Create(ctx, req.User, req.Password)
+	if err != nil {
+		log.WithError(err).Error("could not create user")
+		render.WriteProblem(w, r, userErrorStatus(err), "")
+		return
+	}
+
+	render.JSON(w, r, http.StatusCreated, created)
+}
+
+// UpdateRoles replaces the roles of a user
+func (u Users) UpdateRoles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		log.WithField("vars", vars).Error("id not provided")
+		render.WriteProblem(w, r, http.StatusBadRequest, "")
+		return
+	}
+
+	var user entities.User
+	if !decodeJSON(w, r, &user) {
+		return
+	}
+	user.ID = id
+
+	updated, err := u.usecase.UpdateRoles(ctx, user)
+	if err != nil {
+		log.WithError(err).Error("could not update user roles")
+		render.WriteProblem(w, r, userErrorStatus(err), "")
+		return
+	}
+
+	render.JSON(w, r, http.StatusOK, updated)
+}
+
+// userErrorStatus maps the errors of the users usecase to a status code
+func userErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrEntityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,64 @@
+// Package v1 has the bodies of the first version of the articles API, deprecated by v2
+package v1
+
+import (
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// Article is an article in the responses and events
+type Article struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Author    string    `json:"author"`
+}
+
+// NewArticle maps the entity to its body
+func NewArticle(a entities.Article) Article {
+	return Article{
+		ID:        a.ID,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Title:     a.Title,
+		Content:   a.Content,
+		Author:    a.Author,
+	}
+}
+
+// NewArticles maps a list of entities, an empty list is not nil
+func NewArticles(as []entities.Article) []Article {
+	articles := make([]Article, 0, len(as))
+	for _, a := range as {
+		articles = append(articles, NewArticle(a))
+	}
+	return articles
+}
+
+// ArticleInput is the body of the create and update requests,
+// the id, dates and author of the article are set by the server
+type ArticleInput struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// Entity maps the body to the article with the id
+func (in ArticleInput) Entity(id string) entities.Article {
+	return entities.Article{ID: id, Title: in.Title, Content: in.Content}
+}
+
+// Event is an article event in the streams, the webhook deliveries and the outbox messages
+type Event struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	Article    Article   `json:"article"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// NewEvent maps the entity to its body
+func NewEvent(e entities.ArticleEvent) Event {
+	return Event{ID: e.ID, Type: e.Type, Article: NewArticle(e.Article), OccurredAt: e.OccurredAt}
+}
@@ -0,0 +1,51 @@
+// Package v2 has the bodies of the second version of the articles API, where the content of an article is its body
+package v2
+
+import (
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// Article is an article in the responses and events
+type Article struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Author    string    `json:"author"`
+}
+
+// NewArticle maps the entity to its body
+func NewArticle(a entities.Article) Article {
+	return Article{
+		ID:        a.ID,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Title:     a.Title,
+		Body:      a.Content,
+		Author:    a.Author,
+	}
+}
+
+// NewArticles maps a list of entities, an empty list is not nil
+func NewArticles(as []entities.Article) []Article {
+	articles := make([]Article, 0, len(as))
+	for _, a := range as {
+		articles = append(articles, NewArticle(a))
+	}
+	return articles
+}
+
+// ArticleInput is the body of the create and update requests,
+// the id, dates and author of the article are set by the server
+type ArticleInput struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Entity maps the body to the article with the id
+func (in ArticleInput) Entity(id string) entities.Article {
+	return entities.Article{ID: id, Title: in.Title, Content: in.Body}
+}
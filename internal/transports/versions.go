@@ -0,0 +1,62 @@
+package transports
+
+import (
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	v1 "github.com/nachogoca/golang-example-rest-api-layout/internal/transports/v1"
+	v2 "github.com/nachogoca/golang-example-rest-api-layout/internal/transports/v2"
+)
+
+// ArticlesVersion is a version of the articles API, with the bodies of its requests and responses,
+// so the entities can change without breaking the clients of the versions
+type ArticlesVersion struct {
+	// Name prefixes the paths of the version, and names it in the media types, like v1
+	Name string
+	// Deprecated versions are marked as such in the OpenAPI document
+	Deprecated bool
+	// contentField is the name of the content of the article in the bodies
+	contentField string
+	article      func(entities.Article) interface{}
+	articles     func([]entities.Article) interface{}
+	input        func() articleInput
+}
+
+// articleInput is the body of the create and update requests of a version
+type articleInput interface {
+	Entity(id string) entities.Article
+}
+
+// eventBody is an article event, with the article in the body of the version
+type eventBody struct {
+	ID         int64       `json:"id"`
+	Type       string      `json:"type"`
+	Article    interface{} `json:"article"`
+	OccurredAt time.Time   `json:"occurredAt"`
+}
+
+// ArticlesV1 is the first version, deprecated by ArticlesV2
+var ArticlesV1 = ArticlesVersion{
+	Name:         "v1",
+	Deprecated:   true,
+	contentField: "content",
+	article:      func(a entities.Article) interface{} { return v1.NewArticle(a) },
+	articles:     func(as []entities.Article) interface{} { return v1.NewArticles(as) },
+	input:        func() articleInput { return &v1.ArticleInput{} },
+}
+
+// ArticlesV2 renames the content of the articles to body
+var ArticlesV2 = ArticlesVersion{
+	Name:         "v2",
+	contentField: "body",
+	article:      func(a entities.Article) interface{} { return v2.NewArticle(a) },
+	articles:     func(as []entities.Article) interface{} { return v2.NewArticles(as) },
+	input:        func() articleInput { return &v2.ArticleInput{} },
+}
+
+// ArticlesVersions are all the versions, oldest first
+var ArticlesVersions = []ArticlesVersion{ArticlesV1, ArticlesV2}
+
+func (v ArticlesVersion) event(e entities.ArticleEvent) eventBody {
+	return eventBody{ID: e.ID, Type: e.Type, Article: v.article(e.Article), OccurredAt: e.OccurredAt}
+}
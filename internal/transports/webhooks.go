@@ -0,0 +1,222 @@
+package transports
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+//go:generate mockgen -destination=./mocks/webhooks_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/transports WebhooksUsecase
+
+// WebhooksUsecase describes all the functions we need from usecase layer to manage webhooks
+type WebhooksUsecase interface {
+	GetAll(ctx context.Context) ([]entities.Webhook, error)
+	GetOne(ctx context.Context, id string) (entities.Webhook, error)
+	Create(ctx context.Context, hook entities.Webhook) (entities.Webhook, error)
+	Update(ctx context.Context, hook entities.Webhook) (entities.Webhook, error)
+	Delete(ctx context.Context, id string) error
+	Deliveries(ctx context.Context, webhookID string) ([]entities.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (entities.WebhookDelivery, error)
+}
+
+// webhookInput is the body of the create and update requests,
+// the secret is optional, one is generated on create and the current one is kept on update
+type webhookInput struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+func (in webhookInput) entity(id string) entities.Webhook {
+	return entities.Webhook{ID: id, URL: in.URL, Events: in.Events, Secret: in.Secret}
+}
+
+// Webhooks is the transport struct of the webhooks admin endpoints
+type Webhooks struct {
+	usecase WebhooksUsecase
+}
+
+// NewWebhooks is the Webhooks transport constructor
+func NewWebhooks(wu WebhooksUsecase) Webhooks {
+	return Webhooks{usecase: wu}
+}
+
+// GetAll returns all webhooks
+func (h Webhooks) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	hooks, err := h.usecase.GetAll(ctx)
+	if err != nil {
+		log.WithError(err).Error("could not get all webhooks")
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	// To respond empty array instead of nil
+	if hooks == nil {
+		hooks = []entities.Webhook{}
+	}
+	render.JSON(w, r, http.StatusOK, hooks)
+}
+
+// GetOne returns a webhook
+func (h Webhooks) GetOne(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	id, ok := pathVar(w, r, "id")
+	if !ok {
+		return
+	}
+
+	hook, err := h.usecase.GetOne(ctx, id)
+	if err != nil {
+		log.WithError(err).Error("could not get webhook")
+		writeWebhookError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, http.StatusOK, hook)
+}
+
+// Create subscribes a URL to the article events, the response is the only one that contains the secret
+func (h Webhooks) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	var in webhookInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	created, err := h.usecase.Create(ctx, in.entity(""))
+	if err != nil {
+		log.WithError(err).Error("could not create webhook")
+		writeWebhookError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, http.StatusCreated, created)
+}
+
+// Update replaces the URL and events of a webhook, and its secret if one is given
+func (h Webhooks) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	id, ok := pathVar(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var in webhookInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	updated, err := h.usecase.Update(ctx, in.entity(id))
+	if err != nil {
+		log.WithError(err).Error("could not update webhook")
+		writeWebhookError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, http.StatusOK, updated)
+}
+
+// Delete removes a webhook and its deliveries
+func (h Webhooks) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	id, ok := pathVar(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(ctx, id); err != nil {
+		log.WithError(err).Error("could not delete webhook")
+		writeWebhookError(w, r, err)
+		return
+	}
+
+	render.Status(w, http.StatusNoContent)
+}
+
+// Deliveries returns the last deliveries of a webhook, with the result of their last attempt
+func (h Webhooks) Deliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	id, ok := pathVar(w, r, "id")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.usecase.Deliveries(ctx, id)
+	if err != nil {
+		log.WithError(err).Error("could not get webhook deliveries")
+		writeWebhookError(w, r, err)
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []entities.WebhookDelivery{}
+	}
+	render.JSON(w, r, http.StatusOK, deliveries)
+}
+
+// Redeliver queues a delivery again, the response is the new delivery
+func (h Webhooks) Redeliver(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+
+	id, ok := pathVar(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := pathVar(w, r, "deliveryId")
+	if !ok {
+		return
+	}
+
+	created, err := h.usecase.Redeliver(ctx, id, deliveryID)
+	if err != nil {
+		log.WithError(err).Error("could not redeliver webhook delivery")
+		writeWebhookError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, http.StatusAccepted, created)
+}
+
+// pathVar returns a variable of the route, responding 400 when it is missing
+func pathVar(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	vars := mux.Vars(r)
+	value, ok := vars[name]
+	if !ok {
+		logging.FromContext(r.Context()).WithField("vars", vars).Error(name + " not provided")
+		render.WriteProblem(w, r, http.StatusBadRequest, "")
+	}
+	return value, ok
+}
+
+// writeWebhookError maps the errors of the webhooks usecase to a problem, validation errors are explained
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, consts.ErrEntityNotFound):
+		render.WriteProblem(w, r, http.StatusNotFound, "")
+	case errors.Is(err, consts.ErrInvalidArgument):
+		render.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+	default:
+		render.WriteProblem(w, r, http.StatusInternalServerError, "")
+	}
+}
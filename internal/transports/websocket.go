@@ -0,0 +1,365 @@
+package transports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+)
+
+const (
+	// maxMessageBytes bounds the size of the messages sent by clients
+	maxMessageBytes = 4 << 10
+	// writeWait is how long a client has to read a message before it's disconnected
+	writeWait = 10 * time.Second
+)
+
+// Types of the messages of the WebSocket protocol
+const (
+	msgSubscribe    = "subscribe"
+	msgUnsubscribe  = "unsubscribe"
+	msgSubscribed   = "subscribed"
+	msgUnsubscribed = "unsubscribed"
+	msgEvent        = "event"
+	msgPing         = "ping"
+	msgPong         = "pong"
+	msgError        = "error"
+)
+
+// WebSocketLimits bound the resources a WebSocket connection can take
+type WebSocketLimits struct {
+	// MaxSubscriptions is the most subscriptions a connection can have at once
+	MaxSubscriptions int
+	// MaxFilters is the most article ids and authors a subscription can have
+	MaxFilters int
+	// MaxPending is the most messages waiting to be sent to a connection, past it the subscriptions
+	// wait, and catch up from the change log once the client reads again
+	MaxPending int
+	// PingInterval is how often the server pings, a client that doesn't answer in time is disconnected
+	PingInterval time.Duration
+}
+
+// clientMessage is a message sent by a client
+type clientMessage struct {
+	Type         string   `json:"type"`
+	Subscription string   `json:"subscription"`
+	Articles     []string `json:"articles"`
+	Authors      []string `json:"authors"`
+	// After resumes the subscription after the id of the last event the client got
+	After *int64 `json:"after"`
+}
+
+// serverMessage is a message sent to a client
+type serverMessage struct {
+	Type         string     `json:"type"`
+	Subscription string     `json:"subscription,omitempty"`
+	Event        *eventBody `json:"event,omitempty"`
+	Message      string     `json:"message,omitempty"`
+}
+
+// ArticlesWebSocket lets clients subscribe to the changes of some articles, or of the articles
+// of some authors, over a WebSocket
+type ArticlesWebSocket struct {
+	events   ArticleEvents
+	version  ArticlesVersion
+	limits   WebSocketLimits
+	upgrader websocket.Upgrader
+
+	mu      *sync.Mutex
+	conns   map[*wsConn]struct{}
+	stopped *bool
+}
+
+// NewArticlesWebSocket is the ArticlesWebSocket constructor, the articles are sent in the bodies of the version
+func NewArticlesWebSocket(ae ArticleEvents, version ArticlesVersion, limits WebSocketLimits) ArticlesWebSocket {
+	stopped := false
+	return ArticlesWebSocket{
+		events:  ae,
+		version: version,
+		limits:  limits,
+		mu:      &sync.Mutex{},
+		conns:   map[*wsConn]struct{}{},
+		stopped: &stopped,
+	}
+}
+
+// Serve upgrades the request to a WebSocket and serves the subscriptions of the principal
+// that authenticated it, until either side closes it
+func (a ArticlesWebSocket) Serve(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+
+	a.mu.Lock()
+	stopped := *a.stopped
+	a.mu.Unlock()
+	if stopped {
+		render.WriteProblem(w, r, http.StatusServiceUnavailable, "server is shutting down")
+		return
+	}
+
+	// the upgrader responds to bad handshakes itself
+	ws, err := a.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.WithError(err).Warn("could not upgrade to websocket")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	c := &wsConn{
+		ws:      ws,
+		events:  a.events,
+		version: a.version,
+		limits:  a.limits,
+		send:    make(chan serverMessage, a.limits.MaxPending),
+		subs:    map[string]context.CancelFunc{},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	if !a.add(c) {
+		c.close(websocket.CloseGoingAway, "server is shutting down")
+		return
+	}
+	defer a.remove(c)
+
+	log.Debug("websocket connected")
+	go c.write()
+	err = c.read()
+	c.close(websocket.CloseNormalClosure, "")
+	log.WithError(err).Debug("websocket disconnected")
+}
+
+// Stop closes the connections, telling the clients the server is going away
+func (a ArticlesWebSocket) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	*a.stopped = true
+	for c := range a.conns {
+		c.close(websocket.CloseGoingAway, "server is shutting down")
+	}
+}
+
+func (a ArticlesWebSocket) add(c *wsConn) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if *a.stopped {
+		return false
+	}
+	a.conns[c] = struct{}{}
+	return true
+}
+
+func (a ArticlesWebSocket) remove(c *wsConn) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.conns, c)
+}
+
+// wsConn is a WebSocket connection. The read loop handles the client messages, the write loop
+// sends the queued messages and the pings, and every subscription pushes its events to the queue
+type wsConn struct {
+	ws      *websocket.Conn
+	events  ArticleEvents
+	version ArticlesVersion
+	limits  WebSocketLimits
+	send    chan serverMessage
+
+	// subs is only used by the read loop
+	subs map[string]context.CancelFunc
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// read handles the client messages until the connection fails or is closed
+func (c *wsConn) read() error {
+	pongWait := c.limits.PingInterval + writeWait
+	c.ws.SetReadLimit(maxMessageBytes)
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		c.ws.SetReadDeadline(time.Now().Add(pongWait))
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.enqueue(serverMessage{Type: msgError, Message: "message is not valid JSON"})
+			continue
+		}
+
+		switch msg.Type {
+		case msgSubscribe:
+			c.subscribe(msg)
+		case msgUnsubscribe:
+			c.unsubscribe(msg)
+		case msgPing:
+			c.enqueue(serverMessage{Type: msgPong})
+		default:
+			c.enqueue(serverMessage{Type: msgError, Message: fmt.Sprintf("unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+func (c *wsConn) subscribe(msg clientMessage) {
+	fail := func(message string) {
+		c.enqueue(serverMessage{Type: msgError, Subscription: msg.Subscription, Message: message})
+	}
+	switch filters := len(msg.Articles) + len(msg.Authors); {
+	case msg.Subscription == "":
+		fail("subscription is required")
+		return
+	case c.subs[msg.Subscription] != nil:
+		fail("subscription already exists")
+		return
+	case len(c.subs) >= c.limits.MaxSubscriptions:
+		fail(fmt.Sprintf("a connection can have at most %d subscriptions", c.limits.MaxSubscriptions))
+		return
+	case filters == 0:
+		fail("articles or authors are required")
+		return
+	case filters > c.limits.MaxFilters:
+		fail(fmt.Sprintf("a subscription can have at most %d articles and authors", c.limits.MaxFilters))
+		return
+	}
+
+	var sub *events.Subscription
+	var err error
+	if msg.After != nil {
+		sub, err = c.events.Resume(c.ctx, *msg.After)
+	} else {
+		sub, err = c.events.Subscribe(c.ctx)
+	}
+	if err != nil {
+		if !errors.Is(err, events.ErrClosed) {
+			logging.FromContext(c.ctx).WithError(err).Error("could not subscribe to article events")
+		}
+		fail("could not subscribe")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.subs[msg.Subscription] = cancel
+	c.enqueue(serverMessage{Type: msgSubscribed, Subscription: msg.Subscription})
+	go c.pump(ctx, msg.Subscription, sub, newArticleFilter(msg.Articles, msg.Authors))
+}
+
+func (c *wsConn) unsubscribe(msg clientMessage) {
+	cancel, ok := c.subs[msg.Subscription]
+	if !ok {
+		c.enqueue(serverMessage{Type: msgError, Subscription: msg.Subscription, Message: "subscription does not exist"})
+		return
+	}
+	cancel()
+	delete(c.subs, msg.Subscription)
+	c.enqueue(serverMessage{Type: msgUnsubscribed, Subscription: msg.Subscription})
+}
+
+// pump queues the events that match the filter until the subscription is cancelled
+func (c *wsConn) pump(ctx context.Context, name string, sub *events.Subscription, filter articleFilter) {
+	defer sub.Close()
+	for {
+		event, err := sub.Next(ctx)
+		if errors.Is(err, events.ErrClosed) {
+			c.close(websocket.CloseGoingAway, "server is shutting down")
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.FromContext(ctx).WithError(err).Error("could not get article events")
+				c.close(websocket.CloseInternalServerErr, "could not get article events")
+			}
+			return
+		}
+
+		if !filter.matches(event.Article) {
+			continue
+		}
+		body := c.version.event(event)
+		if !c.enqueueCtx(ctx, serverMessage{Type: msgEvent, Subscription: name, Event: &body}) {
+			return
+		}
+	}
+}
+
+// enqueue queues a message, waiting while the queue is full
+func (c *wsConn) enqueue(msg serverMessage) bool {
+	return c.enqueueCtx(c.ctx, msg)
+}
+
+func (c *wsConn) enqueueCtx(ctx context.Context, msg serverMessage) bool {
+	select {
+	case c.send <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// write sends the queued messages and the pings, a client that doesn't read them in time is disconnected
+func (c *wsConn) write() {
+	ping := time.NewTicker(c.limits.PingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			err = c.ws.WriteJSON(msg)
+		case <-ping.C:
+			err = c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+		case <-c.ctx.Done():
+			return
+		}
+		if err != nil {
+			logging.FromContext(c.ctx).WithError(err).Debug("could not write to websocket")
+			c.close(websocket.CloseGoingAway, "")
+			return
+		}
+	}
+}
+
+// close sends a close message with the code and closes the connection, once
+func (c *wsConn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+		c.ws.Close()
+	})
+}
+
+// articleFilter matches the articles by id or by author
+type articleFilter struct {
+	ids     map[string]bool
+	authors map[string]bool
+}
+
+func newArticleFilter(ids, authors []string) articleFilter {
+	f := articleFilter{ids: map[string]bool{}, authors: map[string]bool{}}
+	for _, id := range ids {
+		f.ids[id] = true
+	}
+	for _, author := range authors {
+		f.authors[author] = true
+	}
+	return f
+}
+
+func (f articleFilter) matches(article entities.Article) bool {
+	return f.ids[article.ID] || f.authors[article.Author]
+}
@@ -0,0 +1,121 @@
+package transports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+func TestArticlesWebSocket_Serve(t *testing.T) {
+	bus, publishEvent := newTestBus(t)
+	socket := NewArticlesWebSocket(bus, ArticlesV2, WebSocketLimits{MaxSubscriptions: 2, MaxFilters: 2, MaxPending: 1, PingInterval: time.Minute})
+	srv := httptest.NewServer(http.HandlerFunc(socket.Serve))
+	defer srv.Close()
+
+	publish := func(id, author string) {
+		event := entities.ArticleEvent{Type: "updated", Article: entities.Article{ID: id, Author: author}}
+		publishEvent(event)
+	}
+	publish("old", "alice")
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer ws.Close()
+	exchange := func(msg clientMessage) serverMessage {
+		assert.NoError(t, ws.WriteJSON(msg))
+		return receive(t, ws)
+	}
+
+	tests := []struct {
+		name string
+		msg  clientMessage
+		want serverMessage
+	}{
+		{
+			name: "ping",
+			msg:  clientMessage{Type: msgPing},
+			want: serverMessage{Type: msgPong},
+		},
+		{
+			name: "subscribe to an article",
+			msg:  clientMessage{Type: msgSubscribe, Subscription: "a", Articles: []string{"1"}},
+			want: serverMessage{Type: msgSubscribed, Subscription: "a"},
+		},
+		{
+			name: "duplicated subscription",
+			msg:  clientMessage{Type: msgSubscribe, Subscription: "a", Articles: []string{"1"}},
+			want: serverMessage{Type: msgError, Subscription: "a", Message: "subscription already exists"},
+		},
+		{
+			name: "unknown type",
+			msg:  clientMessage{Type: "publish"},
+			want: serverMessage{Type: msgError, Message: `unknown message type "publish"`},
+		},
+		{
+			name: "no filters",
+			msg:  clientMessage{Type: msgSubscribe, Subscription: "c"},
+			want: serverMessage{Type: msgError, Subscription: "c", Message: "articles or authors are required"},
+		},
+		{
+			name: "subscribe to an author from the start",
+			msg:  clientMessage{Type: msgSubscribe, Subscription: "b", Authors: []string{"alice"}, After: new(int64)},
+			want: serverMessage{Type: msgSubscribed, Subscription: "b"},
+		},
+	}
+	for _, tt := range tests {
+		if !assert.Equal(t, tt.want, exchange(tt.msg), tt.name) {
+			return
+		}
+	}
+
+	// the resumed subscription gets the old event first
+	got := receive(t, ws)
+	assert.Equal(t, "b", got.Subscription)
+	assert.Equal(t, "old", articleID(got))
+	assert.Equal(t,
+		serverMessage{Type: msgError, Subscription: "c", Message: "a connection can have at most 2 subscriptions"},
+		exchange(clientMessage{Type: msgSubscribe, Subscription: "c", Articles: []string{"1"}}))
+
+	// events go to the subscriptions they match, even with a small queue
+	publish("1", "bob")
+	publish("2", "carol")
+	publish("3", "alice")
+	var routed []string
+	for i := 0; i < 2; i++ {
+		got := receive(t, ws)
+		routed = append(routed, got.Subscription+" "+articleID(got))
+	}
+	sort.Strings(routed)
+	assert.Equal(t, []string{"a 1", "b 3"}, routed)
+
+	assert.Equal(t, serverMessage{Type: msgUnsubscribed, Subscription: "a"}, exchange(clientMessage{Type: msgUnsubscribe, Subscription: "a"}))
+	publish("1", "bob")
+	assert.Equal(t, serverMessage{Type: msgPong}, exchange(clientMessage{Type: msgPing}))
+
+	// clients are told when the server goes away
+	socket.Stop()
+	_, _, err = ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "got %v", err)
+}
+
+func receive(t *testing.T, ws *websocket.Conn) serverMessage {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var msg serverMessage
+	assert.NoError(t, ws.ReadJSON(&msg))
+	return msg
+}
+
+// articleID is the id of the article of an event message, decoded as a map
+func articleID(msg serverMessage) string {
+	article, _ := msg.Event.Article.(map[string]interface{})
+	id, _ := article["id"].(string)
+	return id
+}
@@ -0,0 +1,276 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+const (
+	apiKeyPrefix    = "ak_"
+	apiKeyPrefixLen = 8
+	apiKeySecretLen = 32
+
+	minBootstrapKeyLen = 32
+)
+
+//go:generate mockgen -destination=./mocks/apikeys_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/usecases APIKeysStore
+
+// APIKeysStore describes all the functions we need from store layer to manage api keys
+type APIKeysStore interface {
+	GetAll(ctx context.Context) ([]entities.APIKey, error)
+	GetOne(ctx context.Context, id string) (entities.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (entities.APIKey, error)
+	GetByName(ctx context.Context, name string) (entities.APIKey, error)
+	Create(ctx context.Context, key entities.APIKey) (entities.APIKey, error)
+	Update(ctx context.Context, key entities.APIKey) (entities.APIKey, error)
+}
+
+// APIKeys is the usecase that manages api keys and authenticates them
+type APIKeys struct {
+	store APIKeysStore
+}
+
+// NewAPIKeys is the APIKeys constructor
+func NewAPIKeys(ks APIKeysStore) APIKeys {
+	return APIKeys{store: ks}
+}
+
+// GetAll returns all api keys, without their secret
+func (a APIKeys) GetAll(ctx context.Context) ([]entities.APIKey, error) {
+	log := logging.FromContext(ctx)
+
+	keys, err := a.store.GetAll(ctx)
+	if err != nil {
+		log.WithError(err).Error("could not get all api keys")
+		return nil, fmt.Errorf("could not get all api keys: %w", err)
+	}
+
+	log.WithField("keys", len(keys)).Info("found api keys")
+	return keys, nil
+}
+
+// Create generates a new api key with the given scopes
+// The returned entity is the only one that carries the plain key
+func (a APIKeys) Create(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+	log := logging.FromContext(ctx)
+
+	if key.Name == "" {
+		return entities.APIKey{}, fmt.Errorf("api key name is required: %w", consts.ErrInvalidArgument)
+	}
+	if err := validateScopes(key.Scopes); err != nil {
+		return entities.APIKey{}, err
+	}
+	if err := validateRoles(key.Roles); err != nil {
+		return entities.APIKey{}, err
+	}
+
+	plain, err := generateAPIKey()
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not generate api key: %w", err)
+	}
+
+	return a.create(ctx, log, key.Name, key.Scopes, key.Roles, plain)
+}
+
+// Bootstrap stores a well known api key, used to create the first keys through the admin endpoints.
+// The key with the name is kept in sync with plain: when plain changed, or the key was rotated or revoked
+// since the last start, its secret is replaced and it gets all the scopes again
+func (a APIKeys) Bootstrap(ctx context.Context, name, plain string) error {
+	log := logging.FromContext(ctx).WithField("name", name)
+
+	if len(plain) < minBootstrapKeyLen {
+		return fmt.Errorf("bootstrap api key must be at least %d characters: %w", minBootstrapKeyLen, consts.ErrInvalidArgument)
+	}
+
+	key, err := a.store.GetByName(ctx, name)
+	if errors.Is(err, consts.ErrEntityNotFound) {
+		if _, err := a.create(ctx, log, name, consts.Scopes, []string{consts.RoleAdmin}, plain); err != nil {
+			return fmt.Errorf("could not bootstrap api key: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not get bootstrap api key: %w", err)
+	}
+
+	if key.Hash == hashAPIKey(plain) && key.RevokedAt == nil {
+		log.Debug("bootstrap api key already exists")
+		return nil
+	}
+
+	key.Prefix = plain[:len(apiKeyPrefix)+apiKeyPrefixLen]
+	key.Hash = hashAPIKey(plain)
+	key.Scopes = consts.Scopes
+	key.Roles = []string{consts.RoleAdmin}
+	key.RevokedAt = nil
+	key.UpdatedAt = time.Now().UTC()
+	if _, err := a.store.Update(ctx, key); err != nil {
+		return fmt.Errorf("could not update bootstrap api key: %w", err)
+	}
+	log.WithField("id", key.ID).Info("bootstrap api key replaced")
+	return nil
+}
+
+func (a APIKeys) create(ctx context.Context, log *logrus.Entry, name string, scopes, roles []string, plain string) (entities.APIKey, error) {
+	now := time.Now().UTC()
+	created, err := a.store.Create(ctx, entities.APIKey{
+		ID:        uuid.New().String(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      name,
+		Prefix:    plain[:len(apiKeyPrefix)+apiKeyPrefixLen],
+		Scopes:    scopes,
+		Roles:     roles,
+		Hash:      hashAPIKey(plain),
+	})
+	if err != nil {
+		return entities.APIKey{}, fmt.Errorf("could not create api key: %w", err)
+	}
+	log.WithField("id", created.ID).WithField("name", created.Name).Info("api key created")
+
+	created.Key = plain
+	return created, nil
+}
+
+// Revoke disables an api key, it can not be used anymore
+func (a APIKeys) Revoke(ctx context.Context, id string) error {
+	log := logging.FromContext(ctx)
+
+	key, err := a.getActive(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+	key.UpdatedAt = now
+	if _, err := a.store.Update(ctx, key); err != nil {
+		return fmt.Errorf("could not revoke api key: %w", err)
+	}
+	log.WithField("id", id).Info("api key revoked")
+
+	return nil
+}
+
+// Rotate replaces the secret of an api key, keeping its id, name and scopes
+// The previous secret stops working immediately
+func (a APIKeys) Rotate(ctx context.Context, id string) (entities.APIKey, error) {
+	log := logging.FromContext(ctx)
+
+	key, err := a.getActive(ctx, id)
+	if err != nil {
+		return entities.APIKey{}, err
+	}
+
+	plain, err := generateAPIKey()
+	if err != nil {
+		return entities.APIKey{}, stacktrace.Errorf("could not generate api key: %w", err)
+	}
+
+	key.Prefix = plain[:len(apiKeyPrefix)+apiKeyPrefixLen]
+	key.Hash = hashAPIKey(plain)
+	key.UpdatedAt = time.Now().UTC()
+	rotated, err := a.store.Update(ctx, key)
+	if err != nil {
+		return entities.APIKey{}, fmt.Errorf("could not rotate api key: %w", err)
+	}
+	log.WithField("id", id).Info("api key rotated")
+
+	rotated.Key = plain
+	return rotated, nil
+}
+
+// Authenticate returns the principal that owns the api key
+func (a APIKeys) Authenticate(ctx context.Context, plain string) (entities.Principal, error) {
+	key, err := a.store.GetByHash(ctx, hashAPIKey(plain))
+	if err != nil {
+		if errors.Is(err, consts.ErrEntityNotFound) {
+			return entities.Principal{}, fmt.Errorf("unknown api key: %w", consts.ErrUnauthenticated)
+		}
+		return entities.Principal{}, fmt.Errorf("could not get api key: %w", err)
+	}
+
+	if key.RevokedAt != nil {
+		return entities.Principal{}, fmt.Errorf("api key %s is revoked: %w", key.ID, consts.ErrUnauthenticated)
+	}
+
+	return entities.Principal{
+		Subject: key.Name,
+		Kind:    consts.PrincipalAPIKey,
+		Scopes:  key.Scopes,
+		Roles:   key.Roles,
+	}, nil
+}
+
+// getActive returns a key that has not been revoked
+func (a APIKeys) getActive(ctx context.Context, id string) (entities.APIKey, error) {
+	key, err := a.store.GetOne(ctx, id)
+	if err != nil {
+		return entities.APIKey{}, fmt.Errorf("could not get api key id %s: %w", id, err)
+	}
+	if key.RevokedAt != nil {
+		return entities.APIKey{}, fmt.Errorf("api key id %s is revoked: %w", id, consts.ErrInvalidArgument)
+	}
+	return key, nil
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required: %w", consts.ErrInvalidArgument)
+	}
+
+	for _, scope := range scopes {
+		if !contains(consts.Scopes, scope) {
+			return fmt.Errorf("unknown scope %s: %w", scope, consts.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+func validateRoles(roles []string) error {
+	for _, role := range roles {
+		if !contains(consts.Roles, role) {
+			return fmt.Errorf("unknown role %s: %w", role, consts.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAPIKey returns a random key, the prefix helps to identify leaked keys
+func generateAPIKey() (string, error) {
+	secret := make([]byte, apiKeySecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashAPIKey hashes the key before storing it or looking it up
+// keys are random and long, so a fast hash is enough
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
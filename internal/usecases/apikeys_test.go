@@ -0,0 +1,167 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys_Authenticate(t *testing.T) {
+	revokedAt := time.Now()
+	tests := []struct {
+		name      string
+		mockStore func(m *mocks.MockAPIKeysStore)
+		want      entities.Principal
+		wantErr   error
+	}{
+		{
+			name: "Success: Active key",
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				m.EXPECT().
+					GetByHash(gomock.Any(), hashAPIKey("ak_secret")).
+					Return(entities.APIKey{Name: "frontend", Scopes: []string{consts.ScopeArticlesRead}}, nil)
+			},
+			want: entities.Principal{
+				Subject: "frontend",
+				Kind:    consts.PrincipalAPIKey,
+				Scopes:  []string{consts.ScopeArticlesRead},
+			},
+		},
+		{
+			name: "Failure: Unknown key",
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				m.EXPECT().
+					GetByHash(gomock.Any(), gomock.Any()).
+					Return(entities.APIKey{}, consts.ErrEntityNotFound)
+			},
+			wantErr: consts.ErrUnauthenticated,
+		},
+		{
+			name: "Failure: Revoked key",
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				m.EXPECT().
+					GetByHash(gomock.Any(), gomock.Any()).
+					Return(entities.APIKey{Name: "frontend", RevokedAt: &revokedAt}, nil)
+			},
+			wantErr: consts.ErrUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			m := mocks.NewMockAPIKeysStore(ctrl)
+			tt.mockStore(m)
+
+			a := APIKeys{
+				store: m,
+			}
+
+			got, err := a.Authenticate(context.Background(), "ak_secret")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("APIKeys.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAPIKeys_Bootstrap(t *testing.T) {
+	const plain = "abcdefghijklmnopqrstuvwxyz0123456789"
+	revokedAt := time.Now()
+	stored := entities.APIKey{ID: "1", Name: "bootstrap", Hash: hashAPIKey(plain), Scopes: consts.Scopes, Roles: []string{consts.RoleAdmin}}
+	replaced := func(key entities.APIKey) bool {
+		return key.ID == "1" && key.Hash == hashAPIKey(plain) && key.Prefix == plain[:11] &&
+			key.RevokedAt == nil && assert.ObjectsAreEqual(consts.Scopes, key.Scopes)
+	}
+
+	tests := []struct {
+		name      string
+		plain     string
+		mockStore func(m *mocks.MockAPIKeysStore)
+		wantErr   error
+	}{
+		{
+			name:  "Success: First start creates the key",
+			plain: plain,
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				m.EXPECT().GetByName(gomock.Any(), "bootstrap").Return(entities.APIKey{}, consts.ErrEntityNotFound)
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+					assert.Equal(t, hashAPIKey(plain), key.Hash)
+					return key, nil
+				})
+			},
+		},
+		{
+			name:  "Success: Same key on the next start",
+			plain: plain,
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				m.EXPECT().GetByName(gomock.Any(), "bootstrap").Return(stored, nil)
+			},
+		},
+		{
+			name:  "Success: Key rotated through the api is replaced",
+			plain: plain,
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				rotated := stored
+				rotated.Hash = hashAPIKey("ak_rotated")
+				m.EXPECT().GetByName(gomock.Any(), "bootstrap").Return(rotated, nil)
+				m.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+					assert.True(t, replaced(key), "key not replaced: %+v", key)
+					return key, nil
+				})
+			},
+		},
+		{
+			name:  "Success: Changed environment variable replaces the revoked key",
+			plain: plain,
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				previous := stored
+				previous.Hash = hashAPIKey("the previous bootstrap key of the environment")
+				previous.RevokedAt = &revokedAt
+				m.EXPECT().GetByName(gomock.Any(), "bootstrap").Return(previous, nil)
+				m.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+					assert.True(t, replaced(key), "key not replaced: %+v", key)
+					return key, nil
+				})
+			},
+		},
+		{
+			name:      "Failure: Short key",
+			plain:     "short",
+			mockStore: func(m *mocks.MockAPIKeysStore) {},
+			wantErr:   consts.ErrInvalidArgument,
+		},
+		{
+			name:  "Failure: Store error",
+			plain: plain,
+			mockStore: func(m *mocks.MockAPIKeysStore) {
+				m.EXPECT().GetByName(gomock.Any(), "bootstrap").Return(entities.APIKey{}, errors.New("database is down"))
+			},
+			wantErr: errors.New("database is down"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := mocks.NewMockAPIKeysStore(ctrl)
+			tt.mockStore(m)
+
+			err := NewAPIKeys(m).Bootstrap(context.Background(), "bootstrap", tt.plain)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) && !errors.Is(err, tt.wantErr) {
+				assert.Contains(t, err.Error(), tt.wantErr.Error())
+			}
+		})
+	}
+}
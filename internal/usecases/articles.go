@@ -7,40 +7,53 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
-	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
 )
 
 const maxContentLen = 1000
 
-//go:generate mockgen -destination=./mocks/articles_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/usecases ArticlesStore
+//go:generate mockgen -destination=./mocks/articles_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/usecases ArticlesStore,ArticlesNotifier
 
 // ArticlesStore describes all the functions we need from store layer
 // create other interfaces as usecases needed
 type ArticlesStore interface {
 	GetAll(ctx context.Context) ([]entities.Article, error)
 	GetOne(ctx context.Context, id string) (entities.Article, error)
+	Find(ctx context.Context, filter entities.ArticleFilter) ([]entities.Article, error)
 	Create(ctx context.Context, article entities.Article) (entities.Article, error)
-	Update(ctx context.Context, article entities.Article) (entities.Article, error)
-	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, id string, modify func(entities.Article) (entities.Article, error)) (entities.Article, error)
+	Delete(ctx context.Context, id string, check func(entities.Article) error) error
+}
+
+// ArticlesPolicy describes the authorization rules we need to modify articles
+type ArticlesPolicy interface {
+	CanCreateArticle(ctx context.Context) (entities.Principal, error)
+	CanModifyArticle(ctx context.Context, article entities.Article) error
+}
+
+// ArticlesNotifier is told when the store wrote new events to the outbox, so they are relayed right away
+type ArticlesNotifier interface {
+	Notify()
 }
 
 // Articles is the usecase that has all the business logic about articles
 type Articles struct {
-	store ArticlesStore
+	store  ArticlesStore
+	policy ArticlesPolicy
+	relay  ArticlesNotifier
 }
 
 // NewArticles is the Articles constructor
-func NewArticles(as ArticlesStore) Articles {
-	return Articles{store: as}
+func NewArticles(as ArticlesStore, ap ArticlesPolicy, an ArticlesNotifier) Articles {
+	return Articles{store: as, policy: ap, relay: an}
 }
 
 // GetAll returns all articles
 func (a Articles) GetAll(ctx context.Context) ([]entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	articles, err := a.store.GetAll(ctx)
 	if err != nil {
@@ -54,7 +67,7 @@ func (a Articles) GetAll(ctx context.Context) ([]entities.Article, error) {
 
 // GetOne returns one article given an id
 func (a Articles) GetOne(ctx context.Context, id string) (entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	article, err := a.store.GetOne(ctx, id)
 	if err != nil {
@@ -67,17 +80,38 @@ func (a Articles) GetOne(ctx context.Context, id string) (entities.Article, erro
 		return entities.Article{}, fmt.Errorf("could not get article id %s: %w", id, err)
 	}
 
-	log.WithField("article", article).Info("article retrieved")
+	log.WithField("id", article.ID).Info("article retrieved")
 	return article, nil
 }
 
+// Find returns the articles that match the filter
+func (a Articles) Find(ctx context.Context, filter entities.ArticleFilter) ([]entities.Article, error) {
+	log := logging.FromContext(ctx)
+
+	articles, err := a.store.Find(ctx, filter)
+	if err != nil {
+		log.WithError(err).Error("could not find articles")
+		return nil, fmt.Errorf("could not find articles: %w", err)
+	}
+
+	log.WithField("articles", len(articles)).Debug("found articles")
+	return articles, nil
+}
+
 // Create creates an article
 func (a Articles) Create(ctx context.Context, article entities.Article) (entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
 
 	// example of business logic applied, which should only live in the usecase layer
 	if len(article.Content) > maxContentLen {
-		return entities.Article{}, fmt.Errorf("article content is longer than allowed")
+		return entities.Article{}, fmt.Errorf("article content is longer than %d characters: %w", maxContentLen, consts.ErrInvalidArgument)
+	}
+
+	// the author is whoever creates the article, the body can not impersonate another one
+	principal, err := a.policy.CanCreateArticle(ctx)
+	if err != nil {
+		log.WithError(err).Warn("not allowed to create article")
+		return entities.Article{}, fmt.Errorf("could not create article: %w", err)
 	}
 
 	id := uuid.New().String()
@@ -87,66 +121,81 @@ func (a Articles) Create(ctx context.Context, article entities.Article) (entitie
 		UpdatedAt: time.Now().UTC(),
 		Title:     article.Title,
 		Content:   article.Content,
-		Author:    article.Author,
+		Author:    principal.ID(),
 	}
 
 	created, err := a.store.Create(ctx, art)
 	if err != nil {
 		return entities.Article{}, fmt.Errorf("could not create article: %w", err)
 	}
-	log.WithField("article", created).Info("article created")
+	log.WithField("id", created.ID).Info("article created")
+	a.relay.Notify()
 	return created, nil
 }
 
 // Update updates the attributes of an article
 func (a Articles) Update(ctx context.Context, article entities.Article) (entities.Article, error) {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
+	log := logging.FromContext(ctx)
+
+	// the policy is checked on the article read in the transaction of the update, so it can't change in between
+	updated, err := a.store.Update(ctx, article.ID, func(toUpdate entities.Article) (entities.Article, error) {
+		log.WithField("id", article.ID).Debug("found article to update")
+		if err := a.policy.CanModifyArticle(ctx, toUpdate); err != nil {
+			log.WithError(err).WithField("id", article.ID).Warn("not allowed to update article")
+			return entities.Article{}, err
+		}
 
-	toUpdate, err := a.GetOne(ctx, article.ID)
+		// the author is kept, an article can not be transferred
+		toUpdate.Title = article.Title
+		toUpdate.Content = article.Content
+		toUpdate.UpdatedAt = time.Now().UTC()
+		return toUpdate, nil
+	})
 	if err != nil {
 		if errors.Is(err, consts.ErrEntityNotFound) {
 			log.WithError(err).WithField("id", article.ID).Warn("could not get article")
 			return entities.Article{}, fmt.Errorf("article id %s not found %w", article.ID, err)
 		}
+		if errors.Is(err, consts.ErrForbidden) || errors.Is(err, consts.ErrUnauthenticated) {
+			return entities.Article{}, err
+		}
 
-		log.WithError(err).WithField("id", article.ID).Error("could not get article")
-		return entities.Article{}, fmt.Errorf("could not get article id %s: %w", article.ID, err)
-	}
-	log.WithField("article", article).Debug("found article to update")
-	toUpdate.Title = article.Title
-	toUpdate.Content = article.Content
-	toUpdate.Author = article.Author
-	toUpdate.UpdatedAt = time.Now().UTC()
-
-	updated, err := a.store.Update(ctx, toUpdate)
-	if err != nil {
+		log.WithError(err).WithField("id", article.ID).Error("could not update article")
 		return entities.Article{}, fmt.Errorf("could not update article: %w", err)
 	}
-	log.WithField("article", updated).Info("article updated")
+	log.WithField("id", updated.ID).Info("article updated")
+	a.relay.Notify()
 
 	return updated, nil
 }
 
 // Delete removes an article
 func (a Articles) Delete(ctx context.Context, id string) error {
-	log := logrus.WithField("request_id", middlewares.GetRequestID(ctx))
-
-	toDelete, err := a.GetOne(ctx, id)
+	log := logging.FromContext(ctx)
+
+	// like Update, the policy is checked in the transaction of the delete
+	err := a.store.Delete(ctx, id, func(toDelete entities.Article) error {
+		log.WithField("id", toDelete.ID).Debug("found article to delete")
+		if err := a.policy.CanModifyArticle(ctx, toDelete); err != nil {
+			log.WithError(err).WithField("id", id).Warn("not allowed to delete article")
+			return err
+		}
+		return nil
+	})
 	if err != nil {
 		if errors.Is(err, consts.ErrEntityNotFound) {
 			log.WithError(err).WithField("id", id).Warn("could not get article")
 			return fmt.Errorf("article id %s not found %w", id, err)
 		}
+		if errors.Is(err, consts.ErrForbidden) || errors.Is(err, consts.ErrUnauthenticated) {
+			return err
+		}
 
-		log.WithError(err).WithField("id", id).Error("could not get article")
-		return fmt.Errorf("could not get article id %s: %w", id, err)
-	}
-	log.WithField("article", toDelete).Debug("found article to delete")
-
-	if err := a.store.Delete(ctx, id); err != nil {
+		log.WithError(err).WithField("id", id).Error("could not delete article")
 		return fmt.Errorf("could not delete article: %w", err)
 	}
 	log.WithField("id", id).Info("article deleted")
+	a.relay.Notify()
 
 	return nil
 }
@@ -2,11 +2,14 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases/mocks"
 	"github.com/stretchr/testify/assert"
 )
@@ -17,7 +20,8 @@ func TestArticles_Create(t *testing.T) {
 		mockStore func(m *mocks.MockArticlesStore)
 	}
 	type args struct {
-		article entities.Article
+		principal *entities.Principal
+		article   entities.Article
 	}
 	tests := []struct {
 		name    string
@@ -35,24 +39,77 @@ func TestArticles_Create(t *testing.T) {
 						Return(entities.Article{
 							Title:   "title",
 							Content: "content",
-							Author:  "author",
+							Author:  "jwt:author",
 						}, nil)
 				},
 			},
 			args: args{
+				principal: &entities.Principal{Subject: "author", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
 				article: entities.Article{
 					Title:   "title",
 					Content: "content",
-					Author:  "author",
+					Author:  "jwt:author",
 				},
 			},
 			want: entities.Article{
 				Title:   "title",
 				Content: "content",
-				Author:  "author",
+				Author:  "jwt:author",
 			},
 			wantErr: false,
 		},
+		{
+			name: "Success: Author is taken from the principal",
+			fields: fields{
+				mockStore: func(m *mocks.MockArticlesStore) {
+					m.EXPECT().
+						Create(gomock.Any(), authoredBy("jwt:author")).
+						Return(entities.Article{
+							Title:   "title",
+							Content: "content",
+							Author:  "jwt:author",
+						}, nil)
+				},
+			},
+			args: args{
+				principal: &entities.Principal{Subject: "author", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
+				article: entities.Article{
+					Title:   "title",
+					Content: "content",
+					Author:  "impersonated",
+				},
+			},
+			want: entities.Article{
+				Title:   "title",
+				Content: "content",
+				Author:  "jwt:author",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Failure: No principal",
+			args: args{
+				article: entities.Article{
+					Title:   "title",
+					Content: "content",
+					Author:  "jwt:author",
+				},
+			},
+			want:    entities.Article{},
+			wantErr: true,
+		},
+		{
+			name: "Failure: Reader can not create",
+			args: args{
+				principal: &entities.Principal{Subject: "reader", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleReader}},
+				article: entities.Article{
+					Title:   "title",
+					Content: "content",
+				},
+			},
+			want:    entities.Article{},
+			wantErr: true,
+		},
 		{
 			name: "Failure: Store returns error",
 			fields: fields{
@@ -63,10 +120,11 @@ func TestArticles_Create(t *testing.T) {
 				},
 			},
 			args: args{
+				principal: &entities.Principal{Subject: "author", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
 				article: entities.Article{
 					Title:   "title",
 					Content: "content",
-					Author:  "author",
+					Author:  "jwt:author",
 				},
 			},
 			want:    entities.Article{},
@@ -82,11 +140,23 @@ func TestArticles_Create(t *testing.T) {
 				tt.fields.mockStore(m)
 			}
 
+			n := mocks.NewMockArticlesNotifier(ctrl)
+			if !tt.wantErr {
+				n.EXPECT().Notify()
+			}
+
 			a := Articles{
-				store: m,
+				store:  m,
+				policy: NewPolicy(rolePermissions),
+				relay:  n,
+			}
+
+			ctx := context.Background()
+			if tt.args.principal != nil {
+				ctx = middlewares.WithPrincipal(ctx, *tt.args.principal)
 			}
 
-			got, err := a.Create(context.Background(), tt.args.article)
+			got, err := a.Create(ctx, tt.args.article)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Articles.Create() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -95,3 +165,188 @@ func TestArticles_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestArticles_Update(t *testing.T) {
+	stored := entities.Article{
+		ID:      "id",
+		Title:   "title",
+		Content: "content",
+		Author:  "jwt:author",
+	}
+	tests := []struct {
+		name      string
+		principal entities.Principal
+		mockStore func(m *mocks.MockArticlesStore)
+		wantErr   error
+	}{
+		{
+			name:      "Success: Author updates own article",
+			principal: entities.Principal{Subject: "author", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Update(gomock.Any(), "id", gomock.Any()).DoAndReturn(modifying(stored))
+			},
+		},
+		{
+			name:      "Success: Editor updates any article",
+			principal: entities.Principal{Subject: "editor", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleEditor}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Update(gomock.Any(), "id", gomock.Any()).DoAndReturn(modifying(stored))
+			},
+		},
+		{
+			name:      "Failure: Another author",
+			principal: entities.Principal{Subject: "other", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Update(gomock.Any(), "id", gomock.Any()).DoAndReturn(modifying(stored))
+			},
+			wantErr: consts.ErrForbidden,
+		},
+		{
+			name:      "Failure: API key named like the author",
+			principal: entities.Principal{Subject: "author", Kind: consts.PrincipalAPIKey, Roles: []string{consts.RoleAuthor}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Update(gomock.Any(), "id", gomock.Any()).DoAndReturn(modifying(stored))
+			},
+			wantErr: consts.ErrForbidden,
+		},
+		{
+			name:      "Failure: Article not found",
+			principal: entities.Principal{Subject: "author", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Update(gomock.Any(), "id", gomock.Any()).Return(entities.Article{}, consts.ErrEntityNotFound)
+			},
+			wantErr: consts.ErrEntityNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			m := mocks.NewMockArticlesStore(ctrl)
+			tt.mockStore(m)
+
+			n := mocks.NewMockArticlesNotifier(ctrl)
+			if tt.wantErr == nil {
+				n.EXPECT().Notify()
+			}
+
+			a := Articles{
+				store:  m,
+				policy: NewPolicy(rolePermissions),
+				relay:  n,
+			}
+
+			ctx := middlewares.WithPrincipal(context.Background(), tt.principal)
+			got, err := a.Update(ctx, entities.Article{ID: "id", Title: "new title", Author: "impersonated"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Articles.Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				// the author is kept
+				assert.Equal(t, "new title", got.Title)
+				assert.Equal(t, "jwt:author", got.Author)
+			}
+		})
+	}
+}
+
+func TestArticles_Delete(t *testing.T) {
+	stored := entities.Article{ID: "id", Author: "jwt:author"}
+	tests := []struct {
+		name      string
+		principal entities.Principal
+		mockStore func(m *mocks.MockArticlesStore)
+		wantErr   error
+	}{
+		{
+			name:      "Success: Admin deletes any article",
+			principal: entities.Principal{Subject: "admin", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAdmin}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Delete(gomock.Any(), "id", gomock.Any()).DoAndReturn(checking(stored))
+			},
+		},
+		{
+			name:      "Failure: Another author",
+			principal: entities.Principal{Subject: "other", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Delete(gomock.Any(), "id", gomock.Any()).DoAndReturn(checking(stored))
+			},
+			wantErr: consts.ErrForbidden,
+		},
+		{
+			name:      "Failure: Article not found",
+			principal: entities.Principal{Subject: "admin", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAdmin}},
+			mockStore: func(m *mocks.MockArticlesStore) {
+				m.EXPECT().Delete(gomock.Any(), "id", gomock.Any()).Return(consts.ErrEntityNotFound)
+			},
+			wantErr: consts.ErrEntityNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			m := mocks.NewMockArticlesStore(ctrl)
+			tt.mockStore(m)
+
+			n := mocks.NewMockArticlesNotifier(ctrl)
+			if tt.wantErr == nil {
+				n.EXPECT().Notify()
+			}
+
+			a := Articles{
+				store:  m,
+				policy: NewPolicy(rolePermissions),
+				relay:  n,
+			}
+
+			ctx := middlewares.WithPrincipal(context.Background(), tt.principal)
+			if err := a.Delete(ctx, "id"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Articles.Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// modifying is a store Update that calls modify with the stored article, as the store does in its transaction
+func modifying(stored entities.Article) func(context.Context, string, func(entities.Article) (entities.Article, error)) (entities.Article, error) {
+	return func(ctx context.Context, id string, modify func(entities.Article) (entities.Article, error)) (entities.Article, error) {
+		return modify(stored)
+	}
+}
+
+// checking is a store Delete that calls check with the stored article, as the store does in its transaction
+func checking(stored entities.Article) func(context.Context, string, func(entities.Article) error) error {
+	return func(ctx context.Context, id string, check func(entities.Article) error) error {
+		return check(stored)
+	}
+}
+
+// rolePermissions mirrors the permissions seeded in the database
+var rolePermissions = staticPermissions{
+	consts.RoleAuthor: {consts.ScopeArticlesRead, consts.ScopeArticlesWrite},
+	consts.RoleEditor: {consts.ScopeArticlesRead, consts.ScopeArticlesWrite, consts.ScopeArticlesEditAny},
+	consts.RoleAdmin:  {consts.ScopeArticlesRead, consts.ScopeArticlesWrite, consts.ScopeArticlesEditAny},
+}
+
+type staticPermissions map[string][]string
+
+func (s staticPermissions) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, s[role]...)
+	}
+	return permissions, nil
+}
+
+// authoredBy matches the articles written by author
+type authoredBy string
+
+func (a authoredBy) Matches(x interface{}) bool {
+	article, ok := x.(entities.Article)
+	return ok && article.Author == string(a)
+}
+
+func (a authoredBy) String() string {
+	return "is authored by " + string(a)
+}
@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/nachogoca/golang-example-rest-api-layout/internal/usecases (interfaces: APIKeysStore)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	reflect "reflect"
+)
+
+// MockAPIKeysStore is a mock of APIKeysStore interface
+type MockAPIKeysStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeysStoreMockRecorder
+}
+
+// MockAPIKeysStoreMockRecorder is the mock recorder for MockAPIKeysStore
+type MockAPIKeysStoreMockRecorder struct {
+	mock *MockAPIKeysStore
+}
+
+// NewMockAPIKeysStore creates a new mock instance
+func NewMockAPIKeysStore(ctrl *gomock.Controller) *MockAPIKeysStore {
+	mock := &MockAPIKeysStore{ctrl: ctrl}
+	mock.recorder = &MockAPIKeysStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAPIKeysStore) EXPECT() *MockAPIKeysStoreMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockAPIKeysStore) Create(arg0 context.Context, arg1 entities.APIKey) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockAPIKeysStoreMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPIKeysStore)(nil).Create), arg0, arg1)
+}
+
+// GetAll mocks base method
+func (m *MockAPIKeysStore) GetAll(arg0 context.Context) ([]entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", arg0)
+	ret0, _ := ret[0].([]entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll
+func (mr *MockAPIKeysStoreMockRecorder) GetAll(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockAPIKeysStore)(nil).GetAll), arg0)
+}
+
+// GetByHash mocks base method
+func (m *MockAPIKeysStore) GetByHash(arg0 context.Context, arg1 string) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByHash", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByHash indicates an expected call of GetByHash
+func (mr *MockAPIKeysStoreMockRecorder) GetByHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHash", reflect.TypeOf((*MockAPIKeysStore)(nil).GetByHash), arg0, arg1)
+}
+
+// GetByName mocks base method
+func (m *MockAPIKeysStore) GetByName(arg0 context.Context, arg1 string) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName
+func (mr *MockAPIKeysStoreMockRecorder) GetByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockAPIKeysStore)(nil).GetByName), arg0, arg1)
+}
+
+// GetOne mocks base method
+func (m *MockAPIKeysStore) GetOne(arg0 context.Context, arg1 string) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOne", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOne indicates an expected call of GetOne
+func (mr *MockAPIKeysStoreMockRecorder) GetOne(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOne", reflect.TypeOf((*MockAPIKeysStore)(nil).GetOne), arg0, arg1)
+}
+
+// Update mocks base method
+func (m *MockAPIKeysStore) Update(arg0 context.Context, arg1 entities.APIKey) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *MockAPIKeysStoreMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockAPIKeysStore)(nil).Update), arg0, arg1)
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/nachogoca/golang-example-rest-api-layout/internal/usecases (interfaces: ArticlesStore)
+// Source: github.com/nachogoca/golang-example-rest-api-layout/internal/usecases (interfaces: ArticlesStore,ArticlesNotifier)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -50,17 +50,32 @@ func (mr *MockArticlesStoreMockRecorder) Create(arg0, arg1 interface{}) *gomock.
 }
 
 // Delete mocks base method
-func (m *MockArticlesStore) Delete(arg0 context.Context, arg1 string) error {
+func (m *MockArticlesStore) Delete(arg0 context.Context, arg1 string, arg2 func(entities.Article) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete
-func (mr *MockArticlesStoreMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockArticlesStoreMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockArticlesStore)(nil).Delete), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockArticlesStore)(nil).Delete), arg0, arg1, arg2)
+}
+
+// Find mocks base method
+func (m *MockArticlesStore) Find(arg0 context.Context, arg1 entities.ArticleFilter) ([]entities.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].([]entities.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *MockArticlesStoreMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockArticlesStore)(nil).Find), arg0, arg1)
 }
 
 // GetAll mocks base method
@@ -94,16 +109,51 @@ func (mr *MockArticlesStoreMockRecorder) GetOne(arg0, arg1 interface{}) *gomock.
 }
 
 // Update mocks base method
-func (m *MockArticlesStore) Update(arg0 context.Context, arg1 entities.Article) (entities.Article, error) {
+func (m *MockArticlesStore) Update(arg0 context.Context, arg1 string, arg2 func(entities.Article) (entities.Article, error)) (entities.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
 	ret0, _ := ret[0].(entities.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update
-func (mr *MockArticlesStoreMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockArticlesStoreMockRecorder) Update(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockArticlesStore)(nil).Update), arg0, arg1, arg2)
+}
+
+// MockArticlesNotifier is a mock of ArticlesNotifier interface
+type MockArticlesNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockArticlesNotifierMockRecorder
+}
+
+// MockArticlesNotifierMockRecorder is the mock recorder for MockArticlesNotifier
+type MockArticlesNotifierMockRecorder struct {
+	mock *MockArticlesNotifier
+}
+
+// NewMockArticlesNotifier creates a new mock instance
+func NewMockArticlesNotifier(ctrl *gomock.Controller) *MockArticlesNotifier {
+	mock := &MockArticlesNotifier{ctrl: ctrl}
+	mock.recorder = &MockArticlesNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockArticlesNotifier) EXPECT() *MockArticlesNotifierMockRecorder {
+	return m.recorder
+}
+
+// Notify mocks base method
+func (m *MockArticlesNotifier) Notify() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Notify")
+}
+
+// Notify indicates an expected call of Notify
+func (mr *MockArticlesNotifierMockRecorder) Notify() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockArticlesStore)(nil).Update), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockArticlesNotifier)(nil).Notify))
 }
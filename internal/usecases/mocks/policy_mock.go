@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/nachogoca/golang-example-rest-api-layout/internal/usecases (interfaces: PermissionsStore)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockPermissionsStore is a mock of PermissionsStore interface
+type MockPermissionsStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockPermissionsStoreMockRecorder
+}
+
+// MockPermissionsStoreMockRecorder is the mock recorder for MockPermissionsStore
+type MockPermissionsStoreMockRecorder struct {
+	mock *MockPermissionsStore
+}
+
+// NewMockPermissionsStore creates a new mock instance
+func NewMockPermissionsStore(ctrl *gomock.Controller) *MockPermissionsStore {
+	mock := &MockPermissionsStore{ctrl: ctrl}
+	mock.recorder = &MockPermissionsStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockPermissionsStore) EXPECT() *MockPermissionsStoreMockRecorder {
+	return m.recorder
+}
+
+// GetPermissions mocks base method
+func (m *MockPermissionsStore) GetPermissions(arg0 context.Context, arg1 []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissions", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissions indicates an expected call of GetPermissions
+func (mr *MockPermissionsStoreMockRecorder) GetPermissions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissions", reflect.TypeOf((*MockPermissionsStore)(nil).GetPermissions), arg0, arg1)
+}
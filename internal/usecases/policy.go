@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+)
+
+//go:generate mockgen -destination=./mocks/policy_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/usecases PermissionsStore
+
+// PermissionsStore describes the function we need from store layer to resolve the permissions of roles
+type PermissionsStore interface {
+	GetPermissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// Policy holds the authorization rules, so they live in one place instead of in each usecase
+// A principal is granted its own scopes plus the permissions of its roles
+type Policy struct {
+	store PermissionsStore
+}
+
+// NewPolicy is the Policy constructor
+func NewPolicy(ps PermissionsStore) Policy {
+	return Policy{store: ps}
+}
+
+// Permissions returns the permissions granted to the roles
+func (p Policy) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions, err := p.store.GetPermissions(ctx, roles)
+	if err != nil {
+		return nil, fmt.Errorf("could not get permissions of roles %v: %w", roles, err)
+	}
+	return permissions, nil
+}
+
+// Can checks that the principal of the context was granted the scope, by itself or through its roles
+func (p Policy) Can(ctx context.Context, scope string) error {
+	principal, granted, err := p.grants(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !granted[scope] {
+		return fmt.Errorf("%s was not granted %s: %w", principal.Subject, scope, consts.ErrForbidden)
+	}
+	return nil
+}
+
+// CanCreateArticle returns the principal of the context if it can write articles
+func (p Policy) CanCreateArticle(ctx context.Context) (entities.Principal, error) {
+	principal, granted, err := p.grants(ctx)
+	if err != nil {
+		return entities.Principal{}, err
+	}
+
+	if !granted[consts.ScopeArticlesWrite] {
+		return entities.Principal{}, fmt.Errorf("%s can not write articles: %w", principal.Subject, consts.ErrForbidden)
+	}
+	return principal, nil
+}
+
+// CanModifyArticle checks that the principal of the context can update or delete the article
+// Authors can modify their own articles, the ones granted articles:edit_any can modify any.
+// The author is the ID of the principal, so an api key named like a user is not that user
+func (p Policy) CanModifyArticle(ctx context.Context, article entities.Article) error {
+	principal, granted, err := p.grants(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !granted[consts.ScopeArticlesWrite] {
+		return fmt.Errorf("%s can not write articles: %w", principal.Subject, consts.ErrForbidden)
+	}
+	if principal.ID() != article.Author && !granted[consts.ScopeArticlesEditAny] {
+		return fmt.Errorf("%s is not the author of article id %s: %w", principal.Subject, article.ID, consts.ErrForbidden)
+	}
+	return nil
+}
+
+// grants returns the principal of the context and everything it was granted
+func (p Policy) grants(ctx context.Context) (entities.Principal, map[string]bool, error) {
+	principal, ok := middlewares.GetPrincipal(ctx)
+	if !ok {
+		return entities.Principal{}, nil, fmt.Errorf("no principal in context: %w", consts.ErrUnauthenticated)
+	}
+
+	permissions, err := p.Permissions(ctx, principal.Roles)
+	if err != nil {
+		return entities.Principal{}, nil, err
+	}
+
+	granted := make(map[string]bool, len(principal.Scopes)+len(permissions))
+	for _, scope := range principal.Scopes {
+		granted[scope] = true
+	}
+	for _, permission := range permissions {
+		granted[permission] = true
+	}
+	return principal, granted, nil
+}
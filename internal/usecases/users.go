@@ -0,0 +1,194 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+)
+
+const (
+	minPasswordLen = 8
+	// bcrypt ignores everything after 72 bytes
+	maxPasswordLen = 72
+)
+
+// dummyHash is compared when the username does not exist, so both cases take the same time
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+//go:generate mockgen -destination=./mocks/users_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/usecases UsersStore,TokenIssuer
+
+// UsersStore describes all the functions we need from store layer to manage users
+type UsersStore interface {
+	GetAll(ctx context.Context) ([]entities.User, error)
+	GetOne(ctx context.Context, id string) (entities.User, error)
+	GetByUsername(ctx context.Context, username string) (entities.User, error)
+	Create(ctx context.Context, user entities.User) (entities.User, error)
+	UpdateRoles(ctx context.Context, user entities.User) (entities.User, error)
+}
+
+// TokenIssuer describes the function we need to issue access tokens for a principal
+type TokenIssuer interface {
+	Issue(ctx context.Context, principal entities.Principal) (entities.Token, error)
+}
+
+// Users is the usecase that manages user accounts and logs them in
+type Users struct {
+	store  UsersStore
+	issuer TokenIssuer
+}
+
+// NewUsers is the Users constructor
+func NewUsers(us UsersStore, ti TokenIssuer) Users {
+	return Users{store: us, issuer: ti}
+}
+
+// GetAll returns all users
+func (u Users) GetAll(ctx context.Context) ([]entities.User, error) {
+	log := logging.FromContext(ctx)
+
+	users, err := u.store.GetAll(ctx)
+	if err != nil {
+		log.WithError(err).Error("could not get all users")
+		return nil, fmt.Errorf("could not get all users: %w", err)
+	}
+
+	log.WithField("users", len(users)).Info("found users")
+	return users, nil
+}
+
+// Create creates a user with the password hashed with bcrypt
+func (u Users) Create(ctx context.Context, user entities.User, password string) (entities.User, error) {
+	log := logging.FromContext(ctx)
+
+	if user.Username == "" {
+		return entities.User{}, fmt.Errorf("username is required: %w", consts.ErrInvalidArgument)
+	}
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		return entities.User{}, fmt.Errorf("password must have between %d and %d characters: %w", minPasswordLen, maxPasswordLen, consts.ErrInvalidArgument)
+	}
+	if err := validateRoles(user.Roles); err != nil {
+		return entities.User{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return entities.User{}, stacktrace.Errorf("could not hash password: %w", err)
+	}
+
+	now := time.Now().UTC()
+	created, err := u.store.Create(ctx, entities.User{
+		ID:           uuid.New().String(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Username:     user.Username,
+		Roles:        user.Roles,
+		PasswordHash: string(hash),
+	})
+	if err != nil {
+		return entities.User{}, fmt.Errorf("could not create user: %w", err)
+	}
+	log.WithField("id", created.ID).WithField("username", created.Username).Info("user created")
+
+	return created, nil
+}
+
+// UpdateRoles replaces the roles of a user
+func (u Users) UpdateRoles(ctx context.Context, user entities.User) (entities.User, error) {
+	log := logging.FromContext(ctx)
+
+	if err := validateRoles(user.Roles); err != nil {
+		return entities.User{}, err
+	}
+
+	toUpdate, err := u.store.GetOne(ctx, user.ID)
+	if err != nil {
+		return entities.User{}, fmt.Errorf("could not get user id %s: %w", user.ID, err)
+	}
+	toUpdate.Roles = user.Roles
+	toUpdate.UpdatedAt = time.Now().UTC()
+
+	updated, err := u.store.UpdateRoles(ctx, toUpdate)
+	if err != nil {
+		return entities.User{}, fmt.Errorf("could not update user roles: %w", err)
+	}
+	log.WithField("id", updated.ID).WithField("roles", updated.Roles).Info("user roles updated")
+
+	return updated, nil
+}
+
+// Login checks the credentials and issues a token with the user roles. The token has no scopes, the Policy
+// resolves the permissions of the roles on each request, so changing the roles applies right away
+func (u Users) Login(ctx context.Context, credentials entities.Credentials) (entities.Token, error) {
+	log := logging.FromContext(ctx)
+
+	user, err := u.store.GetByUsername(ctx, credentials.Username)
+	if err != nil {
+		if !errors.Is(err, consts.ErrEntityNotFound) {
+			return entities.Token{}, fmt.Errorf("could not get user: %w", err)
+		}
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(credentials.Password))
+		log.WithField("username", credentials.Username).Warn("login with unknown username")
+		return entities.Token{}, fmt.Errorf("invalid credentials: %w", consts.ErrUnauthenticated)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password)); err != nil {
+		log.WithField("username", credentials.Username).Warn("login with wrong password")
+		return entities.Token{}, fmt.Errorf("invalid credentials: %w", consts.ErrUnauthenticated)
+	}
+
+	token, err := u.issuer.Issue(ctx, entities.Principal{
+		Subject: user.Username,
+		Kind:    consts.PrincipalUser,
+		Roles:   user.Roles,
+	})
+	if err != nil {
+		return entities.Token{}, stacktrace.Errorf("could not issue token: %w", err)
+	}
+	log.WithField("id", user.ID).Info("user logged in")
+
+	return token, nil
+}
+
+// TokenAuthenticator describes the function we need to verify the tokens of the users
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (entities.Principal, error)
+}
+
+// UserTokens authenticates bearer tokens, giving the users the roles they have now instead of the ones
+// of their token, so the roles removed with UpdateRoles are not granted until the token expires
+type UserTokens struct {
+	users Users
+	auth  TokenAuthenticator
+}
+
+// Tokens returns the UserTokens of the tokens verified by auth
+func (u Users) Tokens(auth TokenAuthenticator) UserTokens {
+	return UserTokens{users: u, auth: auth}
+}
+
+// Authenticate verifies the token, and replaces the roles of users with their current ones
+func (t UserTokens) Authenticate(ctx context.Context, token string) (entities.Principal, error) {
+	principal, err := t.auth.Authenticate(ctx, token)
+	if err != nil || principal.Kind != consts.PrincipalUser {
+		return principal, err
+	}
+
+	user, err := t.users.store.GetByUsername(ctx, principal.Subject)
+	if errors.Is(err, consts.ErrEntityNotFound) {
+		return entities.Principal{}, fmt.Errorf("user %s does not exist: %w", principal.Subject, consts.ErrUnauthenticated)
+	}
+	if err != nil {
+		return entities.Principal{}, fmt.Errorf("could not get user %s: %w", principal.Subject, err)
+	}
+	principal.Roles = user.Roles
+	return principal, nil
+}
@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases/mocks"
+)
+
+func TestUsers_Login(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	user := entities.User{ID: "id", Username: "alice", Roles: []string{consts.RoleAuthor}, PasswordHash: string(hash)}
+
+	tests := []struct {
+		name        string
+		credentials entities.Credentials
+		mock        func(s *mocks.MockUsersStore, i *mocks.MockTokenIssuer)
+		want        entities.Token
+		wantErr     error
+	}{
+		{
+			name:        "Success: Token with the roles and no scopes",
+			credentials: entities.Credentials{Username: "alice", Password: "password"},
+			mock: func(s *mocks.MockUsersStore, i *mocks.MockTokenIssuer) {
+				s.EXPECT().GetByUsername(gomock.Any(), "alice").Return(user, nil)
+				i.EXPECT().Issue(gomock.Any(), entities.Principal{
+					Subject: "alice",
+					Kind:    consts.PrincipalUser,
+					Roles:   []string{consts.RoleAuthor},
+				}).Return(entities.Token{AccessToken: "token"}, nil)
+			},
+			want: entities.Token{AccessToken: "token"},
+		},
+		{
+			name:        "Failure: Wrong password",
+			credentials: entities.Credentials{Username: "alice", Password: "wrong"},
+			mock: func(s *mocks.MockUsersStore, i *mocks.MockTokenIssuer) {
+				s.EXPECT().GetByUsername(gomock.Any(), "alice").Return(user, nil)
+			},
+			wantErr: consts.ErrUnauthenticated,
+		},
+		{
+			name:        "Failure: Unknown username",
+			credentials: entities.Credentials{Username: "bob", Password: "password"},
+			mock: func(s *mocks.MockUsersStore, i *mocks.MockTokenIssuer) {
+				s.EXPECT().GetByUsername(gomock.Any(), "bob").Return(entities.User{}, consts.ErrEntityNotFound)
+			},
+			wantErr: consts.ErrUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			s := mocks.NewMockUsersStore(ctrl)
+			i := mocks.NewMockTokenIssuer(ctrl)
+			tt.mock(s, i)
+
+			u := Users{
+				store:  s,
+				issuer: i,
+			}
+
+			got, err := u.Login(context.Background(), tt.credentials)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Users.Login() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// tokens authenticates each token as the principal of the map
+type tokens map[string]entities.Principal
+
+func (t tokens) Authenticate(ctx context.Context, token string) (entities.Principal, error) {
+	principal, ok := t[token]
+	if !ok {
+		return entities.Principal{}, consts.ErrUnauthenticated
+	}
+	return principal, nil
+}
+
+func TestUserTokens_Authenticate(t *testing.T) {
+	auth := tokens{
+		"user":    {Subject: "alice", Kind: consts.PrincipalUser, Roles: []string{consts.RoleAuthor}},
+		"deleted": {Subject: "bob", Kind: consts.PrincipalUser, Roles: []string{consts.RoleAuthor}},
+		"idp":     {Subject: "alice", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		mock    func(s *mocks.MockUsersStore)
+		want    entities.Principal
+		wantErr error
+	}{
+		{
+			name:  "Success: Roles removed since the login are not granted",
+			token: "user",
+			mock: func(s *mocks.MockUsersStore) {
+				s.EXPECT().GetByUsername(gomock.Any(), "alice").Return(entities.User{Username: "alice", Roles: []string{consts.RoleReader}}, nil)
+			},
+			want: entities.Principal{Subject: "alice", Kind: consts.PrincipalUser, Roles: []string{consts.RoleReader}},
+		},
+		{
+			name:  "Success: Tokens of other issuers keep their roles",
+			token: "idp",
+			mock:  func(s *mocks.MockUsersStore) {},
+			want:  entities.Principal{Subject: "alice", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleAuthor}},
+		},
+		{
+			name:  "Failure: Deleted user",
+			token: "deleted",
+			mock: func(s *mocks.MockUsersStore) {
+				s.EXPECT().GetByUsername(gomock.Any(), "bob").Return(entities.User{}, consts.ErrEntityNotFound)
+			},
+			wantErr: consts.ErrUnauthenticated,
+		},
+		{
+			name:    "Failure: Invalid token",
+			token:   "invalid",
+			mock:    func(s *mocks.MockUsersStore) {},
+			wantErr: consts.ErrUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			s := mocks.NewMockUsersStore(ctrl)
+			tt.mock(s)
+
+			got, err := NewUsers(s, nil).Tokens(auth).Authenticate(context.Background(), tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UserTokens.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/stacktrace"
+	v1 "github.com/nachogoca/golang-example-rest-api-layout/internal/transports/v1"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	webhookSecretLen    = 32
+	minWebhookSecretLen = 16
+
+	// maxDeliveries is the number of deliveries shown in the log of a webhook
+	maxDeliveries = 100
+	// deliveryBatch is the number of deliveries sent at once
+	deliveryBatch = 20
+	// maxErrorLen truncates the errors recorded in the delivery log
+	maxErrorLen = 500
+)
+
+//go:generate mockgen -destination=./mocks/webhooks_mock.go -package=mocks github.com/nachogoca/golang-example-rest-api-layout/internal/usecases WebhooksStore,WebhookSender
+
+// WebhooksStore describes all the functions we need from store layer to manage webhooks and their deliveries
+type WebhooksStore interface {
+	GetAll(ctx context.Context) ([]entities.Webhook, error)
+	GetOne(ctx context.Context, id string) (entities.Webhook, error)
+	Create(ctx context.Context, hook entities.Webhook) (entities.Webhook, error)
+	Update(ctx context.Context, hook entities.Webhook) (entities.Webhook, error)
+	Delete(ctx context.Context, id string) error
+	Enqueue(ctx context.Context, eventID int64, deliveries []entities.WebhookDelivery) error
+	CreateDelivery(ctx context.Context, d entities.WebhookDelivery) (entities.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id string) (entities.WebhookDelivery, error)
+	Deliveries(ctx context.Context, webhookID string, limit int) ([]entities.WebhookDelivery, error)
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entities.WebhookDelivery, error)
+	SaveAttempt(ctx context.Context, d entities.WebhookDelivery) error
+}
+
+// WebhookSender posts a delivery to a webhook, and returns the status code of the response
+// an error means there was no response. CheckURL fails for the URLs it doesn't send deliveries to
+type WebhookSender interface {
+	Send(ctx context.Context, hook entities.Webhook, d entities.WebhookDelivery) (int, error)
+	CheckURL(ctx context.Context, rawURL string) error
+}
+
+// WebhookRetries chooses when failed deliveries are retried
+type WebhookRetries struct {
+	// MaxAttempts is the number of attempts before a delivery fails for good
+	MaxAttempts int
+	// BaseDelay is the wait after the first failed attempt, it doubles after each one up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Lease is how long a delivery that is being sent is hidden from the other workers
+	// it must be longer than the send timeout
+	Lease time.Duration
+}
+
+// Webhooks is the usecase that manages the webhooks, and queues and sends their deliveries
+type Webhooks struct {
+	store   WebhooksStore
+	sender  WebhookSender
+	retries WebhookRetries
+}
+
+// NewWebhooks is the Webhooks constructor
+func NewWebhooks(ws WebhooksStore, sender WebhookSender, retries WebhookRetries) Webhooks {
+	return Webhooks{store: ws, sender: sender, retries: retries}
+}
+
+// GetAll returns all webhooks, without their secret
+func (w Webhooks) GetAll(ctx context.Context) ([]entities.Webhook, error) {
+	log := logging.FromContext(ctx)
+
+	hooks, err := w.store.GetAll(ctx)
+	if err != nil {
+		log.WithError(err).Error("could not get all webhooks")
+		return nil, fmt.Errorf("could not get all webhooks: %w", err)
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	log.WithField("webhooks", len(hooks)).Info("found webhooks")
+	return hooks, nil
+}
+
+// GetOne returns one webhook, without its secret
+func (w Webhooks) GetOne(ctx context.Context, id string) (entities.Webhook, error) {
+	hook, err := w.store.GetOne(ctx, id)
+	if err != nil {
+		return entities.Webhook{}, fmt.Errorf("could not get webhook id %s: %w", id, err)
+	}
+
+	hook.Secret = ""
+	return hook, nil
+}
+
+// Create subscribes a URL to the article events, a secret is generated if none is given
+// The returned entity is the only one that carries the secret
+func (w Webhooks) Create(ctx context.Context, hook entities.Webhook) (entities.Webhook, error) {
+	log := logging.FromContext(ctx)
+
+	if err := w.validate(ctx, hook); err != nil {
+		return entities.Webhook{}, err
+	}
+
+	secret := hook.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateWebhookSecret(); err != nil {
+			return entities.Webhook{}, stacktrace.Errorf("could not generate webhook secret: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	created, err := w.store.Create(ctx, entities.Webhook{
+		ID:        uuid.New().String(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		URL:       hook.URL,
+		Events:    hook.Events,
+		Secret:    secret,
+	})
+	if err != nil {
+		return entities.Webhook{}, fmt.Errorf("could not create webhook: %w", err)
+	}
+	log.WithField("id", created.ID).WithField("url", created.URL).Info("webhook created")
+
+	return created, nil
+}
+
+// Update replaces the URL and the events of a webhook, and its secret when one is given
+// Queued deliveries are sent to the new URL
+func (w Webhooks) Update(ctx context.Context, hook entities.Webhook) (entities.Webhook, error) {
+	log := logging.FromContext(ctx)
+
+	if err := w.validate(ctx, hook); err != nil {
+		return entities.Webhook{}, err
+	}
+
+	toUpdate, err := w.store.GetOne(ctx, hook.ID)
+	if err != nil {
+		return entities.Webhook{}, fmt.Errorf("could not get webhook id %s: %w", hook.ID, err)
+	}
+
+	toUpdate.URL = hook.URL
+	toUpdate.Events = hook.Events
+	if hook.Secret != "" {
+		toUpdate.Secret = hook.Secret
+	}
+	toUpdate.UpdatedAt = time.Now().UTC()
+
+	updated, err := w.store.Update(ctx, toUpdate)
+	if err != nil {
+		return entities.Webhook{}, fmt.Errorf("could not update webhook: %w", err)
+	}
+	log.WithField("id", updated.ID).Info("webhook updated")
+
+	updated.Secret = ""
+	return updated, nil
+}
+
+// Delete removes a webhook and its deliveries
+func (w Webhooks) Delete(ctx context.Context, id string) error {
+	if err := w.store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("could not delete webhook id %s: %w", id, err)
+	}
+	logging.FromContext(ctx).WithField("id", id).Info("webhook deleted")
+	return nil
+}
+
+// Deliveries returns the last deliveries of a webhook, newest first
+func (w Webhooks) Deliveries(ctx context.Context, webhookID string) ([]entities.WebhookDelivery, error) {
+	if _, err := w.store.GetOne(ctx, webhookID); err != nil {
+		return nil, fmt.Errorf("could not get webhook id %s: %w", webhookID, err)
+	}
+
+	deliveries, err := w.store.Deliveries(ctx, webhookID, maxDeliveries)
+	if err != nil {
+		return nil, fmt.Errorf("could not get deliveries of webhook id %s: %w", webhookID, err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues a new delivery with the payload of a previous one, sent on the next check of the queue
+func (w Webhooks) Redeliver(ctx context.Context, webhookID, deliveryID string) (entities.WebhookDelivery, error) {
+	log := logging.FromContext(ctx)
+
+	previous, err := w.store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return entities.WebhookDelivery{}, fmt.Errorf("could not get webhook delivery id %s: %w", deliveryID, err)
+	}
+	if previous.WebhookID != webhookID {
+		return entities.WebhookDelivery{}, fmt.Errorf("delivery id %s is not of webhook id %s: %w", deliveryID, webhookID, consts.ErrEntityNotFound)
+	}
+
+	created, err := w.store.CreateDelivery(ctx, newDelivery(webhookID, previous.EventID, previous.EventType, previous.Payload))
+	if err != nil {
+		return entities.WebhookDelivery{}, fmt.Errorf("could not create webhook delivery: %w", err)
+	}
+	log.WithField("id", created.ID).WithField("previous", deliveryID).Info("webhook delivery queued again")
+
+	return created, nil
+}
+
+// Enqueue queues a delivery of the event for each webhook subscribed to its type
+// An event that was already queued is ignored, so it can be relayed more than once
+func (w Webhooks) Enqueue(ctx context.Context, event entities.ArticleEvent) error {
+	hooks, err := w.store.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get all webhooks: %w", err)
+	}
+
+	payload, err := json.Marshal(v1.NewEvent(event))
+	if err != nil {
+		return stacktrace.Errorf("could not encode article event: %w", err)
+	}
+
+	var deliveries []entities.WebhookDelivery
+	for _, hook := range hooks {
+		if contains(hook.Events, event.Type) {
+			deliveries = append(deliveries, newDelivery(hook.ID, event.ID, event.Type, payload))
+		}
+	}
+
+	if err := w.store.Enqueue(ctx, event.ID, deliveries); err != nil {
+		return fmt.Errorf("could not queue deliveries of article event %d: %w", event.ID, err)
+	}
+	logging.FromContext(ctx).WithField("event", event.ID).WithField("deliveries", len(deliveries)).Debug("article event queued")
+	return nil
+}
+
+// DeliverDue sends the deliveries that are due, and returns how many were sent
+// Failed attempts are retried with an exponential backoff, until MaxAttempts
+func (w Webhooks) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	deliveries, err := w.store.Claim(ctx, now, now.Add(w.retries.Lease), deliveryBatch)
+	if err != nil {
+		return 0, fmt.Errorf("could not claim webhook deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d entities.WebhookDelivery) {
+			defer wg.Done()
+			w.deliver(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver sends a delivery and saves the result of the attempt
+func (w Webhooks) deliver(ctx context.Context, d entities.WebhookDelivery) {
+	log := logging.FromContext(ctx).WithField("delivery", d.ID).WithField("webhook", d.WebhookID)
+
+	hook, err := w.store.GetOne(ctx, d.WebhookID)
+	if err != nil {
+		// the lease ends and the delivery is tried again, unless the webhook was deleted with it
+		log.WithError(err).Warn("could not get webhook of delivery")
+		return
+	}
+
+	status, err := w.sender.Send(ctx, hook, d)
+	if ctx.Err() != nil {
+		// stopped while sending, the attempt is not counted and it's retried once the lease ends
+		return
+	}
+
+	d.Attempts++
+	d.UpdatedAt = time.Now().UTC()
+	d.ResponseStatus = status
+	d.Error = ""
+	switch {
+	case err != nil:
+		d.Error = err.Error()
+	case status < 200 || status > 299:
+		d.Error = fmt.Sprintf("webhook responded with status %d", status)
+	}
+	if len(d.Error) > maxErrorLen {
+		d.Error = d.Error[:maxErrorLen]
+	}
+
+	switch {
+	case d.Error == "":
+		d.Status = consts.DeliverySucceeded
+		d.NextAttemptAt = d.UpdatedAt
+	case d.Attempts >= w.retries.MaxAttempts:
+		d.Status = consts.DeliveryFailed
+		d.NextAttemptAt = d.UpdatedAt
+	default:
+		d.NextAttemptAt = d.UpdatedAt.Add(w.retries.backoff(d.Attempts))
+	}
+
+	log = log.WithField("attempts", d.Attempts).WithField("status", d.Status)
+	if err := w.store.SaveAttempt(ctx, d); err != nil {
+		if !errors.Is(err, consts.ErrEntityNotFound) {
+			log.WithError(err).Error("could not save webhook delivery attempt")
+		}
+		return
+	}
+	if d.Error != "" {
+		log.WithField("error", d.Error).Warn("webhook delivery attempt failed")
+		return
+	}
+	log.Info("webhook delivered")
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (r WebhookRetries) backoff(attempts int) time.Duration {
+	delay := r.BaseDelay
+	for i := 1; i < attempts && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	return delay
+}
+
+func newDelivery(webhookID string, eventID int64, eventType string, payload []byte) entities.WebhookDelivery {
+	now := time.Now().UTC()
+	return entities.WebhookDelivery{
+		ID:            uuid.New().String(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        consts.DeliveryPending,
+		NextAttemptAt: now,
+	}
+}
+
+// validate checks the webhook, and that the sender can deliver to its URL
+func (w Webhooks) validate(ctx context.Context, hook entities.Webhook) error {
+	if err := validateWebhook(hook); err != nil {
+		return err
+	}
+	if err := w.sender.CheckURL(ctx, hook.URL); err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("url", hook.URL).Warn("webhook url not allowed")
+		return fmt.Errorf("webhook url not allowed: %s: %w", err.Error(), consts.ErrInvalidArgument)
+	}
+	return nil
+}
+
+func validateWebhook(hook entities.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute http or https url: %w", consts.ErrInvalidArgument)
+	}
+
+	if len(hook.Events) == 0 {
+		return fmt.Errorf("at least one event is required: %w", consts.ErrInvalidArgument)
+	}
+	for _, event := range hook.Events {
+		if !contains(consts.EventTypes, event) {
+			return fmt.Errorf("unknown event %s: %w", event, consts.ErrInvalidArgument)
+		}
+	}
+
+	if hook.Secret != "" && len(hook.Secret) < minWebhookSecretLen {
+		return fmt.Errorf("webhook secret must be at least %d characters: %w", minWebhookSecretLen, consts.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// generateWebhookSecret returns a random secret to sign the deliveries
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
@@ -0,0 +1,220 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhooks_Create(t *testing.T) {
+	tests := []struct {
+		name     string
+		hook     entities.Webhook
+		checkErr error
+		wantErr  error
+	}{
+		{
+			name: "Success: Generated secret",
+			hook: entities.Webhook{URL: "https://partner.example.com/hooks", Events: []string{consts.EventArticleCreated}},
+		},
+		{
+			name: "Success: Given secret",
+			hook: entities.Webhook{URL: "http://partner.example.com", Events: consts.EventTypes, Secret: "0123456789abcdef"},
+		},
+		{
+			name:    "Failure: Relative url",
+			hook:    entities.Webhook{URL: "/hooks", Events: []string{consts.EventArticleCreated}},
+			wantErr: consts.ErrInvalidArgument,
+		},
+		{
+			name:    "Failure: Not http",
+			hook:    entities.Webhook{URL: "ftp://partner.example.com", Events: []string{consts.EventArticleCreated}},
+			wantErr: consts.ErrInvalidArgument,
+		},
+		{
+			name:    "Failure: No events",
+			hook:    entities.Webhook{URL: "https://partner.example.com"},
+			wantErr: consts.ErrInvalidArgument,
+		},
+		{
+			name:    "Failure: Unknown event",
+			hook:    entities.Webhook{URL: "https://partner.example.com", Events: []string{"published"}},
+			wantErr: consts.ErrInvalidArgument,
+		},
+		{
+			name:     "Failure: Private address",
+			hook:     entities.Webhook{URL: "http://10.0.0.1/hooks", Events: []string{consts.EventArticleCreated}},
+			checkErr: errors.New("10.0.0.1: webhook address is not public"),
+			wantErr:  consts.ErrInvalidArgument,
+		},
+		{
+			name:    "Failure: Short secret",
+			hook:    entities.Webhook{URL: "https://partner.example.com", Events: []string{consts.EventArticleCreated}, Secret: "secret"},
+			wantErr: consts.ErrInvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			m := mocks.NewMockWebhooksStore(ctrl)
+			if tt.wantErr == nil {
+				m.EXPECT().
+					Create(gomock.Any(), gomock.AssignableToTypeOf(entities.Webhook{})).
+					DoAndReturn(func(_ context.Context, hook entities.Webhook) (entities.Webhook, error) { return hook, nil })
+			}
+
+			// the sender checks the urls that are valid
+			s := mocks.NewMockWebhookSender(ctrl)
+			s.EXPECT().CheckURL(gomock.Any(), tt.hook.URL).Return(tt.checkErr).MaxTimes(1)
+
+			w := NewWebhooks(m, s, WebhookRetries{})
+			got, err := w.Create(context.Background(), tt.hook)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Webhooks.Create() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			assert.Equal(t, tt.hook.URL, got.URL)
+			assert.NotEmpty(t, got.ID)
+			if tt.hook.Secret != "" {
+				assert.Equal(t, tt.hook.Secret, got.Secret)
+			} else {
+				assert.Regexp(t, "^whsec_.{43}$", got.Secret)
+			}
+		})
+	}
+}
+
+func TestWebhooks_Enqueue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	m := mocks.NewMockWebhooksStore(ctrl)
+	m.EXPECT().GetAll(gomock.Any()).Return([]entities.Webhook{
+		{ID: "all", Events: consts.EventTypes},
+		{ID: "deleted", Events: []string{consts.EventArticleDeleted}},
+		{ID: "created", Events: []string{consts.EventArticleCreated}},
+	}, nil)
+	m.EXPECT().
+		Enqueue(gomock.Any(), int64(7), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ int64, deliveries []entities.WebhookDelivery) error {
+			var hooks []string
+			for _, d := range deliveries {
+				hooks = append(hooks, d.WebhookID)
+				assert.Equal(t, consts.DeliveryPending, d.Status)
+				assert.JSONEq(t, `{"id":7,"type":"created","article":{"id":"a","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z","title":"","content":"","author":""},"occurredAt":"0001-01-01T00:00:00Z"}`, string(d.Payload))
+			}
+			assert.Equal(t, []string{"all", "created"}, hooks)
+			return nil
+		})
+
+	w := NewWebhooks(m, nil, WebhookRetries{})
+	err := w.Enqueue(context.Background(), entities.ArticleEvent{ID: 7, Type: consts.EventArticleCreated, Article: entities.Article{ID: "a"}})
+	assert.NoError(t, err)
+}
+
+func TestWebhooks_DeliverDue(t *testing.T) {
+	retries := WebhookRetries{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: 90 * time.Second, Lease: time.Minute}
+	tests := []struct {
+		name         string
+		attempts     int
+		status       int
+		sendErr      error
+		wantStatus   string
+		wantError    string
+		wantNextWait time.Duration
+	}{
+		{
+			name:       "Success: 2xx response",
+			status:     204,
+			wantStatus: consts.DeliverySucceeded,
+		},
+		{
+			name:         "Retry: Error response",
+			status:       500,
+			wantStatus:   consts.DeliveryPending,
+			wantError:    "webhook responded with status 500",
+			wantNextWait: time.Minute,
+		},
+		{
+			name:         "Retry: No response, the delay doubles up to the max",
+			attempts:     1,
+			sendErr:      fmt.Errorf("connection refused"),
+			wantStatus:   consts.DeliveryPending,
+			wantError:    "connection refused",
+			wantNextWait: 90 * time.Second,
+		},
+		{
+			name:       "Failure: Last attempt",
+			attempts:   2,
+			status:     301,
+			wantStatus: consts.DeliveryFailed,
+			wantError:  "webhook responded with status 301",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			hook := entities.Webhook{ID: "hook", URL: "https://partner.example.com", Secret: "secret"}
+			delivery := entities.WebhookDelivery{ID: "delivery", WebhookID: "hook", Status: consts.DeliveryPending, Attempts: tt.attempts}
+
+			m := mocks.NewMockWebhooksStore(ctrl)
+			m.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any(), deliveryBatch).Return([]entities.WebhookDelivery{delivery}, nil)
+			m.EXPECT().GetOne(gomock.Any(), "hook").Return(hook, nil)
+			m.EXPECT().
+				SaveAttempt(gomock.Any(), gomock.AssignableToTypeOf(entities.WebhookDelivery{})).
+				DoAndReturn(func(_ context.Context, d entities.WebhookDelivery) error {
+					assert.Equal(t, tt.attempts+1, d.Attempts)
+					assert.Equal(t, tt.wantStatus, d.Status)
+					assert.Equal(t, tt.wantError, d.Error)
+					assert.Equal(t, tt.status, d.ResponseStatus)
+					if tt.wantNextWait > 0 {
+						assert.Equal(t, tt.wantNextWait, d.NextAttemptAt.Sub(d.UpdatedAt))
+					}
+					return nil
+				})
+			s := mocks.NewMockWebhookSender(ctrl)
+			s.EXPECT().Send(gomock.Any(), hook, delivery).Return(tt.status, tt.sendErr)
+
+			w := NewWebhooks(m, s, retries)
+			sent, err := w.DeliverDue(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, 1, sent)
+		})
+	}
+}
+
+func TestWebhooks_Redeliver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	previous := entities.WebhookDelivery{ID: "previous", WebhookID: "hook", EventID: 3, EventType: consts.EventArticleUpdated, Payload: []byte(`{}`), Status: consts.DeliveryFailed, Attempts: 8}
+	m := mocks.NewMockWebhooksStore(ctrl)
+	m.EXPECT().GetDelivery(gomock.Any(), "previous").Return(previous, nil).Times(2)
+	m.EXPECT().
+		CreateDelivery(gomock.Any(), gomock.AssignableToTypeOf(entities.WebhookDelivery{})).
+		DoAndReturn(func(_ context.Context, d entities.WebhookDelivery) (entities.WebhookDelivery, error) { return d, nil })
+
+	w := NewWebhooks(m, nil, WebhookRetries{})
+	got, err := w.Redeliver(context.Background(), "hook", "previous")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "previous", got.ID)
+	assert.Equal(t, consts.DeliveryPending, got.Status)
+	assert.Equal(t, 0, got.Attempts)
+	assert.Equal(t, previous.Payload, got.Payload)
+
+	// a delivery can only be sent again to its webhook
+	_, err = w.Redeliver(context.Background(), "other", "previous")
+	assert.True(t, errors.Is(err, consts.ErrEntityNotFound))
+}
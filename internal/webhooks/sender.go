@@ -0,0 +1,103 @@
+// Package webhooks posts the article events to the webhooks, signed with their secret
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+// Headers of the delivery requests
+const (
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// maxResponseBytes is how much of the response body is read, so the connection can be reused
+const maxResponseBytes = 64 << 10
+
+// Sign returns the signature of a delivery, the HMAC-SHA256 with the secret of "<timestamp>.<body>"
+// The timestamp is signed too so receivers can reject old deliveries that are replayed
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender posts the deliveries over HTTP
+type Sender struct {
+	client       *http.Client
+	allowPrivate bool
+	lookup       func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewSender is the Sender constructor, timeout bounds each request
+// Redirects are not followed, they count as failed attempts. Unless allowPrivate is set, the deliveries are only
+// sent to public addresses, and not through the proxy of the environment, which would be dialed instead
+func NewSender(timeout time.Duration, allowPrivate bool) Sender {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if !allowPrivate {
+		dialer.Control = checkAddress
+		transport.Proxy = nil
+	}
+
+	return Sender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowPrivate: allowPrivate,
+		lookup:       net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+// Send posts the payload of the delivery to the webhook, and returns the status code of the response
+func (s Sender) Send(ctx context.Context, hook entities.Webhook, d entities.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("could not build request: %w", err)
+	}
+	req = req.WithContext(ctx)
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "articles-webhooks/1.0")
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("could not send delivery: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1600000000.{"id":1}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=49847f6653f3434dc0d5563850815d91e18471282eeccadbf48380236b3ed25f", Sign("secret", 1600000000, []byte(`{"id":1}`)))
+}
+
+func TestSender_Send(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	hook := entities.Webhook{URL: srv.URL, Secret: "secret"}
+	delivery := entities.WebhookDelivery{ID: "delivery", EventType: "created", Payload: []byte(`{"id":1}`)}
+	status, err := NewSender(time.Second, true).Send(context.Background(), hook, delivery)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, `{"id":1}`, string(body))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "delivery", got.Header.Get(HeaderDelivery))
+	assert.Equal(t, "created", got.Header.Get(HeaderEvent))
+	timestamp, err := strconv.ParseInt(got.Header.Get(HeaderTimestamp), 10, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, Sign("secret", timestamp, body), got.Header.Get(HeaderSignature))
+
+	// redirects are not followed
+	hook.URL = srv.URL + "/moved"
+	status, err = NewSender(time.Second, true).Send(context.Background(), hook, delivery)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, status)
+}
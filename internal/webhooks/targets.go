@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenTarget is returned for the webhook addresses the deliveries are not sent to
+var ErrForbiddenTarget = errors.New("webhook address is not public")
+
+// blockedNets are the loopback, private, shared, link-local, unspecified and multicast networks. A webhook could
+// reach the services of the internal network or the cloud metadata endpoint through them
+var blockedNets = parseNets(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16",
+	"224.0.0.0/4", "240.0.0.0/4", "::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func parseNets(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// publicIP reports whether the ip is outside of the blocked networks, IPv4 addresses mapped to IPv6 included
+func publicIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkAddress is the Control of the dialer, it refuses the connections to blocked addresses once the host was
+// resolved, so a name that resolves to another address after the webhook was registered is refused too
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("could not parse address %s: %w", address, err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%s: %w", host, ErrForbiddenTarget)
+	}
+	return nil
+}
+
+// CheckURL checks that the host of the webhook URL only resolves to public addresses, it's checked when the
+// webhook is registered so it fails right away instead of on each delivery
+func (s Sender) CheckURL(ctx context.Context, rawURL string) error {
+	if s.allowPrivate {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("could not parse webhook url: %w", err)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicIP(ip) {
+			return fmt.Errorf("%s: %w", host, ErrForbiddenTarget)
+		}
+		return nil
+	}
+
+	addrs, err := s.lookup(ctx, host)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return fmt.Errorf("%s resolves to %s: %w", host, addr.IP, ErrForbiddenTarget)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "100.64.0.1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "0.0.0.0"},
+		{ip: "::"},
+		{ip: "224.0.0.1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "::ffff:10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, publicIP(net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestSender_CheckURL(t *testing.T) {
+	hosts := map[string][]string{
+		"partner.example.com":  {"93.184.216.34"},
+		"internal.example.com": {"93.184.216.34", "10.0.0.5"},
+	}
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		ips, ok := hosts[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		var addrs []net.IPAddr
+		for _, ip := range ips {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+		}
+		return addrs, nil
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		allowPrivate bool
+		wantErr      string
+	}{
+		{name: "public host", url: "https://partner.example.com/hooks"},
+		{name: "public address", url: "http://93.184.216.34:8080/hooks"},
+		{name: "loopback address", url: "http://127.0.0.1:8080/hooks", wantErr: "127.0.0.1: webhook address is not public"},
+		{name: "link-local address", url: "http://[fe80::1]/hooks", wantErr: "fe80::1: webhook address is not public"},
+		{name: "metadata endpoint", url: "http://169.254.169.254/latest", wantErr: "169.254.169.254: webhook address is not public"},
+		{name: "host with a private address", url: "https://internal.example.com", wantErr: "internal.example.com resolves to 10.0.0.5: webhook address is not public"},
+		{name: "unknown host", url: "https://missing.example.com", wantErr: "could not resolve missing.example.com: no such host"},
+		{name: "private allowed", url: "http://127.0.0.1:8080/hooks", allowPrivate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSender(time.Second, tt.allowPrivate)
+			s.lookup = lookup
+
+			err := s.CheckURL(context.Background(), tt.url)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSender_SendPrivate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// the connection is refused once the address is resolved, whatever the URL was when it was checked
+	hook := entities.Webhook{URL: srv.URL, Secret: "secret"}
+	_, err := NewSender(time.Second, false).Send(context.Background(), hook, entities.WebhookDelivery{ID: "delivery"})
+	assert.True(t, errors.Is(err, ErrForbiddenTarget), err)
+}
@@ -0,0 +1,84 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
+)
+
+// Dispatcher queues the events and sends the deliveries, usecases.Webhooks
+type Dispatcher interface {
+	Enqueue(ctx context.Context, event entities.ArticleEvent) error
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+// Worker sends the deliveries that are due, the events are queued by the outbox relay with Publish
+type Worker struct {
+	dispatcher Dispatcher
+	interval   time.Duration
+	wake       chan struct{}
+	done       chan struct{}
+}
+
+// NewWorker is the Worker constructor, interval is how often the queue is checked for retries
+func NewWorker(d Dispatcher, interval time.Duration) *Worker {
+	return &Worker{
+		dispatcher: d,
+		interval:   interval,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+}
+
+// Publish queues the deliveries of the event, and wakes the worker up to send them
+func (w *Worker) Publish(ctx context.Context, event entities.ArticleEvent) error {
+	if err := w.dispatcher.Enqueue(ctx, event); err != nil {
+		return err
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run sends the due deliveries every interval, and as soon as new ones are queued, until ctx is canceled
+// Sends are not cancelled with ctx, they are bounded by the timeout of the sender
+func (w *Worker) Run(ctx context.Context) {
+	defer close(w.done)
+	log := logging.FromContext(ctx)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.wake:
+		case <-ctx.Done():
+			return
+		}
+
+		for ctx.Err() == nil {
+			sent, err := w.dispatcher.DeliverDue(context.Background())
+			if err != nil {
+				log.WithError(err).Error("could not send webhook deliveries")
+			}
+			if err != nil || sent == 0 {
+				break
+			}
+		}
+	}
+}
+
+// Wait blocks until Run has finished, or ctx is done
+func (w *Worker) Wait(ctx context.Context) error {
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
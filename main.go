@@ -2,82 +2,600 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
-	"os/signal"
-	"runtime"
 	"time"
 
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/config"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/events"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/graphqltransport"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/grpctransport/articlespb"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/jwt"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/lifecycle"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/logging"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/openapi"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/outbox"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/ratelimit"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/reporting"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/stores"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/tlsconfig"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/transports"
 	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/webhooks"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Exit codes of the process
+const (
+	exitOK = iota
+	exitFailure
+	exitConfig
+)
+
+// Build info, set with -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..."
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
 )
 
 func main() {
-	// The first defered call is the last to be executed
-	// os.Exit terminates the program
-	defer os.Exit(0)
+	// os.Exit skips deferred calls, so everything that needs cleaning up lives in run
+	os.Exit(run())
+}
+
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.WithError(err).Error("could not load config")
+		return exitConfig
+	}
+	// Components are started in the order they're appended, and stopped in reverse order
+	// A failed set up stops the ones appended so far, so the logs and error reports are flushed and closed
+	lc := lifecycle.NewManager(cfg.ShutdownTimeout)
+	abort := func(code int) int {
+		if err := lc.Abort(); err != nil {
+			logrus.WithError(err).Error("could not stop cleanly")
+		}
+		return code
+	}
+
+	// The level can be changed later from the admin server
+	logrus.SetLevel(cfg.LogLevel)
+	// Added first, so every other hook gets the redacted fields too
+	logrus.AddHook(logging.NewRedactHook(cfg.LogRedactFields, cfg.LogMaxFieldLength))
+	logOutput, err := logging.Setup(logrus.StandardLogger(), cfg.Log)
+	if err != nil {
+		logrus.WithError(err).Error("could not set up logs")
+		return exitConfig
+	}
+	// Log files are closed last, so the shutdown is logged
+	lc.Append(lifecycle.Hook{
+		Name:   "log files",
+		OnStop: func(ctx context.Context) error { return logOutput.Close() },
+	})
+	rotateCtx, stopRotate := context.WithCancel(context.Background())
+	lc.Append(lifecycle.Hook{
+		Name: "log rotation",
+		OnStart: func(ctx context.Context) error {
+			go logOutput.Watch(rotateCtx)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopRotate()
+			return nil
+		},
+	})
 
-	logrus.SetLevel(logrus.DebugLevel)
+	// Error entries are reported with their stack, grouped by fingerprint
+	var sinks []reporting.Sink
+	if cfg.ErrorReportFile != "" {
+		fileSink, err := reporting.NewFileSink(cfg.ErrorReportFile)
+		if err != nil {
+			logrus.WithError(err).Error("could not open error report file")
+			return abort(exitConfig)
+		}
+		sinks = append(sinks, fileSink)
+		lc.Append(lifecycle.Hook{
+			Name:   "error report file",
+			OnStop: func(ctx context.Context) error { return fileSink.Close() },
+		})
+	}
+	if cfg.ErrorReportDSN != "" {
+		httpSink, err := reporting.NewHTTPSink(cfg.ErrorReportDSN)
+		if err != nil {
+			logrus.WithError(err).Error("could not parse ERROR_REPORT_DSN")
+			return abort(exitConfig)
+		}
+		sinks = append(sinks, httpSink)
+	}
+	reporter := reporting.NewReporter(cfg.ErrorReportInterval, version, sinks...)
+	logrus.AddHook(reporter)
+
+	reportCtx, stopReports := context.WithCancel(context.Background())
+	lc.Append(lifecycle.Hook{
+		Name: "error reporter",
+		OnStart: func(ctx context.Context) error {
+			go reporter.Run(reportCtx)
+			return nil
+		},
+		// Send the errors of the shut down before closing the sinks
+		OnStop: func(ctx context.Context) error {
+			stopReports()
+			return reporter.Wait(ctx)
+		},
+	})
 
 	// Init service, usecase and transport layers
 	// Clean code architecture is used here
-	store, err := stores.NewArticles()
+	db, err := stores.NewDB(cfg.DBFile, cfg.DBReset)
+	if err != nil {
+		logrus.WithError(err).Error("could not init database")
+		return abort(exitFailure)
+	}
+	lc.Append(lifecycle.Hook{
+		Name:   "database",
+		OnStop: func(ctx context.Context) error { return db.Close() },
+	})
+
+	policy := usecases.NewPolicy(stores.NewRoles(db))
+
+	store := stores.NewArticles(db)
+	// The store writes the changes and their events to the outbox in the same transaction, the relay
+	// publishes them. A stream that falls more than 64 events behind the bus catches up from the outbox
+	outboxStore := stores.NewOutbox(db)
+	bus := events.NewBus(outboxStore, 64)
+
+	// Article events are queued for the webhooks subscribed to them by the relay, and sent with retries
+	hooksUsecase := usecases.NewWebhooks(stores.NewWebhooks(db), webhooks.NewSender(cfg.WebhookSendTimeout, cfg.WebhookAllowPrivateTargets), usecases.WebhookRetries{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		BaseDelay:   cfg.WebhookRetryBaseDelay,
+		MaxDelay:    cfg.WebhookRetryMaxDelay,
+		Lease:       2 * cfg.WebhookSendTimeout,
+	})
+	hooksTransport := transports.NewWebhooks(hooksUsecase)
+	hooksWorker := webhooks.NewWorker(hooksUsecase, cfg.WebhookPollInterval)
+	hooksCtx, stopHooks := context.WithCancel(context.Background())
+	lc.Append(lifecycle.Hook{
+		Name: "webhooks",
+		OnStart: func(ctx context.Context) error {
+			go hooksWorker.Run(hooksCtx)
+			return nil
+		},
+		// Deliveries being sent are finished, the ones left are sent on the next start
+		OnStop: func(ctx context.Context) error {
+			stopHooks()
+			return hooksWorker.Wait(ctx)
+		},
+	})
+
+	destinations := []outbox.Destination{
+		{Name: "bus", Publisher: bus},
+		{Name: "webhooks", Publisher: hooksWorker},
+	}
+	var closePublishers []func() error
+	// Appended before the relay, so they are closed once it stopped publishing to them
+	lc.Append(lifecycle.Hook{
+		Name: "outbox publishers",
+		OnStop: func(ctx context.Context) error {
+			for _, c := range closePublishers {
+				if err := c(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	if cfg.OutboxFile != "" {
+		file, err := outbox.NewFile(cfg.OutboxFile)
+		if err != nil {
+			logrus.WithError(err).Error("could not open outbox file")
+			return abort(exitConfig)
+		}
+		destinations = append(destinations, outbox.Destination{Name: "file", Publisher: file})
+		closePublishers = append(closePublishers, file.Close)
+	}
+	if cfg.NATSURL != "" {
+		nats, err := outbox.NewNATS(cfg.NATSURL, cfg.NATSSubjectPrefix)
+		if err != nil {
+			logrus.WithError(err).Error("could not parse NATS_URL")
+			return abort(exitConfig)
+		}
+		destinations = append(destinations, outbox.Destination{Name: "nats", Publisher: nats})
+		closePublishers = append(closePublishers, nats.Close)
+	}
+	relay := outbox.NewRelay(outboxStore, cfg.OutboxPollInterval, cfg.OutboxRetention, destinations...)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	lc.Append(lifecycle.Hook{
+		Name: "outbox relay",
+		OnStart: func(ctx context.Context) error {
+			go relay.Run(relayCtx)
+			return nil
+		},
+		// Events that were not published yet are published on the next start
+		OnStop: func(ctx context.Context) error {
+			stopRelay()
+			return relay.Wait(ctx)
+		},
+	})
+
+	usecase := usecases.NewArticles(store, policy, relay)
+	codecs := render.NewNegotiator(render.JSONCodec{}, render.XMLCodec{}, render.YAMLCodec{}, render.MsgPackCodec{}, render.CSVCodec{})
+	socketLimits := transports.WebSocketLimits{
+		MaxSubscriptions: cfg.WebSocketMaxSubscriptions,
+		MaxFilters:       cfg.WebSocketMaxFilters,
+		MaxPending:       cfg.WebSocketMaxPending,
+		PingInterval:     cfg.WebSocketPingInterval,
+	}
+	gqlLimits := graphqltransport.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+		MaxBatch:      cfg.GraphQLMaxBatch,
+	}
+	gql, err := graphqltransport.NewGraphQL(usecase, policy, gqlLimits)
 	if err != nil {
-		logrus.WithError(err).Fatal("could not init store")
+		logrus.WithError(err).Error("could not init graphql")
+		return abort(exitFailure)
+	}
+
+	keysStore := stores.NewAPIKeys(db)
+	keysUsecase := usecases.NewAPIKeys(keysStore)
+	keysTransport := transports.NewAPIKeys(keysUsecase)
+
+	if cfg.BootstrapAPIKey != "" {
+		if err := keysUsecase.Bootstrap(context.Background(), "bootstrap", cfg.BootstrapAPIKey); err != nil {
+			logrus.WithError(err).Error("could not bootstrap api key")
+			return abort(exitConfig)
+		}
+	} else {
+		logrus.Warn("API_BOOTSTRAP_KEY is not set, no api key can be created")
 	}
-	defer store.Close()
 
-	usecase := usecases.NewArticles(store)
-	transport := transports.NewArticles(usecase)
+	// The OpenAPI document describes the articles routes, it is public as the playgrounds
+	// Requests to the articles are validated against it, and responses too with OPENAPI_STRICT_RESPONSES
+	doc := transports.OpenAPI(version, codecs)
+	docs, err := openapi.NewHandler(doc)
+	if err != nil {
+		logrus.WithError(err).Error("could not init openapi document")
+		return abort(exitFailure)
+	}
 
 	// Init router
 	r := mux.NewRouter()
-	s := r.PathPrefix("/articles").Subrouter()
-	s.HandleFunc("", transport.GetAll).Methods("GET")
-	s.HandleFunc("", transport.Create).Methods("POST")
-	s.HandleFunc("/{id}", transport.GetOne).Methods("GET")
-	s.HandleFunc("/{id}", transport.Update).Methods("PUT")
-	s.HandleFunc("/{id}", transport.Delete).Methods("DELETE")
+
+	// Each version of the articles API is under its own prefix, with its own bodies
+	// The deprecated ones link to the same path in the latest version
+	validate := middlewares.OpenAPI(openapi.NewValidator(doc), cfg.OpenAPIStrictResponses)
+	latest := transports.ArticlesVersions[len(transports.ArticlesVersions)-1]
+	var versionNames []string
+	var sockets []transports.ArticlesWebSocket
+	for _, v := range transports.ArticlesVersions {
+		prefix := "/" + v.Name
+		versionNames = append(versionNames, v.Name)
+		socket := transports.NewArticlesWebSocket(bus, v, socketLimits)
+		sockets = append(sockets, socket)
+
+		vr := r.PathPrefix(prefix).Subrouter()
+		if v.Deprecated && !cfg.APIV1Deprecation.IsZero() {
+			// v1 is the only deprecated version so far, its headers are sent once it has a date
+			vr.Use(middlewares.Deprecated(cfg.APIV1Deprecation, cfg.APIV1Sunset, prefix, "/"+latest.Name))
+		}
+		articleRoutes(vr, policy, validate,
+			transports.NewArticles(usecase, codecs, v),
+			transports.NewArticlesStream(bus, v, cfg.StreamHeartbeat, cfg.StreamMaxDuration),
+			socket)
+	}
+	r.HandleFunc("/openapi.json", docs.Spec).Methods("GET")
+	if cfg.SwaggerUI {
+		r.HandleFunc("/docs", docs.UI("/openapi.json")).Methods("GET")
+	}
+
+	// mutations also need articles:write, checked by the handler
+	g := r.PathPrefix("/graphql").Methods("POST").Subrouter()
+	g.Use(middlewares.RequireScope(policy, consts.ScopeArticlesRead))
+	g.HandleFunc("", gql.Query)
+	if cfg.GraphQLPlayground {
+		r.HandleFunc("/graphql", gql.Playground).Methods("GET")
+	}
+
+	k := r.PathPrefix("/admin/keys").Subrouter()
+	k.Use(middlewares.RequireScope(policy, consts.ScopeKeysAdmin))
+	k.HandleFunc("", keysTransport.GetAll).Methods("GET")
+	k.HandleFunc("", keysTransport.Create).Methods("POST")
+	k.HandleFunc("/{id}", keysTransport.Revoke).Methods("DELETE")
+	k.HandleFunc("/{id}/rotate", keysTransport.Rotate).Methods("POST")
+
+	wh := r.PathPrefix("/admin/webhooks").Subrouter()
+	wh.Use(middlewares.RequireScope(policy, consts.ScopeWebhooksAdmin))
+	wh.HandleFunc("", hooksTransport.GetAll).Methods("GET")
+	wh.HandleFunc("", hooksTransport.Create).Methods("POST")
+	wh.HandleFunc("/{id}", hooksTransport.GetOne).Methods("GET")
+	wh.HandleFunc("/{id}", hooksTransport.Update).Methods("PUT")
+	wh.HandleFunc("/{id}", hooksTransport.Delete).Methods("DELETE")
+	wh.HandleFunc("/{id}/deliveries", hooksTransport.Deliveries).Methods("GET")
+	wh.HandleFunc("/{id}/deliveries/{deliveryId}/redeliver", hooksTransport.Redeliver).Methods("POST")
+
+	// Users log in with a password and get a token signed with AUTH_TOKEN_SECRET
+	// which is verified as any other bearer token
+	var tokenKeys jwt.KeyProviders
+	var usersUsecase usecases.Users
+	if cfg.TokenSecret != "" {
+		signer := jwt.NewSigner("local", []byte(cfg.TokenSecret), cfg.JWTIssuer, cfg.JWTAudience, cfg.TokenTTL)
+		tokenKeys = append(tokenKeys, signer)
+
+		usersUsecase = usecases.NewUsers(stores.NewUsers(db), signer)
+		usersTransport := transports.NewUsers(usersUsecase)
+
+		r.HandleFunc("/login", usersTransport.Login).Methods("POST")
+		u := r.PathPrefix("/admin/users").Subrouter()
+		u.Use(middlewares.RequireScope(policy, consts.ScopeUsersAdmin))
+		u.HandleFunc("", usersTransport.GetAll).Methods("GET")
+		u.HandleFunc("", usersTransport.Create).Methods("POST")
+		u.HandleFunc("/{id}/roles", usersTransport.UpdateRoles).Methods("PUT")
+	} else {
+		logrus.Warn("AUTH_TOKEN_SECRET is not set, users can not log in")
+	}
 
 	// Set middlewares
+	// RequestID and Logging start the request logger, that all layers get with logging.FromContext
 	r.Use(middlewares.RequestID)
-	r.Use(middlewares.Timing)
 	r.Use(middlewares.Logging)
+	r.Use(middlewares.Timing)
+	r.Use(middlewares.ClientCertAuth(cfg.ClientCertScopes, cfg.ClientCertRoles))
+	r.Use(middlewares.APIKeyAuth(keysUsecase))
+
+	// The gRPC API authenticates and limits calls as the HTTP middlewares do
+	grpcSteps := []grpctransport.Step{
+		grpctransport.RequestID,
+		grpctransport.Logging,
+		grpctransport.ClientCertAuth(cfg.ClientCertScopes, cfg.ClientCertRoles),
+		grpctransport.APIKeyAuth(keysUsecase),
+	}
+
+	// Bearer tokens are verified against a local JWKS file, reloaded when it changes
+	if cfg.JWKSFile != "" {
+		keyFile, err := jwt.NewKeyFile(cfg.JWKSFile)
+		if err != nil {
+			logrus.WithError(err).Error("could not load jwks file")
+			return abort(exitConfig)
+		}
+		tokenKeys = append(tokenKeys, keyFile)
+
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		lc.Append(lifecycle.Hook{
+			Name: "jwks watcher",
+			OnStart: func(ctx context.Context) error {
+				go keyFile.Watch(watchCtx, cfg.JWKSReloadInterval)
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				stopWatch()
+				return nil
+			},
+		})
+	}
+	if len(tokenKeys) > 0 {
+		var verifier middlewares.TokenAuthenticator = jwt.NewVerifier(tokenKeys, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway)
+		// Users get the roles they have now, not the ones of their token
+		if cfg.TokenSecret != "" {
+			verifier = usersUsecase.Tokens(verifier)
+		}
+		r.Use(middlewares.BearerAuth(verifier))
+		grpcSteps = append(grpcSteps, grpctransport.BearerAuth(verifier))
+	}
+
+	// Rate limits go after authentication, so they are applied per principal
+	var limiter middlewares.RateLimiter = ratelimit.NewMemory()
+	if cfg.RateLimitStore == "sqlite" {
+		limiter = stores.NewRateLimits(db)
+	}
+	r.Use(middlewares.RateLimit(limiter, cfg.RateLimits, cfg.RateLimitTrustForwarded))
+	grpcSteps = append(grpcSteps, grpctransport.RateLimit(limiter, cfg.RateLimits), grpctransport.RequireScope(policy, grpctransport.ArticlesScopes))
+
+	// Health endpoints skip the middlewares, probes are not authenticated nor rate limited
+	health := transports.NewHealth(
+		transports.HealthCheck{Name: "database", Check: store.Ping},
+		transports.HealthCheck{Name: "migrations", Check: db.Migrated},
+	)
+	root := http.NewServeMux()
+	root.HandleFunc("/healthz", health.Live)
+	root.HandleFunc("/readyz", health.Ready)
+	// the paths without a version are served by the version of their media types, v1 by default as before versioning
+	root.Handle("/", middlewares.APIVersion("/articles", versionNames, transports.ArticlesV1.Name)(r))
 
 	// Init server with timeouts
 	srv := &http.Server{
-		Handler:      r,
-		Addr:         "0.0.0.0:8080",
+		Handler:      root,
+		Addr:         cfg.HTTPAddr,
 		WriteTimeout: 30 * time.Second,
 		ReadTimeout:  30 * time.Second,
 	}
 
-	// Start server in goroutine
-	go func() {
-		logrus.Warn("Starting server")
-		if err := srv.ListenAndServe(); err != nil {
-			logrus.WithError(err).Error("Got server error")
+	// Certificates are reloaded when the files change or on SIGHUP, without dropping connections
+	if cfg.TLS.CertFile != "" {
+		certs, err := tlsconfig.NewReloader(cfg.TLS)
+		if err != nil {
+			logrus.WithError(err).Error("could not load tls certificates")
+			return abort(exitConfig)
 		}
-	}()
+		srv.TLSConfig = certs.Config()
 
-	// Handle graceful shutdowns via SIGINT
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		lc.Append(lifecycle.Hook{
+			Name: "tls watcher",
+			OnStart: func(ctx context.Context) error {
+				go certs.Watch(watchCtx, cfg.TLSReloadInterval)
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				stopWatch()
+				return nil
+			},
+		})
+	}
 
-	// Wait for requests to finish
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	lc.Append(serverHook(lc, "http server", srv))
 
-	// Graceful shutdown
-	srv.Shutdown(ctx)
-	logrus.Warn("Shutting down gracefully")
+	// The gRPC server shares the TLS certificates of the HTTP server
+	if cfg.GRPCAddr != "" {
+		opts := []grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(grpctransport.UnaryInterceptor(grpcSteps...)),
+			grpc.ChainStreamInterceptor(grpctransport.StreamInterceptor(grpcSteps...)),
+		}
+		if srv.TLSConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(srv.TLSConfig)))
+		}
+		grpcSrv := grpc.NewServer(opts...)
+		grpcArticles := grpctransport.NewArticles(usecase, bus)
+		articlespb.RegisterArticlesServiceServer(grpcSrv, grpcArticles)
+
+		lc.Append(lifecycle.Hook{
+			Name: "grpc server",
+			OnStart: func(ctx context.Context) error {
+				ln, err := net.Listen("tcp", cfg.GRPCAddr)
+				if err != nil {
+					return err
+				}
+
+				logrus.WithField("addr", cfg.GRPCAddr).WithField("tls", srv.TLSConfig != nil).Warn("Starting grpc server")
+				go func() {
+					if err := grpcSrv.Serve(ln); err != nil {
+						lc.Fail(err)
+					}
+				}()
+				return nil
+			},
+			// Watch streams never end by themselves, they are ended first so in-flight calls can finish
+			OnStop: func(ctx context.Context) error {
+				grpcArticles.Stop()
+				stopped := make(chan struct{})
+				go func() {
+					grpcSrv.GracefulStop()
+					close(stopped)
+				}()
+				select {
+				case <-stopped:
+					return nil
+				case <-ctx.Done():
+					grpcSrv.Stop()
+					return ctx.Err()
+				}
+			},
+		})
+	}
+
+	// The admin server must only be reachable from the private network
+	if cfg.AdminAddr != "" {
+		admin := transports.NewAdmin(transports.BuildInfo{Version: version, Commit: commit, BuildDate: buildDate}, cfg.Redacted(), reporter)
+		am := mux.NewRouter()
+		am.HandleFunc("/version", admin.Version).Methods("GET")
+		am.HandleFunc("/config", admin.Config).Methods("GET")
+		am.HandleFunc("/runtime", admin.Runtime).Methods("GET")
+		am.HandleFunc("/errors", admin.Errors).Methods("GET")
+		am.HandleFunc("/loglevel", admin.GetLogLevel).Methods("GET")
+		am.HandleFunc("/loglevel", admin.SetLogLevel).Methods("PUT")
+		am.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		am.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		am.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		am.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		am.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+
+		// No write timeout, profiles and traces take as long as the seconds parameter
+		adminSrv := &http.Server{
+			Handler:     am,
+			Addr:        cfg.AdminAddr,
+			ReadTimeout: 30 * time.Second,
+		}
+		lc.Append(serverHook(lc, "admin server", adminSrv))
+	}
+
+	// Streams and websockets never end by themselves, they are ended after the drain delay
+	// so the servers can shut down, the http server does not wait for websockets
+	lc.Append(lifecycle.Hook{
+		Name: "event streams",
+		OnStop: func(ctx context.Context) error {
+			for _, socket := range sockets {
+				socket.Stop()
+			}
+			bus.Close()
+			return nil
+		},
+	})
+
+	// Stopped first: readiness fails and the load balancer has some time to stop routing traffic
+	lc.Append(lifecycle.Hook{
+		Name: "readiness",
+		OnStop: func(ctx context.Context) error {
+			health.Drain()
+			select {
+			case <-time.After(cfg.ShutdownDrainDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
 
-	// Call all defered calls before closing server
-	runtime.Goexit()
+	if err := lc.Run(); err != nil {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// articleRoutes registers a version of the articles API on the router of its prefix,
+// TestArticleRoutes checks the versions match transports.OpenAPI.
+// Requests are validated after the scope is checked, so callers without it get a 401 or 403, not a 400
+func articleRoutes(r *mux.Router, authz middlewares.Authorizer, validate mux.MiddlewareFunc, transport transports.Articles, stream transports.ArticlesStream, socket transports.ArticlesWebSocket) {
+	s := r.PathPrefix("/articles").Subrouter()
+
+	reads := s.Methods("GET").Subrouter()
+	reads.Use(middlewares.RequireScope(authz, consts.ScopeArticlesRead), validate)
+	reads.HandleFunc("", transport.GetAll)
+	reads.HandleFunc("/stream", stream.Stream)
+	reads.HandleFunc("/ws", socket.Serve)
+	reads.HandleFunc("/{id}", transport.GetOne)
+
+	writes := s.Methods("POST", "PUT", "DELETE").Subrouter()
+	writes.Use(middlewares.RequireScope(authz, consts.ScopeArticlesWrite), validate)
+	writes.HandleFunc("", transport.Create).Methods("POST")
+	writes.HandleFunc("/{id}", transport.Update).Methods("PUT")
+	writes.HandleFunc("/{id}", transport.Delete).Methods("DELETE")
+}
+
+// serverHook starts srv in the background, and shuts it down waiting for requests to finish
+func serverHook(lc *lifecycle.Manager, name string, srv *http.Server) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			// Listen before serving, so a busy port fails the start up
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+
+			logrus.WithField("addr", srv.Addr).WithField("tls", srv.TLSConfig != nil).Warn("Starting " + name)
+			go func() {
+				serve := srv.Serve
+				if srv.TLSConfig != nil {
+					// certificates come from TLSConfig.GetCertificate
+					serve = func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }
+				}
+				if err := serve(ln); err != http.ErrServerClosed {
+					lc.Fail(err)
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
+	}
 }
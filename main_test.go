@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/consts"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/entities"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/middlewares"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/openapi"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/render"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/transports"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/transports/mocks"
+	"github.com/nachogoca/golang-example-rest-api-layout/internal/usecases"
+)
+
+// TestArticleRoutes fails when a route is added, removed or changed without updating the OpenAPI document
+func TestArticleRoutes(t *testing.T) {
+	r := mux.NewRouter()
+	noop := func(next http.Handler) http.Handler { return next }
+	for _, v := range transports.ArticlesVersions {
+		articleRoutes(r.PathPrefix("/"+v.Name).Subrouter(), usecases.NewPolicy(rolePermissions{}), noop, transports.Articles{}, transports.ArticlesStream{}, transports.ArticlesWebSocket{})
+	}
+
+	// the methods of a route are found by routing requests, as subrouters add their own matchers
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	vars := regexp.MustCompile(`\{[^}]+\}`)
+	var routes []string
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+
+		for _, method := range methods {
+			var match mux.RouteMatch
+			req := httptest.NewRequest(method, vars.ReplaceAllString(path, "x"), nil)
+			if r.Match(req, &match) && match.Route == route {
+				routes = append(routes, method+" "+path)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	sort.Strings(routes)
+
+	assert.Equal(t, transports.OpenAPI("test", render.NewNegotiator(render.JSONCodec{})).Operations(), routes)
+}
+
+// TestArticlesContract runs the versions of the articles handlers with the responses validated against the
+// OpenAPI document, a response that drifts from it is a 500
+func TestArticlesContract(t *testing.T) {
+	article := entities.Article{
+		ID:        "5e3bd0e4-4b8c-4b5e-9a6b-0a1f4f2d6c11",
+		CreatedAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		Title:     "title",
+		Content:   "content",
+		Author:    "alice",
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		principal   *entities.Principal
+		accept      string
+		contentType string
+		body        string
+		prepare     func(u *mocks.MockArticlesUsecase)
+		wantStatus  int
+		wantDetail  string
+		wantHeader  http.Header
+	}{
+		{
+			name:   "get all",
+			method: http.MethodGet,
+			path:   "/articles",
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().GetAll(gomock.Any()).Return([]entities.Article{article}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "get all fails",
+			method: http.MethodGet,
+			path:   "/articles",
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().GetAll(gomock.Any()).Return(nil, errors.New("db down"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "get one not found",
+			method: http.MethodGet,
+			path:   "/articles/unknown",
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().GetOne(gomock.Any(), "unknown").Return(entities.Article{}, consts.ErrEntityNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:        "create",
+			method:      http.MethodPost,
+			path:        "/articles",
+			contentType: "application/json",
+			body:        `{"title": "title", "content": "content"}`,
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().Create(gomock.Any(), gomock.Any()).Return(article, nil)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:        "create with unknown field",
+			method:      http.MethodPost,
+			path:        "/articles",
+			contentType: "application/json",
+			body:        `{"title": "title", "author": "bob"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "body.author is not allowed",
+		},
+		{
+			name:        "create with wrong type",
+			method:      http.MethodPost,
+			path:        "/articles",
+			contentType: "application/json",
+			body:        `{"title": 1}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "body.title must be a string",
+		},
+		{
+			name:        "create with unsupported media type",
+			method:      http.MethodPost,
+			path:        "/articles",
+			contentType: "text/plain",
+			body:        `title`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "update forbidden",
+			method:      http.MethodPut,
+			path:        "/articles/" + article.ID,
+			contentType: "application/json",
+			body:        `{"title": "new"}`,
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().Update(gomock.Any(), gomock.Any()).Return(entities.Article{}, consts.ErrForbidden)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			path:   "/articles/" + article.ID,
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().Delete(gomock.Any(), article.ID).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "granted by a role without scopes",
+			method:      http.MethodPost,
+			path:        "/v2/articles",
+			principal:   &entities.Principal{Subject: "ci", Kind: consts.PrincipalAPIKey, Roles: []string{consts.RoleAuthor}},
+			contentType: "application/json",
+			body:        `{"title": "title", "body": "content"}`,
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().Create(gomock.Any(), gomock.Any()).Return(article, nil)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "role without the scope",
+			method:     http.MethodDelete,
+			path:       "/v2/articles/" + article.ID,
+			principal:  &entities.Principal{Subject: "bob", Kind: consts.PrincipalJWT, Roles: []string{consts.RoleReader}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "v1 is deprecated",
+			method: http.MethodGet,
+			path:   "/v1/articles/" + article.ID,
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().GetOne(gomock.Any(), article.ID).Return(article, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{
+				"Deprecation": {"@1792368000"},
+				"Sunset":      {"Fri, 30 Apr 2027 00:00:00 GMT"},
+				"Link":        {`</v2/articles/` + article.ID + `>; rel="successor-version"`},
+			},
+		},
+		{
+			name:        "v2 create",
+			method:      http.MethodPost,
+			path:        "/v2/articles",
+			contentType: "application/json",
+			body:        `{"title": "title", "body": "content"}`,
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().Create(gomock.Any(), entities.Article{Title: "title", Content: "content"}).Return(article, nil)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:        "v2 create with the v1 field",
+			method:      http.MethodPost,
+			path:        "/v2/articles",
+			contentType: "application/json",
+			body:        `{"title": "title", "content": "content"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "body.content is not allowed",
+		},
+		{
+			name:        "vendor media type picks the version",
+			method:      http.MethodPost,
+			path:        "/articles",
+			accept:      "application/vnd.articles.v2+json",
+			contentType: "application/vnd.articles.v2+json; charset=utf-8",
+			body:        `{"title": "title", "body": "content"}`,
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().Create(gomock.Any(), entities.Article{Title: "title", Content: "content"}).Return(article, nil)
+			},
+			wantStatus: http.StatusCreated,
+			wantHeader: http.Header{"Content-Type": {"application/vnd.articles.v2+json"}},
+		},
+		{
+			name:   "vendor media type with xml suffix",
+			method: http.MethodGet,
+			path:   "/articles/" + article.ID,
+			accept: "application/vnd.articles.v2+xml",
+			prepare: func(u *mocks.MockArticlesUsecase) {
+				u.EXPECT().GetOne(gomock.Any(), article.ID).Return(article, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{"Content-Type": {"application/vnd.articles.v2+xml"}},
+		},
+		{
+			name:       "unknown version",
+			method:     http.MethodGet,
+			path:       "/articles",
+			accept:     "application/vnd.articles.v9+json",
+			wantStatus: http.StatusNotAcceptable,
+			wantDetail: "API version v9 of the Accept header does not exist",
+		},
+		{
+			name:       "vendor media type of another version than the path",
+			method:     http.MethodGet,
+			path:       "/v1/articles",
+			accept:     "application/vnd.articles.v2+json",
+			wantStatus: http.StatusNotAcceptable,
+			wantDetail: "Accept header is of API version v2, the path is of v1",
+		},
+		{
+			name:        "headers of different versions",
+			method:      http.MethodPost,
+			path:        "/articles",
+			accept:      "application/vnd.articles.v1+json",
+			contentType: "application/vnd.articles.v2+json",
+			body:        `{"title": "title"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "Accept and Content-Type headers are of different API versions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			u := mocks.NewMockArticlesUsecase(ctrl)
+			if tt.prepare != nil {
+				tt.prepare(u)
+			}
+
+			codecs := render.NewNegotiator(render.JSONCodec{}, render.XMLCodec{})
+			r := mux.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					principal := entities.Principal{Subject: "alice", Kind: consts.PrincipalJWT, Scopes: []string{consts.ScopeArticlesRead, consts.ScopeArticlesWrite}}
+					if tt.principal != nil {
+						principal = *tt.principal
+					}
+					next.ServeHTTP(w, r.WithContext(middlewares.WithPrincipal(r.Context(), principal)))
+				})
+			})
+			validate := middlewares.OpenAPI(openapi.NewValidator(transports.OpenAPI("test", codecs)), true)
+			deprecated := middlewares.Deprecated(
+				time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC), "/v1", "/v2")
+			for _, v := range transports.ArticlesVersions {
+				vr := r.PathPrefix("/" + v.Name).Subrouter()
+				if v.Deprecated {
+					vr.Use(deprecated)
+				}
+				articleRoutes(vr, usecases.NewPolicy(rolePermissions{}), validate, transports.NewArticles(u, codecs, v), transports.ArticlesStream{}, transports.ArticlesWebSocket{})
+			}
+			h := middlewares.APIVersion("/articles", []string{"v1", "v2"}, "v1")(r)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			var problem render.Problem
+			json.Unmarshal(rec.Body.Bytes(), &problem)
+			assert.Equal(t, tt.wantStatus, rec.Code, problem.Detail)
+			if tt.wantDetail != "" {
+				assert.Equal(t, tt.wantDetail, problem.Detail)
+			}
+			for name, values := range tt.wantHeader {
+				assert.Equal(t, values, rec.Header()[name], name)
+			}
+		})
+	}
+}
+
+// rolePermissions grants the permissions of the roles seeded in the database
+type rolePermissions struct{}
+
+func (rolePermissions) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	seeded := map[string][]string{
+		consts.RoleReader: {consts.ScopeArticlesRead},
+		consts.RoleAuthor: {consts.ScopeArticlesRead, consts.ScopeArticlesWrite},
+	}
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, seeded[role]...)
+	}
+	return permissions, nil
+}